@@ -0,0 +1,112 @@
+package goinmonster
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// formatSource removes the unused imports of a generated Go file and formats
+// it like gofmt. Imports whose package name can't be told from the path are
+// kept.
+func formatSource(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("generated code is not valid Go: %w", err)
+	}
+
+	// Package-qualified identifiers don't resolve to a declaration in the file
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, decl)
+			continue
+		}
+
+		specs := gen.Specs[:0]
+		for _, spec := range gen.Specs {
+			if name, ok := importName(spec.(*ast.ImportSpec)); !ok || used[name] {
+				specs = append(specs, spec)
+			}
+		}
+		gen.Specs = specs
+		if len(specs) > 0 {
+			decls = append(decls, gen)
+		}
+	}
+	file.Decls = decls
+
+	// Drop the removed imports from the file's import list too
+	imports := file.Imports[:0]
+	for _, spec := range file.Imports {
+		if name, ok := importName(spec); !ok || used[name] {
+			imports = append(imports, spec)
+		}
+	}
+	file.Imports = imports
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+
+	// A second pass collapses the blank lines left by removed imports
+	return format.Source(buf.Bytes())
+}
+
+// versionSuffix matches the major version element of an import path (v2, v3)
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName returns the name an import is referred to by; ok is false for
+// blank and dot imports and for paths the name can't be guessed from
+func importName(spec *ast.ImportSpec) (name string, ok bool) {
+	if spec.Name != nil {
+		if spec.Name.Name == "_" || spec.Name.Name == "." {
+			return "", false
+		}
+		return spec.Name.Name, true
+	}
+
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return "", false
+	}
+	return packageName(importPath)
+}
+
+// packageName guesses the name of the package at importPath from its last
+// element; ok is false if that isn't a valid identifier
+func packageName(importPath string) (name string, ok bool) {
+	name = path.Base(importPath)
+	if versionSuffix.MatchString(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	// gopkg.in/yaml.v3 -> yaml
+	if i := strings.Index(name, ".v"); i > 0 && versionSuffix.MatchString(name[i+1:]) {
+		name = name[:i]
+	}
+
+	if strings.ContainsAny(name, ".-") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,90 @@
+package goinmonster
+
+import (
+	"bytes"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatSource(t *testing.T) {
+	src := `package graph
+import (
+	"fmt"
+	"strings"
+	"context"
+	_ "embed"
+	. "math"
+	yaml "gopkg.in/yaml.v3"
+	"github.com/go-chi/chi/v5"
+	"github.com/eddieafk/goinmonster/graph"
+)
+func   f(ctx context.Context)string{ return fmt.Sprint(Pi, chi.NewRouter()) }
+`
+	got, err := formatSource([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kept := range []string{`"fmt"`, `"context"`, `_ "embed"`, `. "math"`, `"github.com/go-chi/chi/v5"`} {
+		if !strings.Contains(string(got), kept) {
+			t.Errorf("import %s was removed:\n%s", kept, got)
+		}
+	}
+	for _, removed := range []string{`"strings"`, `yaml`, `goinmonster/graph`} {
+		if strings.Contains(string(got), removed) {
+			t.Errorf("unused import %s was kept:\n%s", removed, got)
+		}
+	}
+	if !strings.Contains(string(got), "func f(ctx context.Context) string {") {
+		t.Errorf("output is not formatted:\n%s", got)
+	}
+
+	again, err := formatSource(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, again) {
+		t.Errorf("formatSource is not idempotent:\n%s\nthen\n%s", got, again)
+	}
+
+	if _, err := formatSource([]byte("package graph\nfunc {")); err == nil {
+		t.Error("invalid Go was accepted")
+	}
+}
+
+func TestGeneratedCodeIsGofmtStable(t *testing.T) {
+	initProject(t)
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var files int
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		files++
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			t.Errorf("%s does not parse: %v", path, err)
+			return nil
+		}
+		if !bytes.Equal(src, formatted) {
+			t.Errorf("%s is not gofmt-stable", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files == 0 {
+		t.Fatal("no Go files were generated")
+	}
+}
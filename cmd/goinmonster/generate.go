@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/vektah/gqlparser/v2/ast"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,8 +20,14 @@ type Config struct {
 	Relations map[string]RelationConfig `yaml:"relations"`
 	Scalars   map[string]ScalarConfig   `yaml:"scalars"`
 	Resolver  ResolverConfig            `yaml:"resolver"`
+
+	// Directory of the config file; relative schema patterns resolve against it
+	dir string
 }
 
+// defaultConfigFile is the config file name looked up by the commands
+const defaultConfigFile = "goinmonster.yaml"
+
 type OutputConfig struct {
 	Dir       string `yaml:"dir"`
 	Package   string `yaml:"package"`
@@ -52,29 +59,87 @@ type ResolverConfig struct {
 }
 
 func RunGenerate() error {
-	configPath := "goinmonster.yaml"
+	config, schemaContent, schema, err := loadProject()
+	if err != nil {
+		return err
+	}
 
-	// Check for --config flag
-	for i, arg := range os.Args {
-		if arg == "--config" && i+1 < len(os.Args) {
-			configPath = os.Args[i+1]
+	// Catch config keys that name nothing in the schema
+	if err := validateConfig(schema, config); err != nil {
+		return err
+	}
+
+	// Analyze schema for generation
+	analysis := analyzeSchema(schema, config)
+
+	// Output paths are relative to the config file, like the schema patterns
+	outputDir := config.resolvePath(config.Output.Dir)
+
+	// Create output directory
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Generate code
+	generatedPath := filepath.Join(outputDir, config.Output.Filename)
+	if err := generateCode(generatedPath, config, analysis); err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	fmt.Printf("✓ Generated %s\n", generatedPath)
+
+	// Generate resolver stubs if needed
+	if config.Resolver.GenerateStubs {
+		if err := generateResolvers(outputDir, config, analysis); err != nil {
+			return fmt.Errorf("failed to generate resolvers: %w", err)
+		}
+	}
+
+	// Generate server.go if it doesn't exist
+	serverPath := config.resolvePath(config.Output.Server)
+	if _, err := os.Stat(serverPath); os.IsNotExist(err) {
+		if err := generateServer(serverPath, config, analysis, schemaContent); err != nil {
+			return fmt.Errorf("failed to generate server: %w", err)
+		}
+		fmt.Printf("✓ Generated %s\n", serverPath)
+	} else {
+		fmt.Printf("• Skipped %s (already exists)\n", serverPath)
+	}
+
+	fmt.Println()
+	fmt.Println("Generation complete!")
+	return nil
+}
+
+// loadProject loads the config and parses the schema files it names,
+// returning the combined schema source along with the parsed schema
+func loadProject() (*Config, string, *ast.Schema, error) {
+	// An explicit --config wins; otherwise the nearest config up the tree
+	configPath := configFlag()
+	if configPath == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		configPath, err = findConfig(wd)
+		if err != nil {
+			return nil, "", nil, err
 		}
 	}
 
 	// Load config
 	config, err := loadConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Find schema files
-	schemaFiles, err := findSchemaFiles(config.Schema)
+	schemaFiles, err := findSchemaFiles(config.dir, config.Schema)
 	if err != nil {
-		return fmt.Errorf("failed to find schema files: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to find schema files: %w", err)
 	}
 
 	if len(schemaFiles) == 0 {
-		return fmt.Errorf("no schema files found matching patterns: %v", config.Schema)
+		return nil, "", nil, fmt.Errorf("no schema files found matching patterns: %v", config.Schema)
 	}
 
 	fmt.Printf("Found %d schema file(s):\n", len(schemaFiles))
@@ -87,7 +152,7 @@ func RunGenerate() error {
 	for _, file := range schemaFiles {
 		content, err := os.ReadFile(file)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", file, err)
+			return nil, "", nil, fmt.Errorf("failed to read %s: %w", file, err)
 		}
 		schemaContent.Write(content)
 		schemaContent.WriteString("\n")
@@ -96,51 +161,42 @@ func RunGenerate() error {
 	// Parse schema
 	schema, err := parseSchema(schemaContent.String())
 	if err != nil {
-		return fmt.Errorf("failed to parse schema: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to parse schema: %w", err)
 	}
 
-	// Analyze schema for generation
-	analysis := analyzeSchema(schema, config)
+	return config, schemaContent.String(), schema, nil
+}
 
-	// Create output directory
-	if err := os.MkdirAll(config.Output.Dir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// configFlag returns the value of the --config flag, if given
+func configFlag() string {
+	for i, arg := range os.Args {
+		if arg == "--config" && i+1 < len(os.Args) {
+			return os.Args[i+1]
+		}
 	}
+	return ""
+}
 
-	// Generate code
-	generatedPath := filepath.Join(config.Output.Dir, config.Output.Filename)
-	if err := generateCode(generatedPath, config, analysis); err != nil {
-		return fmt.Errorf("failed to generate code: %w", err)
+// findConfig returns the path of the goinmonster.yaml nearest to dir, looking
+// in dir and then each of its parents
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
 	}
-	fmt.Printf("✓ Generated %s\n", generatedPath)
 
-	// Generate resolver stubs if needed
-	if config.Resolver.GenerateStubs {
-		resolversPath := filepath.Join(config.Output.Dir, config.Output.Resolvers)
-		if _, err := os.Stat(resolversPath); os.IsNotExist(err) {
-			if err := generateResolvers(resolversPath, config, analysis); err != nil {
-				return fmt.Errorf("failed to generate resolvers: %w", err)
-			}
-			fmt.Printf("✓ Generated %s\n", resolversPath)
-		} else {
-			fmt.Printf("• Skipped %s (already exists)\n", resolversPath)
+	for current := dir; ; {
+		path := filepath.Join(current, defaultConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
 		}
-	}
 
-	// Generate server.go if it doesn't exist
-	serverPath := config.Output.Server
-	if _, err := os.Stat(serverPath); os.IsNotExist(err) {
-		if err := generateServer(serverPath, config, analysis, schemaContent.String()); err != nil {
-			return fmt.Errorf("failed to generate server: %w", err)
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("no %s found in %s or any parent directory", defaultConfigFile, dir)
 		}
-		fmt.Printf("✓ Generated %s\n", serverPath)
-	} else {
-		fmt.Printf("• Skipped %s (already exists)\n", serverPath)
+		current = parent
 	}
-
-	fmt.Println()
-	fmt.Println("Generation complete!")
-	return nil
 }
 
 func loadConfig(path string) (*Config, error) {
@@ -154,6 +210,12 @@ func loadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	config.dir = filepath.Dir(absPath)
+
 	// Apply defaults
 	if config.Output.Dir == "" {
 		config.Output.Dir = "graph"
@@ -189,11 +251,23 @@ func loadConfig(path string) (*Config, error) {
 	return &config, nil
 }
 
-func findSchemaFiles(patterns []string) ([]string, error) {
+// resolvePath resolves a path from the config against the config's directory
+func (c *Config) resolvePath(path string) string {
+	if filepath.IsAbs(path) || c.dir == "" {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// findSchemaFiles globs patterns, resolving relative ones against baseDir
+func findSchemaFiles(baseDir string, patterns []string) ([]string, error) {
 	var files []string
 	seen := make(map[string]bool)
 
 	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) && baseDir != "" {
+			pattern = filepath.Join(baseDir, pattern)
+		}
 		matches, err := filepath.Glob(pattern)
 		if err != nil {
 			return nil, err
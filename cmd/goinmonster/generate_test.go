@@ -0,0 +1,75 @@
+package goinmonster
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigDiscoveryFromNestedDir(t *testing.T) {
+	initProject(t)
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	path, err := findConfig(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, defaultConfigFile); path != want {
+		t.Errorf("findConfig = %s, want %s", path, want)
+	}
+
+	// The schema glob is relative to the config, not the working directory
+	config, schemaContent, _, err := loadProject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.dir != root || schemaContent == "" {
+		t.Errorf("config dir = %s, schema %d bytes", config.dir, len(schemaContent))
+	}
+	if err := RunValidate(); err != nil {
+		t.Errorf("validate from a nested directory: %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, err := findConfig("."); err == nil {
+		t.Error("findConfig found a config outside the project")
+	}
+}
+
+func TestGenerateResolvesPathsAgainstConfig(t *testing.T) {
+	root := t.TempDir()
+	backend := filepath.Join(root, "backend")
+	if err := os.Mkdir(backend, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(backend)
+	if err := RunInit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run from the repository root with the config in a subdirectory
+	t.Chdir(root)
+	args := os.Args
+	os.Args = []string{"goinmonster", "gen", "--config", filepath.Join("backend", defaultConfigFile)}
+	t.Cleanup(func() { os.Args = args })
+
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"graph/generated.go", "server.go"} {
+		if _, err := os.Stat(filepath.Join(backend, path)); err != nil {
+			t.Errorf("%s was not generated next to the config: %v", path, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, path)); err == nil {
+			t.Errorf("%s was generated in the working directory", path)
+		}
+	}
+}
@@ -80,13 +80,9 @@ resolver:
 `
 
 func RunInit() error {
-	configPath := "goinmonster.yaml"
-
-	// Check for --config flag
-	for i, arg := range os.Args {
-		if arg == "--config" && i+1 < len(os.Args) {
-			configPath = os.Args[i+1]
-		}
+	configPath := configFlag()
+	if configPath == "" {
+		configPath = defaultConfigFile
 	}
 
 	// Check if config already exists
@@ -163,7 +159,17 @@ directive @sql(
   column: String
   table: String
   relation: String
-) on FIELD_DEFINITION
+  filterable: Boolean
+  sortable: Boolean
+  default: String
+  cast: String
+  index: String
+  readonly: Boolean
+  schema: String
+  primaryKey: String
+  joinOn: String
+  jsonExtract: String
+) on FIELD_DEFINITION | OBJECT
 
 # Example types - replace with your own
 
@@ -41,10 +41,11 @@ type InputTypeDef struct {
 
 // InputFieldDef represents a field in an input type
 type InputFieldDef struct {
-	Name      string
-	TypeName  string
-	IsList    bool
-	IsNonNull bool
+	Name          string
+	TypeName      string
+	IsList        bool
+	IsNonNull     bool
+	IsElemNonNull bool // List elements are non-null ([T!])
 }
 
 // EnumTypeDef represents a GraphQL enum type
@@ -133,6 +134,9 @@ func analyzeSchema(schema *ast.Schema, config *Config) *SchemaAnalysis {
 					IsList:    isListType(field.Type),
 					IsNonNull: field.Type.NonNull,
 				}
+				if field.Type.Elem != nil {
+					inputField.IsElemNonNull = field.Type.Elem.NonNull
+				}
 				inputType.Fields = append(inputType.Fields, inputField)
 			}
 
@@ -247,21 +251,8 @@ func getBaseTypeName(t *ast.Type) string {
 
 // isListType checks if the type is a list
 func isListType(t *ast.Type) bool {
-	if t == nil {
-		return false
-	}
-
-	// If this is a NonNull type, check the inner type
-	if t.NonNull && t.Elem != nil {
-		return isListType(t.Elem)
-	}
-
-	// Check if it's a list
-	if t.Elem != nil {
-		return true
-	}
-
-	return false
+	// Non-null is a flag on the type itself, so [T!]! has the list's Elem
+	return t != nil && t.Elem != nil
 }
 
 // isBuiltinScalar checks if a scalar is a GraphQL built-in
@@ -0,0 +1,36 @@
+package goinmonster
+
+import (
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestIsListType(t *testing.T) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Input: `type Query {
+		list: [String]
+		nonNullList: [String]!
+		nonNullItems: [String!]!
+		nested: [[String]]!
+		scalar: String
+		nonNullScalar: String!
+	}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"list":          true,
+		"nonNullList":   true,
+		"nonNullItems":  true,
+		"nested":        true,
+		"scalar":        false,
+		"nonNullScalar": false,
+	}
+	for name, isList := range want {
+		if got := isListType(schema.Query.Fields.ForName(name).Type); got != isList {
+			t.Errorf("isListType(%s) = %v, want %v", name, got, isList)
+		}
+	}
+}
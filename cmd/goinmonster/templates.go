@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -19,12 +22,18 @@ package {{.Package}}
 import (
 	"context"
 	"fmt"
+{{- if .JoinConfigs}}
+	"log"
+{{- end}}
 	"time"
 
 	"github.com/eddieafk/goinmonster/graph"
 	"github.com/eddieafk/goinmonster/graph/marshal"
 	"github.com/eddieafk/goinmonster/sql/ast"
 	"github.com/eddieafk/goinmonster/sql/dialect"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
 )
 
 // SQLConverter provides the configured SQL converter
@@ -49,6 +58,14 @@ func InitSQLConverter(schema *graph.Schema) *graph.SQLConverter {
 		RelationType: "{{.RelationType}}",
 	})
 {{- end}}
+{{- if .JoinConfigs}}
+
+	// Each belongsTo relation also makes its reverse field queryable;
+	// ambiguous reverse fields are skipped and need ConfigureJoin
+	if err := sqlConverter.InferInverseJoins(); err != nil {
+		log.Printf("goinmonster: %v", err)
+	}
+{{- end}}
 
 	return sqlConverter
 }
@@ -94,6 +111,37 @@ func (m *{{.Marshaler}}) UnmarshalGraphQL(v interface{}) (interface{}, error) {
 }
 {{- end}}
 
+{{- range .Enums}}
+{{$enum := .Name}}
+// {{.Name}} is the GraphQL enum {{.Name}}
+type {{.Name}} string
+
+const (
+{{- range .Values}}
+	{{.GoName}} {{$enum}} = "{{.Name}}"
+{{- end}}
+)
+
+// IsValid reports whether e is a value of {{.Name}}
+func (e {{.Name}}) IsValid() bool {
+	switch e {
+	case {{range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.GoName}}{{end}}:
+		return true
+	}
+	return false
+}
+{{- end}}
+
+{{- range .Inputs}}
+
+// {{.Name}} is the GraphQL input {{.Name}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.GoName}} {{.GoType}} ` + "`" + `json:"{{.Name}}"` + "`" + `
+{{- end}}
+}
+{{- end}}
+
 // RegisterResolvers registers all resolvers on the executable schema
 func RegisterResolvers(es *graph.ExecutableSchema, resolver ResolverRoot) {
 {{- range .QueryFields}}
@@ -135,8 +183,10 @@ var (
 )
 `
 
-const resolversFileTemplate = `package {{.Package}}
-
+// resolverTemplates holds the pieces of the resolver files: single-file
+// layout puts them all in one file, follow-schema splits the resolver
+// functions into a file per type
+const resolverTemplates = `{{define "imports"}}
 import (
 	"context"
 	"fmt"
@@ -145,8 +195,8 @@ import (
 
 	"github.com/eddieafk/goinmonster/graph"
 )
-
-// Resolver is the root resolver
+{{end}}
+{{- define "root"}}// Resolver is the root resolver
 type Resolver struct {
 	// Add your dependencies here (e.g., database connection)
 }
@@ -163,7 +213,8 @@ func (r *Resolver) Mutation() MutationResolver {
 
 type queryResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
-{{range .QueryFields}}
+{{end}}
+{{- define "funcs"}}{{range .QueryFields}}
 
 // {{.GoName}} resolves Query.{{.Name}}
 func (r *queryResolver) {{.GoName}}(es *graph.ExecutableSchema) graph.ResolverFunc {
@@ -173,6 +224,12 @@ func (r *queryResolver) {{.GoName}}(es *graph.ExecutableSchema) graph.ResolverFu
 			return nil, fmt.Errorf("missing resolve info")
 		}
 
+		// Explain requests only record the SQL
+		if graph.ExplainRequested(ctx) {
+			_, err := sqlConverter.Explain(ctx, info)
+			return nil, err
+		}
+
 		// Convert to SQL query
 		result, err := sqlConverter.ConvertToSelect(ctx, info)
 		if err != nil {
@@ -221,12 +278,26 @@ func (r *mutationResolver) {{.GoName}}(es *graph.ExecutableSchema) graph.Resolve
 		if err != nil {
 			return nil, err
 		}
+{{else if and (eq .MutationType "update") .HasWhere}}
+		where, _ := args["where"].(map[string]interface{})
+		all, _ := args["all"].(bool)
+		result, err := sqlConverter.ConvertToBulkUpdate(ctx, "{{.TypeName}}", where, input, all, returning)
+		if err != nil {
+			return nil, err
+		}
 {{else if eq .MutationType "update"}}
 		where := map[string]interface{}{"id": id}
 		result, err := sqlConverter.ConvertToUpdate(ctx, "{{.TypeName}}", where, input, returning)
 		if err != nil {
 			return nil, err
 		}
+{{else if and (eq .MutationType "delete") .HasWhere}}
+		where, _ := args["where"].(map[string]interface{})
+		all, _ := args["all"].(bool)
+		result, err := sqlConverter.ConvertToBulkDelete(ctx, "{{.TypeName}}", where, all, returning)
+		if err != nil {
+			return nil, err
+		}
 {{else if eq .MutationType "delete"}}
 		where := map[string]interface{}{"id": id}
 		result, err := sqlConverter.ConvertToDelete(ctx, "{{.TypeName}}", where, returning)
@@ -254,8 +325,17 @@ func (r *mutationResolver) {{.GoName}}(es *graph.ExecutableSchema) graph.Resolve
 var (
 	_ = time.Now
 	_ = log.Printf
+	_ = fmt.Errorf
 )
-`
+{{end}}
+{{- define "single"}}package {{.Package}}
+{{template "imports" .}}
+{{template "root" .}}{{template "funcs" .}}{{end}}
+{{- define "rootFile"}}package {{.Package}}
+
+{{template "root" .}}{{end}}
+{{- define "typeFile"}}package {{.Package}}
+{{template "imports" .}}{{template "funcs" .}}{{end}}`
 
 const serverFileTemplate = `package main
 
@@ -312,9 +392,10 @@ func main() {
 	}))
 
 	// Set up HTTP handler with CORS
+	h := srv.Handler(handler.CORSMiddleware(handler.DefaultCORSConfig()))
 	mux := http.NewServeMux()
-	mux.Handle("/graphql", corsMiddleware(srv))
-	mux.Handle("/playground", corsMiddleware(srv))
+	mux.Handle("/graphql", h)
+	mux.Handle("/playground", h)
 
 	// Start server
 	addr := ":8080"
@@ -326,22 +407,6 @@ func main() {
 	}
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // truncateQuery truncates a query for logging
 func truncateQuery(query string) string {
 	if len(query) > 100 {
@@ -362,6 +427,9 @@ type GeneratedData struct {
 	Scalars        []ScalarData
 	QueryFields    []FieldData
 	MutationFields []MutationFieldData
+	Enums          []EnumData
+	Inputs         []InputData
+	Imports        []string // Import specs of the go_types used by inputs
 }
 
 type TableMapping struct {
@@ -392,10 +460,32 @@ type ScalarData struct {
 	Marshaler string
 }
 
-type FieldData struct {
+type InputData struct {
+	Name   string
+	Fields []InputFieldData
+}
+
+type InputFieldData struct {
 	Name   string
 	GoName string
-	IsList bool
+	GoType string
+}
+
+type EnumData struct {
+	Name   string
+	Values []EnumValueData
+}
+
+type EnumValueData struct {
+	Name   string
+	GoName string // Enum name + value in PascalCase, e.g. StatusInProgress
+}
+
+type FieldData struct {
+	Name     string
+	GoName   string
+	TypeName string
+	IsList   bool
 }
 
 type MutationFieldData struct {
@@ -405,10 +495,14 @@ type MutationFieldData struct {
 	MutationType string // create, update, delete
 	HasInput     bool
 	HasID        bool
+	HasWhere     bool
 }
 
 func generateCode(path string, config *Config, analysis *SchemaAnalysis) error {
 	data := prepareGeneratedData(config, analysis)
+	if problems := enumProblems(data); len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n  "))
+	}
 
 	tmpl, err := template.New("generated").Parse(generatedFileTemplate)
 	if err != nil {
@@ -420,23 +514,126 @@ func generateCode(path string, config *Config, analysis *SchemaAnalysis) error {
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	src, err := formatSource(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	return os.WriteFile(path, src, 0644)
 }
 
-func generateResolvers(path string, config *Config, analysis *SchemaAnalysis) error {
+// generateResolvers writes the resolver stubs into dir in the configured
+// layout, keeping files that already exist
+func generateResolvers(dir string, config *Config, analysis *SchemaAnalysis) error {
 	data := prepareGeneratedData(config, analysis)
 
-	tmpl, err := template.New("resolvers").Parse(resolversFileTemplate)
+	tmpl, err := template.New("resolvers").Parse(resolverTemplates)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	type resolverFile struct {
+		path     string
+		template string
+		data     *GeneratedData
+	}
+	var files []resolverFile
+
+	switch config.Resolver.Layout {
+	case "", "single-file":
+		files = append(files, resolverFile{filepath.Join(dir, config.Output.Resolvers), "single", data})
+
+	case "follow-schema":
+		// The root resolver keeps the configured name; each type's
+		// resolvers go to <type>.resolvers.go
+		root := filepath.Join(dir, config.Output.Resolvers)
+		if singleFileResolvers(root) {
+			return fmt.Errorf("%s holds single-file resolvers; move them into <type>.resolvers.go files "+
+				"(or delete it to regenerate) before switching to the follow-schema layout", root)
+		}
+		files = append(files, resolverFile{root, "rootFile", data})
+		for _, group := range groupResolversByType(data, analysis) {
+			path := filepath.Join(dir, toSnakeCase(group.name)+".resolvers.go")
+			files = append(files, resolverFile{path, "typeFile", group.data})
+		}
+
+	default:
+		return fmt.Errorf("unknown resolver layout %q (expected single-file or follow-schema)", config.Resolver.Layout)
 	}
 
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	for _, file := range files {
+		if _, err := os.Stat(file.path); err == nil {
+			fmt.Printf("• Skipped %s (already exists)\n", file.path)
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, file.template, file.data); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		src, err := formatSource(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", file.path, err)
+		}
+		if err := os.WriteFile(file.path, src, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Generated %s\n", file.path)
+	}
+	return nil
+}
+
+// singleFileResolvers reports whether the file at path defines field
+// resolvers, as the single-file layout does
+func singleFileResolvers(path string) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(src, []byte("func (r *queryResolver) ")) ||
+		bytes.Contains(src, []byte("func (r *mutationResolver) "))
+}
+
+// resolverGroup holds the root field resolvers of one type
+type resolverGroup struct {
+	name string
+	data *GeneratedData
+}
+
+// groupResolversByType splits the query and mutation resolvers by the object
+// type they return or mutate; the others are grouped under Query or Mutation
+func groupResolversByType(data *GeneratedData, analysis *SchemaAnalysis) []resolverGroup {
+	objectTypes := make(map[string]bool)
+	for _, typeDef := range analysis.ObjectTypes {
+		objectTypes[typeDef.Name] = true
+	}
+
+	groups := make(map[string]*GeneratedData)
+	group := func(typeName, fallback string) *GeneratedData {
+		if !objectTypes[typeName] {
+			typeName = fallback
+		}
+		if groups[typeName] == nil {
+			groups[typeName] = &GeneratedData{Package: data.Package}
+		}
+		return groups[typeName]
+	}
+	for _, field := range data.QueryFields {
+		g := group(field.TypeName, "Query")
+		g.QueryFields = append(g.QueryFields, field)
+	}
+	for _, field := range data.MutationFields {
+		g := group(field.TypeName, "Mutation")
+		g.MutationFields = append(g.MutationFields, field)
+	}
+
+	result := make([]resolverGroup, 0, len(groups))
+	for name, g := range groups {
+		result = append(result, resolverGroup{name, g})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
 }
 
 func prepareGeneratedData(config *Config, analysis *SchemaAnalysis) *GeneratedData {
@@ -635,12 +832,61 @@ func prepareGeneratedData(config *Config, analysis *SchemaAnalysis) *GeneratedDa
 		return data.Scalars[i].Name < data.Scalars[j].Name
 	})
 
+	// Enums, except those bound to a Go type in the scalars config
+	for _, enum := range analysis.EnumTypes {
+		if scalar, ok := config.Scalars[enum.Name]; ok && scalar.GoType != "" {
+			continue
+		}
+		enumData := EnumData{Name: enum.Name}
+		for _, value := range enum.Values {
+			enumData.Values = append(enumData.Values, EnumValueData{
+				Name:   value,
+				GoName: enum.Name + enumValueGoName(value),
+			})
+		}
+		data.Enums = append(data.Enums, enumData)
+	}
+	sort.Slice(data.Enums, func(i, j int) bool {
+		return data.Enums[i].Name < data.Enums[j].Name
+	})
+
+	// Input structs
+	generated := make(map[string]bool)
+	for _, enum := range data.Enums {
+		generated[enum.Name] = true
+	}
+	for _, input := range analysis.InputTypes {
+		generated[input.Name] = true
+	}
+	imports := make(map[string]bool)
+	for _, input := range analysis.InputTypes {
+		inputData := InputData{Name: input.Name}
+		for _, field := range input.Fields {
+			goType, importSpec := inputGoType(field, config, generated)
+			if importSpec != "" && !imports[importSpec] {
+				imports[importSpec] = true
+				data.Imports = append(data.Imports, importSpec)
+			}
+			inputData.Fields = append(inputData.Fields, InputFieldData{
+				Name:   field.Name,
+				GoName: toExportedName(field.Name),
+				GoType: goType,
+			})
+		}
+		data.Inputs = append(data.Inputs, inputData)
+	}
+	sort.Slice(data.Inputs, func(i, j int) bool {
+		return data.Inputs[i].Name < data.Inputs[j].Name
+	})
+	sort.Strings(data.Imports)
+
 	// Query fields
 	for _, field := range analysis.QueryFields {
 		data.QueryFields = append(data.QueryFields, FieldData{
-			Name:   field.Name,
-			GoName: toExportedName(field.Name),
-			IsList: field.IsList,
+			Name:     field.Name,
+			GoName:   toExportedName(field.Name),
+			TypeName: field.TypeName,
+			IsList:   field.IsList,
 		})
 	}
 
@@ -666,6 +912,7 @@ func prepareGeneratedData(config *Config, analysis *SchemaAnalysis) *GeneratedDa
 
 		hasInput := false
 		hasID := false
+		hasWhere := false
 		for _, arg := range field.Arguments {
 			if arg == "input" {
 				hasInput = true
@@ -673,6 +920,9 @@ func prepareGeneratedData(config *Config, analysis *SchemaAnalysis) *GeneratedDa
 			if arg == "id" {
 				hasID = true
 			}
+			if arg == "where" {
+				hasWhere = true
+			}
 		}
 
 		data.MutationFields = append(data.MutationFields, MutationFieldData{
@@ -682,6 +932,7 @@ func prepareGeneratedData(config *Config, analysis *SchemaAnalysis) *GeneratedDa
 			MutationType: mutationType,
 			HasInput:     hasInput,
 			HasID:        hasID,
+			HasWhere:     hasWhere,
 		})
 	}
 
@@ -718,6 +969,104 @@ func toExportedName(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
+// inputGoType returns the Go type of an input field: lists are slices and
+// nullable fields and list elements are pointers, except for types that are
+// nil-able already. generated holds the enums and inputs that have generated
+// Go types. importSpec is the import a go_type from another package needs.
+func inputGoType(field InputFieldDef, config *Config, generated map[string]bool) (goType, importSpec string) {
+	goType = "interface{}"
+	switch field.TypeName {
+	case "ID", "String":
+		goType = "string"
+	case "Int":
+		goType = "int"
+	case "Float":
+		goType = "float64"
+	case "Boolean":
+		goType = "bool"
+	default:
+		if scalar, ok := config.Scalars[field.TypeName]; ok && scalar.GoType != "" {
+			goType, importSpec = qualifiedGoType(scalar.GoType)
+		} else if generated[field.TypeName] {
+			goType = field.TypeName
+		}
+	}
+
+	nilable := goType == "interface{}" || strings.HasPrefix(goType, "map[") ||
+		strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "*")
+	if field.IsList {
+		if !field.IsElemNonNull && !nilable {
+			return "[]*" + goType, importSpec
+		}
+		return "[]" + goType, importSpec
+	}
+	if !field.IsNonNull && !nilable {
+		return "*" + goType, importSpec
+	}
+	return goType, importSpec
+}
+
+// qualifiedGoType splits a go_type from another package, such as
+// github.com/google/uuid.UUID, into the type as written in Go code
+// (uuid.UUID) and the import spec it needs. Types without an import path,
+// such as time.Time, are returned as they are.
+func qualifiedGoType(goType string) (typ, importSpec string) {
+	name := strings.TrimLeft(goType, "*[]")
+	prefix := goType[:len(goType)-len(name)]
+
+	slash := strings.LastIndex(name, "/")
+	dot := strings.LastIndex(name, ".")
+	if slash < 0 || dot < slash {
+		return goType, ""
+	}
+
+	importPath := name[:dot]
+	pkg, ok := packageName(importPath)
+	importSpec = strconv.Quote(importPath)
+	if !ok {
+		// Name the import after its last element, minus what Go rejects
+		pkg = strings.Map(func(r rune) rune {
+			if r == '-' || r == '.' {
+				return -1
+			}
+			return r
+		}, path.Base(importPath))
+		importSpec = pkg + " " + importSpec
+	}
+	return prefix + pkg + "." + name[dot+1:], importSpec
+}
+
+// enumValueGoName converts an enum value to PascalCase by capitalising each
+// _-separated segment. All-caps segments are lowered after their first letter
+// (IN_PROGRESS -> InProgress); others keep their case (inProgress -> InProgress).
+func enumValueGoName(value string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(value, "_") {
+		if part == strings.ToUpper(part) {
+			part = strings.ToLower(part)
+		}
+		b.WriteString(toExportedName(part))
+	}
+	return b.String()
+}
+
+// enumProblems lists the enum values whose Go constants would collide
+// (e.g., Foo_Bar and FOO_BAR both become FooBar)
+func enumProblems(data *GeneratedData) []string {
+	var problems []string
+	for _, enum := range data.Enums {
+		seen := make(map[string]string, len(enum.Values))
+		for _, value := range enum.Values {
+			if other, ok := seen[value.GoName]; ok {
+				problems = append(problems, fmt.Sprintf("enum %s: values %s and %s both generate %s", enum.Name, other, value.Name, value.GoName))
+				continue
+			}
+			seen[value.GoName] = value.Name
+		}
+	}
+	return problems
+}
+
 func containsUpperCase(s string) bool {
 	for _, r := range s {
 		if r >= 'A' && r <= 'Z' {
@@ -750,7 +1099,11 @@ func generateServer(path string, config *Config, analysis *SchemaAnalysis, schem
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	src, err := formatSource(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	return os.WriteFile(path, src, 0644)
 }
 
 type ServerData struct {
@@ -0,0 +1,295 @@
+package goinmonster
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSkipsAmbiguousInverseJoins(t *testing.T) {
+	initProject(t)
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two belongsTo joins to User leave User.messages ambiguous
+	f.WriteString(`
+type Message {
+	id: ID!
+	sender: User @sql(relation: "belongsTo")
+	recipient: User @sql(relation: "belongsTo")
+}
+extend type User { messages: [Message] }
+`)
+	f.Close()
+
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile("graph/generated.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "panic(") {
+		t.Errorf("generated code panics on ambiguous inverse joins:\n%s", src)
+	}
+	if !strings.Contains(string(src), "sqlConverter.InferInverseJoins(); err != nil {\n\t\tlog.Printf(") {
+		t.Errorf("generated code does not log inverse join errors:\n%s", src)
+	}
+
+	if err := RunValidate(); err == nil {
+		t.Error("validate accepted ambiguous inverse joins")
+	}
+}
+
+func TestGenerateFollowSchemaLayout(t *testing.T) {
+	initProject(t)
+	config, err := os.ReadFile("goinmonster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = []byte(strings.Replace(string(config), `layout: "single-file"`, `layout: "follow-schema"`, 1))
+	if err := os.WriteFile("goinmonster.yaml", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\ntype Post { id: ID! title: String }\nextend type Query { posts: [Post] serverTime: String }\nextend type Mutation { ping: Boolean }\n")
+	f.Close()
+
+	// An existing file is preserved
+	if err := os.MkdirAll("graph", 0755); err != nil {
+		t.Fatal(err)
+	}
+	const custom = "package graph\n\n// hand-written post resolvers\n"
+	if err := os.WriteFile("graph/post.resolvers.go", []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantFuncs := map[string][]string{
+		"graph/user.resolvers.go":     {"func (r *queryResolver) Users(", "func (r *queryResolver) User(", "func (r *mutationResolver) CreateUser(", "func (r *mutationResolver) DeleteUser("},
+		"graph/query.resolvers.go":    {"func (r *queryResolver) ServerTime("},
+		"graph/mutation.resolvers.go": {"func (r *mutationResolver) Ping("},
+	}
+	for path, funcs := range wantFuncs {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s was not generated: %v", path, err)
+			continue
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), path, src, 0); err != nil {
+			t.Errorf("%s does not parse: %v", path, err)
+		}
+		for _, fn := range funcs {
+			if !strings.Contains(string(src), fn) {
+				t.Errorf("%s is missing %s", path, fn)
+			}
+		}
+		if strings.Contains(string(src), "type Resolver struct") {
+			t.Errorf("%s repeats the root resolver", path)
+		}
+	}
+
+	if src, _ := os.ReadFile("graph/post.resolvers.go"); string(src) != custom {
+		t.Errorf("existing post.resolvers.go was overwritten:\n%s", src)
+	}
+	if src, err := os.ReadFile("graph/resolvers.go"); err != nil || !strings.Contains(string(src), "type Resolver struct") {
+		t.Errorf("root resolver file was not generated: %v", err)
+	}
+
+	config = []byte(strings.Replace(string(config), `layout: "follow-schema"`, `layout: "per-field"`, 1))
+	if err := os.WriteFile("goinmonster.yaml", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunGenerate(); err == nil || !strings.Contains(err.Error(), "per-field") {
+		t.Errorf("unknown layout: err = %v", err)
+	}
+}
+
+func TestGenerateFollowSchemaAfterSingleFile(t *testing.T) {
+	initProject(t)
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	single, err := os.ReadFile("graph/resolvers.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile("goinmonster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = []byte(strings.Replace(string(config), `layout: "single-file"`, `layout: "follow-schema"`, 1))
+	if err := os.WriteFile("goinmonster.yaml", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The single-file resolvers would be defined a second time
+	if err := RunGenerate(); err == nil || !strings.Contains(err.Error(), "follow-schema") {
+		t.Errorf("switching layouts: err = %v", err)
+	}
+	if _, err := os.Stat("graph/user.resolvers.go"); err == nil {
+		t.Error("user.resolvers.go was generated next to single-file resolvers")
+	}
+	if src, _ := os.ReadFile("graph/resolvers.go"); string(src) != string(single) {
+		t.Error("resolvers.go was modified")
+	}
+}
+
+func TestGenerateEnums(t *testing.T) {
+	initProject(t)
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\nenum Status { ACTIVE IN_PROGRESS ARCHIVED }\nenum Role { ADMIN MEMBER }\n")
+	f.Close()
+
+	// Role is bound to a Go type, so no enum type is generated for it
+	config, err := os.ReadFile("goinmonster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = []byte(strings.Replace(string(config), "scalars:\n", "scalars:\n  Role:\n    go_type: \"string\"\n", 1))
+	if err := os.WriteFile("goinmonster.yaml", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile("graph/generated.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type Status string",
+		`StatusActive     Status = "ACTIVE"`,
+		`StatusInProgress Status = "IN_PROGRESS"`,
+		`StatusArchived   Status = "ARCHIVED"`,
+		"func (e Status) IsValid() bool {",
+		"case StatusActive, StatusInProgress, StatusArchived:",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code is missing %q", want)
+		}
+	}
+	if strings.Contains(string(src), "type Role string") || strings.Contains(string(src), "RoleAdmin") {
+		t.Error("an enum bound in the scalars config was generated")
+	}
+}
+
+func TestEnumValueGoName(t *testing.T) {
+	for value, want := range map[string]string{
+		"ACTIVE":       "Active",
+		"IN_PROGRESS":  "InProgress",
+		"inProgress":   "InProgress",
+		"Foo_barBaz":   "FooBarBaz",
+		"HTTP2_ENABLE": "Http2Enable",
+	} {
+		if got := enumValueGoName(value); got != want {
+			t.Errorf("enumValueGoName(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestGenerateEnumCollisions(t *testing.T) {
+	initProject(t)
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\nenum Kind { Foo_Bar FOO_BAR }\n")
+	f.Close()
+
+	err = RunGenerate()
+	if err == nil || !strings.Contains(err.Error(), "Foo_Bar and FOO_BAR both generate KindFooBar") {
+		t.Errorf("generate: err = %v", err)
+	}
+	if err := RunValidate(); err == nil {
+		t.Error("validate accepted colliding enum values")
+	}
+}
+
+func TestGenerateInputStructs(t *testing.T) {
+	initProject(t)
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`
+enum Status { ACTIVE ARCHIVED }
+scalar UUID
+input UserFilter {
+	status: Status
+	tags: [String!]!
+	labels: [String]
+	createdAfter: DateTime
+	metadata: JSON
+	name: CreateUserInput
+	ownerId: UUID
+	teamIds: [UUID!]
+}
+`)
+	f.Close()
+
+	config, err := os.ReadFile("goinmonster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = []byte(strings.Replace(string(config), "scalars:\n", "scalars:\n  UUID:\n    go_type: \"github.com/google/uuid.UUID\"\n", 1))
+	if err := os.WriteFile("goinmonster.yaml", config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunGenerate(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile("graph/generated.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type CreateUserInput struct {\n\tName  string `json:\"name\"`\n\tEmail string `json:\"email\"`\n}",
+		"type UpdateUserInput struct {\n\tName  *string `json:\"name\"`\n\tEmail *string `json:\"email\"`\n}",
+		"Status       *Status                `json:\"status\"`",
+		"Tags         []string               `json:\"tags\"`",
+		"Labels       []*string              `json:\"labels\"`",
+		"OwnerId      *uuid.UUID             `json:\"ownerId\"`",
+		"TeamIds      []uuid.UUID            `json:\"teamIds\"`",
+		"\t\"github.com/google/uuid\"\n)",
+		"CreatedAfter *time.Time             `json:\"createdAfter\"`",
+		"Metadata     map[string]interface{} `json:\"metadata\"`",
+		"Name         *CreateUserInput       `json:\"name\"`",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code is missing:\n%s", want)
+		}
+	}
+}
+
+func TestQualifiedGoType(t *testing.T) {
+	tests := []struct{ goType, typ, importSpec string }{
+		{"time.Time", "time.Time", ""},
+		{"map[string]interface{}", "map[string]interface{}", ""},
+		{"github.com/google/uuid.UUID", "uuid.UUID", `"github.com/google/uuid"`},
+		{"*net/netip.Addr", "*netip.Addr", `"net/netip"`},
+		{"github.com/shopspring/decimal/v2.Decimal", "decimal.Decimal", `"github.com/shopspring/decimal/v2"`},
+		{"example.com/go-money.Money", "gomoney.Money", `gomoney "example.com/go-money"`},
+	}
+	for _, tt := range tests {
+		typ, importSpec := qualifiedGoType(tt.goType)
+		if typ != tt.typ || importSpec != tt.importSpec {
+			t.Errorf("qualifiedGoType(%q) = %q, %q; want %q, %q", tt.goType, typ, importSpec, tt.typ, tt.importSpec)
+		}
+	}
+}
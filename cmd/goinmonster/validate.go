@@ -0,0 +1,239 @@
+package goinmonster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eddieafk/goinmonster/graph"
+	"github.com/eddieafk/goinmonster/sql/dialect"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// RunValidate checks the config and schema without generating anything,
+// printing every problem found; the error reports whether there were any
+func RunValidate() error {
+	config, schemaContent, schema, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	analysis := analyzeSchema(schema, config)
+	checks := []struct {
+		name     string
+		problems []string
+	}{
+		{"Config matches the schema", configProblems(schema, config)},
+		{"@sql directives are valid", directiveProblems(schema)},
+		{"SQL mappings are valid", mappingProblems(schemaContent, config, analysis)},
+		{"Enum values have distinct Go names", enumProblems(prepareGeneratedData(config, analysis))},
+	}
+
+	fmt.Println()
+	total := 0
+	for _, check := range checks {
+		if len(check.problems) == 0 {
+			fmt.Printf("✓ %s\n", check.name)
+			continue
+		}
+		fmt.Printf("✗ %s\n", check.name)
+		for _, p := range check.problems {
+			fmt.Printf("  - %s\n", p)
+		}
+		total += len(check.problems)
+	}
+
+	if total > 0 {
+		return fmt.Errorf("validation found %d problem(s)", total)
+	}
+	fmt.Println()
+	fmt.Println("Validation passed!")
+	return nil
+}
+
+// validateConfig checks that the models, fields and relations of the config
+// name types and fields of the schema, suggesting the closest name for typos
+func validateConfig(schema *ast.Schema, config *Config) error {
+	if problems := configProblems(schema, config); len(problems) > 0 {
+		return fmt.Errorf("config does not match the schema:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// configProblems lists the config keys that name nothing in the schema
+func configProblems(schema *ast.Schema, config *Config) []string {
+	var problems []string
+
+	var typeNames []string
+	for name, def := range schema.Types {
+		if def.Kind == ast.Object && !strings.HasPrefix(name, "__") {
+			typeNames = append(typeNames, name)
+		}
+	}
+
+	// Map iteration is random; report in a stable order
+	for _, typeName := range sortedKeys(config.Models) {
+		if def := schema.Types[typeName]; def == nil || def.Kind != ast.Object {
+			problems = append(problems, "models: unknown type "+typeName+suggest(typeName, typeNames))
+		}
+	}
+
+	checkField := func(section, key string) {
+		typeName, fieldName, ok := strings.Cut(key, ".")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %q must be Type.field", section, key))
+			return
+		}
+		def := schema.Types[typeName]
+		if def == nil || def.Kind != ast.Object {
+			problems = append(problems, fmt.Sprintf("%s: unknown type %s in %s%s", section, typeName, key, suggest(typeName, typeNames)))
+			return
+		}
+		if def.Fields.ForName(fieldName) == nil {
+			fieldNames := make([]string, 0, len(def.Fields))
+			for _, f := range def.Fields {
+				fieldNames = append(fieldNames, f.Name)
+			}
+			problems = append(problems, fmt.Sprintf("%s: type %s has no field %s%s", section, typeName, fieldName, suggest(fieldName, fieldNames)))
+		}
+	}
+	for _, key := range sortedKeys(config.Fields) {
+		checkField("fields", key)
+	}
+	for _, key := range sortedKeys(config.Relations) {
+		checkField("relations", key)
+		if rel := config.Relations[key]; rel.Type != "" && !relationTypes[rel.Type] {
+			problems = append(problems, fmt.Sprintf("relations: %s has unknown type %q%s", key, rel.Type, suggest(rel.Type, sortedKeys(relationTypes))))
+		}
+	}
+
+	return problems
+}
+
+// relationTypes are the relation kinds the SQL converter joins
+var relationTypes = map[string]bool{
+	"hasOne":     true,
+	"hasMany":    true,
+	"belongsTo":  true,
+	"manyToMany": true,
+}
+
+// directiveProblems checks the @sql directives on object fields: relations
+// must be of a known kind and point at an object type
+func directiveProblems(schema *ast.Schema) []string {
+	var problems []string
+	for _, typeName := range sortedKeys(schema.Types) {
+		def := schema.Types[typeName]
+		if def.Kind != ast.Object || strings.HasPrefix(typeName, "__") {
+			continue
+		}
+		for _, field := range def.Fields {
+			sql := field.Directives.ForName("sql")
+			if sql == nil {
+				continue
+			}
+			where := typeName + "." + field.Name
+
+			if arg := sql.Arguments.ForName("column"); arg != nil && arg.Value.Raw == "" {
+				problems = append(problems, where+": column must not be empty")
+			}
+
+			arg := sql.Arguments.ForName("relation")
+			if arg == nil {
+				continue
+			}
+			if !relationTypes[arg.Value.Raw] {
+				problems = append(problems, fmt.Sprintf("%s: unknown relation %q%s", where, arg.Value.Raw, suggest(arg.Value.Raw, sortedKeys(relationTypes))))
+			}
+			if target := schema.Types[getBaseTypeName(field.Type)]; target == nil || target.Kind != ast.Object {
+				problems = append(problems, fmt.Sprintf("%s: relation to %s, which is not an object type", where, getBaseTypeName(field.Type)))
+			}
+		}
+	}
+	return problems
+}
+
+// mappingProblems loads the schema and the generated mappings into a SQL
+// converter the way the generated code does, reporting what it rejects
+func mappingProblems(schemaContent string, config *Config, analysis *SchemaAnalysis) []string {
+	schema, err := graph.NewSchema(schemaContent)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	data := prepareGeneratedData(config, analysis)
+	converter := graph.NewSQLConverter(schema, dialect.PostgreSQL)
+	for _, m := range data.TableMappings {
+		converter.MapTypeToTable(m.TypeName, m.TableName)
+	}
+	for _, m := range data.FieldMappings {
+		converter.MapFieldToColumn(m.TypeName, m.FieldName, m.ColumnName)
+	}
+
+	var problems []string
+	for _, j := range data.JoinConfigs {
+		if j.SourceColumn == "" || j.TargetColumn == "" {
+			problems = append(problems, fmt.Sprintf("%s.%s: join needs both references and foreignKey columns", j.TypeName, j.FieldName))
+		}
+		converter.ConfigureJoin(j.TypeName, j.FieldName, &graph.JoinConfig{
+			SourceTable:  j.SourceTable,
+			SourceColumn: j.SourceColumn,
+			TargetTable:  j.TargetTable,
+			TargetColumn: j.TargetColumn,
+			RelationType: j.RelationType,
+		})
+	}
+	sort.Strings(problems)
+	if err := converter.InferInverseJoins(); err != nil {
+		problems = append(problems, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	return problems
+}
+
+// sortedKeys returns the keys of a config map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// suggest returns " (did you mean X?)" for the candidate closest to name, if
+// it is close enough to be a likely typo
+func suggest(name string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if bestDist < 0 || d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+	if bestDist < 0 || bestDist > max(2, len(name)/3) {
+		return ""
+	}
+	return " (did you mean " + best + "?)"
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
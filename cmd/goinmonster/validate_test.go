@@ -0,0 +1,67 @@
+package goinmonster
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// initProject runs init in a fresh directory and makes it the working directory
+func initProject(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := RunInit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	initProject(t)
+	if err := RunValidate(); err != nil {
+		t.Fatalf("fresh project: %v", err)
+	}
+
+	f, err := os.OpenFile("schema.graphqls", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\ntype Shelf { id: ID! owner: User @sql(relation: \"hasMnay\") }\n")
+	f.Close()
+
+	err = RunValidate()
+	if err == nil || !strings.Contains(err.Error(), "problem") {
+		t.Fatalf("unknown relation: got %v", err)
+	}
+}
+
+func TestGenerateRejectsUnknownConfigKeys(t *testing.T) {
+	initProject(t)
+	config, err := os.ReadFile("goinmonster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := strings.Replace(string(config), "  # Profile: profiles\n", "  # Profile: profiles\n  Uesr: users\n", 1)
+	s = strings.Replace(s, "fields: {}", "fields:\n  User.emial: email_address\n  name: full_name", 1)
+	s = strings.Replace(s, "relations: {}", "relations:\n  Invoice.customer:\n    type: belongsTo", 1)
+	if err := os.WriteFile("goinmonster.yaml", []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = RunGenerate()
+	if err == nil {
+		t.Fatal("misspelled config keys were accepted")
+	}
+	for _, want := range []string{
+		"models: unknown type Uesr (did you mean User?)",
+		"fields: type User has no field emial (did you mean email?)",
+		`fields: "name" must be Type.field`,
+		"relations: unknown type Invoice in Invoice.customer\n",
+	} {
+		if !strings.Contains(err.Error()+"\n", want) {
+			t.Errorf("error is missing %q:\n%v", want, err)
+		}
+	}
+	if _, statErr := os.Stat("graph/generated.go"); statErr == nil {
+		t.Error("code was generated from an invalid config")
+	}
+}
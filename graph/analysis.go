@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// OperationStats describes the shape and estimated cost of an operation
+type OperationStats struct {
+	OperationName string   `json:"operationName"`
+	OperationType string   `json:"operationType"`
+	Complexity    int      `json:"complexity"` // Each field costs 1; selections under a field with first/limit count that many times
+	Depth         int      `json:"depth"`      // Deepest field nesting; root fields are at depth 1
+	FieldCount    int      `json:"fieldCount"` // Field selections, counting fragment repeats
+	AliasCount    int      `json:"aliasCount"`
+	Types         []string `json:"types"` // Composite types the operation selects fields of, sorted
+}
+
+// AnalyzeOperation parses and validates query and computes its stats without
+// executing it. Arguments bound to variables count as unknown, so their list
+// sizes don't multiply the complexity.
+func (e *Executor) AnalyzeOperation(query string) (*OperationStats, error) {
+	op, err := e.Compile(query, "")
+	if err != nil {
+		return nil, err
+	}
+
+	selections, fieldCount, aliasCount := op.selections, op.fieldCount, op.aliasCount
+	if selections == nil {
+		collector := NewFieldCollector(e.schema, op.fragments, nil)
+		selections = collector.CollectFields(op.operation.SelectionSet, op.rootType)
+		fieldCount, aliasCount = collector.FieldCount(), collector.AliasCount()
+	}
+
+	stats := &OperationStats{
+		OperationName: op.operation.Name,
+		OperationType: string(op.operation.Operation),
+		FieldCount:    fieldCount,
+		AliasCount:    aliasCount,
+	}
+
+	types := make(map[string]bool)
+	stats.Complexity = e.analyzeSelections(selections, op.rootType, 1, stats, types)
+
+	stats.Types = make([]string, 0, len(types))
+	for name := range types {
+		stats.Types = append(stats.Types, name)
+	}
+	sort.Strings(stats.Types)
+
+	return stats, nil
+}
+
+// analyzeSelections records the depth and types of a selection set at depth
+// and returns its complexity
+func (e *Executor) analyzeSelections(selections *SelectionSet, parentType string, depth int, stats *OperationStats, types map[string]bool) int {
+	if selections == nil || len(selections.Fields) == 0 {
+		return 0
+	}
+	if depth > stats.Depth {
+		stats.Depth = depth
+	}
+	if !strings.HasPrefix(parentType, "__") {
+		types[parentType] = true
+	}
+
+	complexity := 0
+	for _, field := range selections.Fields {
+		complexity++
+
+		// Fields from fragments on a concrete type of an abstract parent
+		owner := parentType
+		if len(field.TypeConditions) > 0 {
+			owner = field.TypeConditions[0]
+			types[owner] = true
+		}
+		if strings.HasPrefix(field.Name, "__") || !field.HasSelection() {
+			continue
+		}
+
+		fieldType := e.schema.FieldBaseTypeName(owner, field.Name)
+		if fieldType == "" {
+			continue
+		}
+		types[fieldType] = true
+
+		children := e.analyzeSelections(field.Selections, fieldType, depth+1, stats, types)
+		complexity += children * listSize(field.Arguments)
+	}
+	return complexity
+}
+
+// listSize returns the page size requested through first or limit, or 1
+func listSize(args map[string]interface{}) int {
+	for _, name := range []string{"first", "limit"} {
+		if n, err := nonNegativeInt(name, args[name]); err == nil && n > 0 {
+			return int(n)
+		}
+	}
+	return 1
+}
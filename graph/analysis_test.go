@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeOperation(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { users(limit: Int): [User] search: SearchResult }
+type User { id: ID name: String posts(first: Int): [Post] }
+type Post { id: ID title: String }
+union SearchResult = User | Post
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := es.Executor.AnalyzeOperation(`query Dashboard($n: Int) {
+	users(limit: 10) { id posts(first: 5) { id title } }
+	search { ... on Post { title } }
+	recent: users(limit: $n) { name }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &OperationStats{
+		OperationName: "Dashboard",
+		OperationType: "query",
+		// users: 1 + 10 * (id + posts: 1 + 5 * 2); search: 1 + 1; recent: 1 + 1
+		Complexity: 125,
+		Depth:      3,
+		FieldCount: 9,
+		AliasCount: 1,
+		Types:      []string{"Post", "Query", "SearchResult", "User"},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %+v\nwant %+v", stats, want)
+	}
+
+	if _, err := es.Executor.AnalyzeOperation(`{ nope }`); err == nil {
+		t.Error("an invalid query was analyzed")
+	}
+}
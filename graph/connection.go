@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// PageInfo describes the position of a page within a connection
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// Edge wraps a node with the cursor pointing at it
+type Edge struct {
+	Cursor string      `json:"cursor"`
+	Node   interface{} `json:"node"`
+}
+
+// ConnectionResult is a cursor-paginated list of edges with page info
+type ConnectionResult struct {
+	Edges    []*Edge   `json:"edges"`
+	PageInfo *PageInfo `json:"pageInfo"`
+}
+
+// ConnectionBuilder builds a ConnectionResult from rows fetched with one extra
+// row beyond the page size, which tells whether a next page exists
+type ConnectionBuilder struct {
+	first        int
+	cursorFields []string
+	hasPrevious  bool
+}
+
+// NewConnectionBuilder creates a builder for a page of first rows whose cursors
+// encode the values of cursorFields (the sort key, e.g., "id")
+func NewConnectionBuilder(first int, cursorFields ...string) *ConnectionBuilder {
+	return &ConnectionBuilder{
+		first:        first,
+		cursorFields: cursorFields,
+	}
+}
+
+// SetHasPrevious marks the page as following earlier rows (e.g., 'after' was given)
+func (b *ConnectionBuilder) SetHasPrevious(hasPrevious bool) *ConnectionBuilder {
+	b.hasPrevious = hasPrevious
+	return b
+}
+
+// FetchLimit returns the LIMIT to query with: one more than the page size
+func (b *ConnectionBuilder) FetchLimit() int {
+	return b.first + 1
+}
+
+// Build converts the fetched rows into a connection, trimming the extra row
+func (b *ConnectionBuilder) Build(rows []map[string]interface{}) (*ConnectionResult, error) {
+	hasNext := len(rows) > b.first
+	if hasNext {
+		rows = rows[:b.first]
+	}
+
+	edges := make([]*Edge, 0, len(rows))
+	for _, row := range rows {
+		values := make([]interface{}, len(b.cursorFields))
+		for i, field := range b.cursorFields {
+			v, ok := row[field]
+			if !ok {
+				return nil, fmt.Errorf("cursor field %q missing from row", field)
+			}
+			values[i] = v
+		}
+
+		cursor, err := EncodeCursor(values)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, &Edge{Cursor: cursor, Node: row})
+	}
+
+	pageInfo := &PageInfo{
+		HasNextPage:     hasNext,
+		HasPreviousPage: b.hasPrevious,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &ConnectionResult{
+		Edges:    edges,
+		PageInfo: pageInfo,
+	}, nil
+}
+
+// EncodeCursor encodes sort key values as an opaque cursor
+func EncodeCursor(values []interface{}) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor back into sort key values
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectionBuilderPageInfo(t *testing.T) {
+	rows := func(n int) []map[string]interface{} {
+		r := make([]map[string]interface{}, n)
+		for i := range r {
+			r[i] = map[string]interface{}{"id": i + 1}
+		}
+		return r
+	}
+
+	b := NewConnectionBuilder(2, "id")
+	if b.FetchLimit() != 3 {
+		t.Errorf("FetchLimit = %d, want 3", b.FetchLimit())
+	}
+
+	// Exactly N rows: this is the last page
+	conn, err := b.Build(rows(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.PageInfo.HasNextPage || len(conn.Edges) != 2 {
+		t.Errorf("2 rows: hasNextPage = %v, %d edges", conn.PageInfo.HasNextPage, len(conn.Edges))
+	}
+
+	// N+1 rows: the extra row is trimmed and signals a next page
+	conn, err = b.Build(rows(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conn.PageInfo.HasNextPage || len(conn.Edges) != 2 {
+		t.Errorf("3 rows: hasNextPage = %v, %d edges", conn.PageInfo.HasNextPage, len(conn.Edges))
+	}
+	end, err := DecodeCursor(*conn.PageInfo.EndCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(end, []interface{}{float64(2)}) {
+		t.Errorf("endCursor decodes to %v, want the last returned row", end)
+	}
+	if *conn.PageInfo.StartCursor != conn.Edges[0].Cursor {
+		t.Error("startCursor is not the first edge's cursor")
+	}
+
+	conn, err = b.SetHasPrevious(true).Build(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conn.PageInfo.HasPreviousPage || conn.PageInfo.StartCursor != nil || conn.PageInfo.EndCursor != nil {
+		t.Errorf("empty page: %+v", conn.PageInfo)
+	}
+
+	if _, err := NewConnectionBuilder(2, "createdAt").Build(rows(1)); err == nil {
+		t.Error("a row without the cursor field was accepted")
+	}
+}
@@ -2,6 +2,8 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"sync"
 	"time"
 )
@@ -10,12 +12,18 @@ import (
 type contextKey string
 
 const (
-	operationCtxKey contextKey = "goinmonster:operation"
-	resolveInfoKey  contextKey = "goinmonster:resolveinfo"
-	requestCtxKey   contextKey = "goinmonster:request"
-	extensionsKey   contextKey = "goinmonster:extensions"
-	errorsKey       contextKey = "goinmonster:errors"
-	dataLoadersKey  contextKey = "goinmonster:dataloaders"
+	operationCtxKey   contextKey = "goinmonster:operation"
+	resolveInfoKey    contextKey = "goinmonster:resolveinfo"
+	requestCtxKey     contextKey = "goinmonster:request"
+	extensionsKey     contextKey = "goinmonster:extensions"
+	errorsKey         contextKey = "goinmonster:errors"
+	dataLoadersKey    contextKey = "goinmonster:dataloaders"
+	tableOverridesKey contextKey = "goinmonster:tableoverrides"
+	dbPoolKey         contextKey = "goinmonster:dbpool"
+	usePrimaryKey     contextKey = "goinmonster:useprimary"
+	tenantKey         contextKey = "goinmonster:tenant"
+	explainKey        contextKey = "goinmonster:explain"
+	subscriptionKey   contextKey = "goinmonster:subscription"
 )
 
 // RequestContext holds request-scoped data
@@ -31,6 +39,9 @@ type RequestContext struct {
 	OperationName string
 	Variables     map[string]interface{}
 
+	// Canonical form of the query, when the executor has a query normalizer
+	NormalizedQuery string
+
 	// Parsed operation
 	Operation *OperationContext
 
@@ -43,6 +54,14 @@ type RequestContext struct {
 
 	// Custom data storage
 	values map[string]interface{}
+
+	// Deadline of the operation, taken from the execution context
+	deadline    time.Time
+	hasDeadline bool
+
+	// Errors kept before the rest are dropped; 0 means no limit
+	maxErrors     int
+	errorsDropped bool
 }
 
 // Error represents a GraphQL error
@@ -51,6 +70,9 @@ type Error struct {
 	Locations  []Location             `json:"locations,omitempty"`
 	Path       []interface{}          `json:"path,omitempty"`
 	Extensions map[string]interface{} `json:"extensions,omitempty"`
+
+	// Err is the underlying error when this error wraps a resolver error
+	Err error `json:"-"`
 }
 
 // Location represents a location in a GraphQL document
@@ -64,6 +86,29 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// AsError converts err to a GraphQL error at path. A *Error anywhere in the
+// chain keeps its message and extensions; other errors keep the original in Err.
+func AsError(err error, path []interface{}) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		copied := *gqlErr
+		if copied.Path == nil {
+			copied.Path = path
+		}
+		return &copied
+	}
+	return &Error{
+		Message: err.Error(),
+		Path:    path,
+		Err:     err,
+	}
+}
+
 // NewError creates a new GraphQL error
 func NewError(message string, path []interface{}) *Error {
 	return &Error{
@@ -106,6 +151,14 @@ func GetRequestContext(ctx context.Context) *RequestContext {
 	return nil
 }
 
+// GetRequestID retrieves the request ID from a context
+func GetRequestID(ctx context.Context) string {
+	if rc := GetRequestContext(ctx); rc != nil {
+		return rc.RequestID
+	}
+	return ""
+}
+
 // WithOperationContext adds operation context to a context
 func WithOperationContext(ctx context.Context, oc *OperationContext) context.Context {
 	return context.WithValue(ctx, operationCtxKey, oc)
@@ -172,13 +225,33 @@ func (rc *RequestContext) GetInt(key string) int {
 	return 0
 }
 
-// AddError adds an error to the request context
+// AddError adds an error to the request context. Past the SetMaxErrors
+// limit, errors are dropped and a single "too many errors" error is added.
 func (rc *RequestContext) AddError(err *Error) {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
+	if rc.maxErrors > 0 && len(rc.Errors) >= rc.maxErrors {
+		if !rc.errorsDropped {
+			rc.errorsDropped = true
+			rc.Errors = append(rc.Errors, &Error{
+				Message: "too many errors",
+				Extensions: map[string]interface{}{
+					"code": "TOO_MANY_ERRORS",
+				},
+			})
+		}
+		return
+	}
 	rc.Errors = append(rc.Errors, err)
 }
 
+// SetMaxErrors limits how many errors the request collects; 0 means no limit
+func (rc *RequestContext) SetMaxErrors(n int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.maxErrors = n
+}
+
 // AddErrorMessage adds an error message to the request context
 func (rc *RequestContext) AddErrorMessage(message string) {
 	rc.AddError(&Error{Message: message})
@@ -211,6 +284,35 @@ func (rc *RequestContext) Duration() time.Duration {
 	return time.Since(rc.StartTime)
 }
 
+// SetDeadline sets the time by which the operation must complete
+func (rc *RequestContext) SetDeadline(deadline time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.deadline = deadline
+	rc.hasDeadline = true
+}
+
+// Deadline returns the operation deadline; ok is false when there is none
+func (rc *RequestContext) Deadline() (deadline time.Time, ok bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.deadline, rc.hasDeadline
+}
+
+// RemainingBudget returns the time left until the operation deadline, or
+// false when the operation has no deadline. It is never negative.
+func (rc *RequestContext) RemainingBudget() (time.Duration, bool) {
+	deadline, ok := rc.Deadline()
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
 // DataLoaderRegistry holds data loaders for a request
 type DataLoaderRegistry struct {
 	mu      sync.RWMutex
@@ -260,11 +362,160 @@ func GetDataLoader(ctx context.Context, name string) (*DataLoader, bool) {
 	return nil, false
 }
 
+// WithTableOverride makes the SQL converter read typeName from table for
+// conversions using the returned context (e.g., a view or replica table)
+func WithTableOverride(ctx context.Context, typeName, table string) context.Context {
+	overrides := make(map[string]string)
+	if existing, ok := ctx.Value(tableOverridesKey).(map[string]string); ok {
+		for k, v := range existing {
+			overrides[k] = v
+		}
+	}
+	overrides[typeName] = table
+	return context.WithValue(ctx, tableOverridesKey, overrides)
+}
+
+// GetTableOverride retrieves the table override for a type from a context
+func GetTableOverride(ctx context.Context, typeName string) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	overrides, ok := ctx.Value(tableOverridesKey).(map[string]string)
+	if !ok {
+		return "", false
+	}
+	table, ok := overrides[typeName]
+	return table, ok
+}
+
+// WithTenant sets the tenant that SQL conversions using the returned context
+// are scoped to (see SQLConverter.ConfigureTenantColumn)
+func WithTenant(ctx context.Context, tenantID interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// GetTenant retrieves the tenant from a context
+func GetTenant(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenantID := ctx.Value(tenantKey)
+	return tenantID, tenantID != nil
+}
+
+// WithDBPool adds a primary/replica database pair to a context
+func WithDBPool(ctx context.Context, pool *DBPool) context.Context {
+	return context.WithValue(ctx, dbPoolKey, pool)
+}
+
+// GetDBPool retrieves the database pair from a context
+func GetDBPool(ctx context.Context) *DBPool {
+	if pool, ok := ctx.Value(dbPoolKey).(*DBPool); ok {
+		return pool
+	}
+	return nil
+}
+
+// WithPrimary makes DBFromContext return the primary database, even for
+// queries (e.g., to read a row right after writing it)
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey, true)
+}
+
+// DBFromContext returns the database to run SQL on: the replica for query
+// operations and the primary for mutations, subscriptions and contexts from
+// WithPrimary. It returns nil when the context has no DBPool.
+func DBFromContext(ctx context.Context) DB {
+	pool := GetDBPool(ctx)
+	if pool == nil {
+		return nil
+	}
+	if usePrimary, _ := ctx.Value(usePrimaryKey).(bool); usePrimary {
+		return pool.Primary
+	}
+	operationType := ""
+	if oc := GetOperationContext(ctx); oc != nil {
+		operationType = oc.OperationType
+	}
+	return pool.For(operationType)
+}
+
+// ExplainedQuery is a SQL query recorded by SQLConverter.Explain
+type ExplainedQuery struct {
+	Field  string        `json:"field"` // e.g., "Query.users"
+	Query  string        `json:"query"`
+	Params []interface{} `json:"params"`
+}
+
+// explainLog collects the queries explained during one operation
+type explainLog struct {
+	mu      sync.Mutex
+	queries []ExplainedQuery
+}
+
+// WithExplain marks a context as explain-only: resolvers should produce SQL
+// with SQLConverter.Explain and not touch the database
+func WithExplain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainKey, &explainLog{})
+}
+
+// ExplainRequested reports whether a context is explain-only
+func ExplainRequested(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	_, ok := ctx.Value(explainKey).(*explainLog)
+	return ok
+}
+
+// ExplainedQueries returns the queries explained with a context from
+// WithExplain, in the order they were produced
+func ExplainedQueries(ctx context.Context) ([]ExplainedQuery, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	log, ok := ctx.Value(explainKey).(*explainLog)
+	if !ok {
+		return nil, false
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]ExplainedQuery{}, log.queries...), true
+}
+
 // Response represents a GraphQL response
 type Response struct {
-	Data       interface{}            `json:"data,omitempty"`
+	Data       interface{}            `json:"data"`
 	Errors     []*Error               `json:"errors,omitempty"`
 	Extensions map[string]interface{} `json:"extensions,omitempty"`
+
+	// executed is set once execution started, so data is reported (possibly
+	// null) even when field errors nulled it out
+	executed bool
+}
+
+// MarshalJSON follows the spec's presence rules: "data" is present whenever
+// execution started or nothing failed, and absent for request errors raised
+// before execution (parse, validation, operation selection)
+func (r Response) MarshalJSON() ([]byte, error) {
+	if r.IsRequestError() {
+		return json.Marshal(struct {
+			Errors     []*Error               `json:"errors"`
+			Extensions map[string]interface{} `json:"extensions,omitempty"`
+		}{r.Errors, r.Extensions})
+	}
+
+	return json.Marshal(struct {
+		Data       interface{}            `json:"data"`
+		Errors     []*Error               `json:"errors,omitempty"`
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}{r.Data, r.Errors, r.Extensions})
+}
+
+// IsRequestError returns true if the request failed before execution, so the
+// response has errors but no data
+func (r *Response) IsRequestError() bool {
+	return r.Data == nil && !r.executed && len(r.Errors) > 0
 }
 
 // HasData returns true if response has data
@@ -2,7 +2,11 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/eddieafk/goinmonster/graph/marshal"
@@ -17,8 +21,34 @@ type SQLConverter struct {
 	dialect    dialect.Dialect
 	marshaler  *marshal.PostgreSQLMarshaler
 	tableMap   map[string]string            // GraphQL type -> SQL table
+	schemaMap  map[string]string            // GraphQL type -> SQL schema
 	columnMap  map[string]map[string]string // type.field -> SQL column
 	joinConfig map[string]*JoinConfig       // type.field -> join configuration
+	fullText   map[string]*FullTextConfig   // type -> full-text search configuration
+	filterable map[string]bool              // type.field -> filterable override
+	sortable   map[string]bool              // type.field -> sortable override
+	casts      map[string]string            // type.field -> SQL cast type
+	indexed    map[string]bool              // type.field -> indexed override
+	readonly   map[string]bool              // type -> readonly override
+	primaryKey map[string][]string          // type -> primary key columns
+	tenantCols map[string]string            // type -> tenant column
+	sortExprs  map[string]string            // type.sortKey -> ORDER BY expression
+
+	inheritance map[string]*InheritanceConfig // interface -> single-table inheritance
+
+	// Layout of generated queries
+	format SQLFormat
+
+	// Append the primary key to ORDER BY so ties sort consistently
+	orderTiebreaker bool
+
+	// Advisory warnings for filters/sorts on columns not declared indexed
+	indexWarnings bool
+	warnings      []string
+
+	orderByEnums map[string]map[string]*OrderByEnumValue // enum -> value -> sort
+
+	subscriptions map[string]*subscriptionConfig // type.field -> LISTEN channel and query
 }
 
 // JoinConfig describes how to join related types
@@ -32,6 +62,31 @@ type JoinConfig struct {
 	ThroughTable  string // For manyToMany
 	ThroughSource string // For manyToMany
 	ThroughTarget string // For manyToMany
+
+	// On replaces the generated join condition; {source} and {target} stand
+	// for the two tables' aliases (e.g., "{source}.id = {target}.owner_id
+	// AND {source}.tenant = {target}.tenant"). Trusted input, used verbatim.
+	On string
+}
+
+// FullTextConfig describes the columns searched by the 'search' argument
+type FullTextConfig struct {
+	Columns []string // SQL columns combined into the tsvector
+	Config  string   // Text search configuration (e.g., "english"); empty uses the server default
+}
+
+// InheritanceConfig stores all implementations of an interface in one table,
+// with a discriminator column naming the concrete type of each row
+type InheritanceConfig struct {
+	Table               string
+	DiscriminatorColumn string
+	TypeValues          map[string]string // concrete type -> discriminator value
+}
+
+// OrderByEnumValue maps an enum-style sort value (e.g., NAME_ASC) to a field and direction
+type OrderByEnumValue struct {
+	Field     string
+	Direction ast.OrderDirection
 }
 
 // NewSQLConverter creates a new SQL converter
@@ -41,16 +96,69 @@ func NewSQLConverter(schema *Schema, d dialect.Dialect) *SQLConverter {
 		dialect:    d,
 		marshaler:  marshal.NewPostgreSQLMarshaler(),
 		tableMap:   make(map[string]string),
+		schemaMap:  make(map[string]string),
 		columnMap:  make(map[string]map[string]string),
 		joinConfig: make(map[string]*JoinConfig),
+		fullText:   make(map[string]*FullTextConfig),
+		filterable: make(map[string]bool),
+		sortable:   make(map[string]bool),
+		casts:      make(map[string]string),
+		indexed:    make(map[string]bool),
+		readonly:   make(map[string]bool),
+		primaryKey: make(map[string][]string),
+		tenantCols: make(map[string]string),
+		sortExprs:  make(map[string]string),
+
+		inheritance: make(map[string]*InheritanceConfig),
+
+		orderByEnums: make(map[string]map[string]*OrderByEnumValue),
+
+		subscriptions: make(map[string]*subscriptionConfig),
 	}
 }
 
-// MapTypeToTable maps a GraphQL type to a SQL table
+// MapTypeToTable maps a GraphQL type to a SQL table. The table may be
+// schema-qualified (e.g., "analytics.events").
 func (c *SQLConverter) MapTypeToTable(typeName, tableName string) {
 	c.tableMap[typeName] = tableName
 }
 
+// MapTypeToSchema places a GraphQL type's table in a SQL schema (e.g., "analytics")
+func (c *SQLConverter) MapTypeToSchema(typeName, schemaName string) {
+	c.schemaMap[typeName] = schemaName
+}
+
+// quoteTable quotes a table name, quoting each part of a schema-qualified name
+// separately ("analytics"."events")
+func (c *SQLConverter) quoteTable(tableName string) string {
+	parts := strings.Split(tableName, ".")
+	for i, part := range parts {
+		parts[i] = c.dialect.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ConfigureInheritance maps an interface and its implementations to a single
+// table. Selecting the interface projects the runtime type as __typename;
+// selecting an implementation filters rows by its discriminator value.
+func (c *SQLConverter) ConfigureInheritance(interfaceName string, config *InheritanceConfig) {
+	c.inheritance[interfaceName] = config
+}
+
+// inheritanceFor returns the inheritance config of an interface or of one of its
+// implementations, along with the discriminator value for an implementation
+func (c *SQLConverter) inheritanceFor(typeName string) (*InheritanceConfig, string, bool) {
+	if cfg, ok := c.inheritance[typeName]; ok {
+		return cfg, "", true
+	}
+	for _, cfg := range c.inheritance {
+		if value, ok := cfg.TypeValues[typeName]; ok {
+			return cfg, value, true
+		}
+	}
+	return nil, "", false
+}
+
 // MapFieldToColumn maps a GraphQL field to a SQL column
 func (c *SQLConverter) MapFieldToColumn(typeName, fieldName, columnName string) {
 	if c.columnMap[typeName] == nil {
@@ -65,13 +173,496 @@ func (c *SQLConverter) ConfigureJoin(typeName, fieldName string, config *JoinCon
 	c.joinConfig[key] = config
 }
 
-// getTableName gets the SQL table name for a GraphQL type
-func (c *SQLConverter) getTableName(typeName string) string {
+// InferInverseJoins configures the reverse side of each belongsTo join, so
+// declaring Post.author as belongsTo User also makes User.posts queryable as
+// hasMany (or hasOne for a singular field). A reverse field is only inferred
+// when it is the one field of the target type pointing back and the source
+// type has one belongsTo join to the target; otherwise it must be configured
+// with ConfigureJoin. Joins already configured are left as they are.
+func (c *SQLConverter) InferInverseJoins() error {
+	keys := make([]string, 0, len(c.joinConfig))
+	for key, cfg := range c.joinConfig {
+		if cfg.RelationType == "belongsTo" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// source type -> target type -> belongsTo fields, to detect ambiguity
+	belongsTo := make(map[string]map[string][]string)
+	for _, key := range keys {
+		typeName, fieldName, _ := strings.Cut(key, ".")
+		target := c.schema.FieldBaseTypeName(typeName, fieldName)
+		if belongsTo[typeName] == nil {
+			belongsTo[typeName] = make(map[string][]string)
+		}
+		belongsTo[typeName][target] = append(belongsTo[typeName][target], fieldName)
+	}
+
+	var problems []string
+	for _, key := range keys {
+		typeName, fieldName, _ := strings.Cut(key, ".")
+		target := c.schema.FieldBaseTypeName(typeName, fieldName)
+		targetType, ok := c.schema.GetType(target)
+		if !ok {
+			continue
+		}
+
+		// Fields of the target type pointing back that have no join yet
+		var candidates []string
+		for name := range targetType.Fields {
+			if _, configured := c.joinConfig[target+"."+name]; configured {
+				continue
+			}
+			if c.schema.FieldBaseTypeName(target, name) == typeName {
+				candidates = append(candidates, name)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		sort.Strings(candidates)
+
+		if fields := belongsTo[typeName][target]; len(fields) > 1 {
+			if fields[0] == fieldName {
+				problems = append(problems, fmt.Sprintf("%s has several belongsTo joins to %s (%s); configure %s.%s explicitly",
+					typeName, target, strings.Join(fields, ", "), target, strings.Join(candidates, ", "+target+".")))
+			}
+			continue
+		}
+		if len(candidates) > 1 {
+			problems = append(problems, fmt.Sprintf("%s.%s: several fields of %s point back (%s); configure the inverse explicitly",
+				typeName, fieldName, target, strings.Join(candidates, ", ")))
+			continue
+		}
+
+		cfg := c.joinConfig[key]
+		relation := "hasOne"
+		if ref, ok := c.schema.FieldType(target, candidates[0]); ok && ref.IsList {
+			relation = "hasMany"
+		}
+		c.joinConfig[target+"."+candidates[0]] = &JoinConfig{
+			SourceTable:  cfg.TargetTable,
+			SourceColumn: cfg.TargetColumn,
+			TargetTable:  cfg.SourceTable,
+			TargetColumn: cfg.SourceColumn,
+			JoinType:     ast.JoinLeft, // Rows without related rows are kept
+			RelationType: relation,
+			On:           strings.NewReplacer("{source}", "{target}", "{target}", "{source}").Replace(cfg.On),
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot infer inverse joins:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// SetFilterable allows or rejects filters on a field, overriding @sql(filterable: ...)
+func (c *SQLConverter) SetFilterable(typeName, fieldName string, filterable bool) {
+	c.filterable[typeName+"."+fieldName] = filterable
+}
+
+// isFilterable reports whether filters may reference a field (default: allowed)
+func (c *SQLConverter) isFilterable(typeName, fieldName string) bool {
+	if filterable, ok := c.filterable[typeName+"."+fieldName]; ok {
+		return filterable
+	}
+	if objType, ok := c.schema.GetType(typeName); ok {
+		if field, ok := objType.Fields[fieldName]; ok {
+			return field.Filterable
+		}
+	}
+	return true
+}
+
+// SetSortable allows or rejects orderBy on a field, overriding @sql(sortable: ...)
+func (c *SQLConverter) SetSortable(typeName, fieldName string, sortable bool) {
+	c.sortable[typeName+"."+fieldName] = sortable
+}
+
+// isSortable reports whether orderBy may reference a field (default: allowed)
+func (c *SQLConverter) isSortable(typeName, fieldName string) bool {
+	if sortable, ok := c.sortable[typeName+"."+fieldName]; ok {
+		return sortable
+	}
+	if objType, ok := c.schema.GetType(typeName); ok {
+		if field, ok := objType.Fields[fieldName]; ok {
+			return field.Sortable
+		}
+	}
+	return true
+}
+
+// SetColumnCast casts a field's column and bound parameters to a SQL type
+// (e.g., "uuid"), overriding @sql(cast: ...)
+func (c *SQLConverter) SetColumnCast(typeName, fieldName, castType string) {
+	c.casts[typeName+"."+fieldName] = castType
+}
+
+// castFor returns the SQL cast configured for a field, if any
+func (c *SQLConverter) castFor(typeName, fieldName string) (string, error) {
+	castType, ok := c.casts[typeName+"."+fieldName]
+	if !ok {
+		if objType, ok := c.schema.GetType(typeName); ok {
+			if field, ok := objType.Fields[fieldName]; ok {
+				castType = field.SQLCast
+			}
+		}
+	}
+	if castType == "" {
+		return "", nil
+	}
+	if !castTypePattern.MatchString(castType) {
+		return "", fmt.Errorf("invalid cast type %q on %s.%s", castType, typeName, fieldName)
+	}
+	return c.dialect.FormatCast(castType), nil
+}
+
+// jsonExtract returns the expression reading a field's @sql(jsonExtract: ...)
+// path from its JSON column as text (e.g., (u."data" #>> '{profile,bio}')),
+// or "" when the field maps to a plain column
+func (c *SQLConverter) jsonExtract(typeName, fieldName, tableAlias string) string {
+	column, path := c.jsonSource(typeName, fieldName)
+	if len(path) == 0 {
+		return ""
+	}
+	// Path keys are restricted to [A-Za-z0-9_-] when the schema is built
+	return fmt.Sprintf("(%s.%s #>> '{%s}')",
+		tableAlias,
+		c.dialect.QuoteIdentifier(column),
+		strings.Join(path, ","),
+	)
+}
+
+// jsonSource returns the JSON column and path of a field mapped with
+// @sql(jsonExtract: ...), or a nil path for a plain column
+func (c *SQLConverter) jsonSource(typeName, fieldName string) (string, []string) {
+	objType, ok := c.schema.GetType(typeName)
+	if !ok {
+		return "", nil
+	}
+	field, ok := objType.Fields[fieldName]
+	if !ok || len(field.SQLJSONPath) == 0 {
+		return "", nil
+	}
+	// The JSON column is usually shared, named by @sql(column: ...)
+	column := c.getColumnName(typeName, fieldName)
+	if _, mapped := c.columnMap[typeName][fieldName]; !mapped && field.SQLColumn != "" {
+		column = field.SQLColumn
+	}
+	return column, field.SQLJSONPath
+}
+
+// sourceColumn returns the column a field reads: its JSON column for
+// @sql(jsonExtract: ...) fields and its mapped column otherwise
+func (c *SQLConverter) sourceColumn(typeName, fieldName string) string {
+	if column, path := c.jsonSource(typeName, fieldName); len(path) > 0 {
+		return column
+	}
+	return c.getColumnName(typeName, fieldName)
+}
+
+// castTypePattern matches SQL type names such as uuid, varchar(20), numeric(10, 2) or text[]
+var castTypePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .]*(\(\d+(,\s*\d+)?\))?(\[\])?$`)
+
+// SetSortExpression makes orderBy sort key sortKey of a type (a field name or
+// any other key) sort by a SQL expression such as LOWER({table}.name), where
+// {table} is the alias of the type's table. The expression is trusted
+// configuration and is only checked for terminators, comments and balance.
+func (c *SQLConverter) SetSortExpression(typeName, sortKey, expr string) error {
+	if err := marshal.ValidateSortExpression(expr); err != nil {
+		return err
+	}
+	c.sortExprs[typeName+"."+sortKey] = expr
+	return nil
+}
+
+// checkColumnField rejects client-supplied keys that are not scalar fields of
+// the type or columns mapped with MapFieldToColumn
+func (c *SQLConverter) checkColumnField(kind, typeName, key string) error {
+	if _, mapped := c.columnMap[typeName][key]; mapped {
+		return nil
+	}
+	objType, ok := c.schema.GetType(typeName)
+	if !ok {
+		return fmt.Errorf("%s: unknown type %s", kind, typeName)
+	}
+	field, ok := objType.Fields[key]
+	if !ok || !c.schema.IsLeafType(unwrapTypeName(field.Type)) {
+		return fmt.Errorf("%s: %q is not a field of %s", kind, key, typeName)
+	}
+	return nil
+}
+
+// sortTarget returns what ORDER BY sorts by for a sort key: its configured
+// expression, or the column of the sortable field of that name. Sort keys
+// come from clients, so keys that are not columns of the type are rejected.
+func (c *SQLConverter) sortTarget(typeName, sortKey, tableAlias string) (string, error) {
+	if expr, ok := c.sortExprs[typeName+"."+sortKey]; ok {
+		return strings.ReplaceAll(expr, "{table}", tableAlias), nil
+	}
+	if err := c.checkColumnField("orderBy", typeName, sortKey); err != nil {
+		return "", err
+	}
+	if !c.isSortable(typeName, sortKey) {
+		return "", fmt.Errorf("field %s.%s is not sortable", typeName, sortKey)
+	}
+	c.warnUnindexed("sort", typeName, sortKey)
+	if extract := c.jsonExtract(typeName, sortKey, tableAlias); extract != "" {
+		return extract, nil
+	}
+	return tableAlias + "." + c.dialect.QuoteIdentifier(c.getColumnName(typeName, sortKey)), nil
+}
+
+// SetIndexed declares whether a field's column is indexed, overriding @sql(index: ...)
+func (c *SQLConverter) SetIndexed(typeName, fieldName string, indexed bool) {
+	c.indexed[typeName+"."+fieldName] = indexed
+}
+
+// joinOn returns the custom join condition of a relation, from
+// JoinConfig.On or @sql(joinOn: ...), with the table aliases filled in
+func (c *SQLConverter) joinOn(typeName, fieldName string, cfg *JoinConfig, source, target string) string {
+	on := cfg.On
+	if on == "" {
+		if objType, ok := c.schema.GetType(typeName); ok {
+			if field, ok := objType.Fields[fieldName]; ok {
+				on = field.SQLJoinOn
+			}
+		}
+	}
+	if on == "" {
+		return ""
+	}
+	return strings.NewReplacer("{source}", source, "{target}", target).Replace(on)
+}
+
+// ConfigureTenantColumn scopes a type to the tenant set with WithTenant:
+// SELECT, UPDATE and DELETE statements and upsert updates on its table
+// always match column against the context's tenant, and converting them
+// without one fails
+func (c *SQLConverter) ConfigureTenantColumn(typeName, column string) {
+	c.tenantCols[typeName] = column
+}
+
+// tenantCondition returns the tenant predicate for a type's table, or ""
+// when the type isn't tenant-scoped
+func (c *SQLConverter) tenantCondition(ctx context.Context, typeName, tableAlias string) (string, error) {
+	column, ok := c.tenantCols[typeName]
+	if !ok {
+		return "", nil
+	}
+	tenantID, ok := GetTenant(ctx)
+	if !ok {
+		return "", fmt.Errorf("type %s is tenant-scoped but the context has no tenant", typeName)
+	}
+	return tableAlias + "." + c.dialect.QuoteIdentifier(column) + " = " + c.marshaler.AddParam(tenantID), nil
+}
+
+// scopeToTenant adds the tenant predicate to where. Client clauses are
+// parenthesized so none can combine with the predicate other than by AND.
+func (c *SQLConverter) scopeToTenant(ctx context.Context, typeName, tableAlias string, where []string) ([]string, error) {
+	condition, err := c.tenantCondition(ctx, typeName, tableAlias)
+	if err != nil || condition == "" {
+		return where, err
+	}
+	scoped := make([]string, 0, len(where)+1)
+	for _, clause := range where {
+		scoped = append(scoped, "("+clause+")")
+	}
+	return append(scoped, condition), nil
+}
+
+// tenantTypeForTable returns the tenant-scoped type read from table, or ""
+func (c *SQLConverter) tenantTypeForTable(ctx context.Context, table string) string {
+	types := make([]string, 0, len(c.tenantCols))
+	for typeName := range c.tenantCols {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	for _, typeName := range types {
+		if c.getTableName(ctx, typeName) == table {
+			return typeName
+		}
+	}
+	return ""
+}
+
+// SetReadonly declares whether a type rejects inserts, updates and deletes,
+// overriding @sql(readonly: ...). Use it for types backed by views.
+func (c *SQLConverter) SetReadonly(typeName string, readonly bool) {
+	c.readonly[typeName] = readonly
+}
+
+// checkWritable returns an error when typeName is readonly
+func (c *SQLConverter) checkWritable(typeName string) error {
+	readonly, ok := c.readonly[typeName]
+	if !ok {
+		if objType, found := c.schema.GetType(typeName); found {
+			readonly = objType.SQLReadonly
+		}
+	}
+	if readonly {
+		return fmt.Errorf("type %s is readonly and cannot be mutated", typeName)
+	}
+	return nil
+}
+
+// SetPrimaryKey sets the primary key columns of a type's table, overriding
+// @sql(primaryKey:); the default is "id"
+func (c *SQLConverter) SetPrimaryKey(typeName string, columns ...string) {
+	c.primaryKey[typeName] = columns
+}
+
+// primaryKeyColumns returns the primary key columns of a type's table
+func (c *SQLConverter) primaryKeyColumns(typeName string) []string {
+	if columns, ok := c.primaryKey[typeName]; ok {
+		return columns
+	}
+	if objType, ok := c.schema.GetType(typeName); ok && len(objType.SQLPrimaryKey) > 0 {
+		return objType.SQLPrimaryKey
+	}
+	return []string{"id"}
+}
+
+// SetOrderTiebreaker enables appending the primary key to every ORDER BY
+// that doesn't already include it, so rows with equal sort values keep a
+// stable order across pages
+func (c *SQLConverter) SetOrderTiebreaker(enabled bool) {
+	c.orderTiebreaker = enabled
+}
+
+// addOrderTiebreaker appends the primary key columns missing from opts.OrderBy
+func (c *SQLConverter) addOrderTiebreaker(typeName string, opts *dialecttypes.PostgreSQLSelectOptions) {
+	// Grouped rows have no primary key to break ties with
+	if !c.orderTiebreaker || len(opts.OrderBy) == 0 || len(opts.GroupBy) > 0 {
+		return
+	}
+
+	for _, pk := range c.primaryKeyColumns(typeName) {
+		column := opts.TableAlias + "." + c.dialect.QuoteIdentifier(pk)
+		present := false
+		for _, col := range opts.OrderBy {
+			if col.Column == column {
+				present = true
+				break
+			}
+		}
+		if !present {
+			opts.OrderBy = append(opts.OrderBy, dialecttypes.OrderByColumn{
+				Column:    column,
+				Direction: ast.OrderAsc,
+			})
+		}
+	}
+}
+
+// SetIndexWarnings enables warnings in SQLSelectResult.Warnings for filters
+// and sorts on columns not declared indexed. Fields named "id" count as indexed.
+func (c *SQLConverter) SetIndexWarnings(enabled bool) {
+	c.indexWarnings = enabled
+}
+
+// warnUnindexed records a warning when usage ("filter", "sort") targets a
+// column not declared indexed
+func (c *SQLConverter) warnUnindexed(usage, typeName, fieldName string) {
+	if !c.indexWarnings || fieldName == "id" {
+		return
+	}
+	indexed, ok := c.indexed[typeName+"."+fieldName]
+	if !ok {
+		if objType, found := c.schema.GetType(typeName); found {
+			if field, found := objType.Fields[fieldName]; found {
+				indexed = field.SQLIndex != ""
+			}
+		}
+	}
+	if indexed {
+		return
+	}
+
+	warning := fmt.Sprintf("%s on %s.%s uses column %q, which is not declared indexed",
+		usage, typeName, fieldName, c.getColumnName(typeName, fieldName))
+	if !containsString(c.warnings, warning) {
+		c.warnings = append(c.warnings, warning)
+	}
+}
+
+// MapEnumValue maps a GraphQL enum value to the value stored in the database
+// (e.g., ACTIVE -> "active"). Filters bind the database value and results are
+// mapped back to the enum value name.
+func (c *SQLConverter) MapEnumValue(enumName, graphqlValue string, dbValue interface{}) error {
+	return c.schema.SetEnumValue(enumName, graphqlValue, dbValue)
+}
+
+// enumToDB converts enum value names in a filter operand to their database values
+func (c *SQLConverter) enumToDB(enumName string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if dbValue, ok := c.schema.EnumInternalValue(enumName, v); ok {
+			return dbValue
+		}
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			mapped[i] = c.enumToDB(enumName, item)
+		}
+		return mapped
+	}
+	return value
+}
+
+// ConfigureFullTextSearch configures the columns matched by the 'search' argument for a type
+func (c *SQLConverter) ConfigureFullTextSearch(typeName string, columns []string, config string) {
+	c.fullText[typeName] = &FullTextConfig{
+		Columns: columns,
+		Config:  config,
+	}
+}
+
+// ConfigureOrderByEnum maps an enum sort value to a field and direction, for
+// enums that don't follow the <FIELD>_<ASC|DESC> naming convention
+func (c *SQLConverter) ConfigureOrderByEnum(enumName, value, fieldName string, direction ast.OrderDirection) {
+	if c.orderByEnums[enumName] == nil {
+		c.orderByEnums[enumName] = make(map[string]*OrderByEnumValue)
+	}
+	c.orderByEnums[enumName][value] = &OrderByEnumValue{
+		Field:     fieldName,
+		Direction: direction,
+	}
+}
+
+// getTableName gets the SQL table name for a GraphQL type, preferring a
+// per-request override set with WithTableOverride
+func (c *SQLConverter) getTableName(ctx context.Context, typeName string) string {
+	if table, ok := GetTableOverride(ctx, typeName); ok {
+		return c.qualifyTable(typeName, table)
+	}
 	if table, ok := c.tableMap[typeName]; ok {
-		return table
+		return c.qualifyTable(typeName, table)
+	}
+	if objType, ok := c.schema.GetType(typeName); ok && objType.SQLTable != "" {
+		return c.qualifyTable(typeName, objType.SQLTable)
+	}
+	if cfg, _, ok := c.inheritanceFor(typeName); ok {
+		return c.qualifyTable(typeName, cfg.Table)
 	}
 	// Default: snake_case of type name
-	return toSnakeCase(typeName)
+	return c.qualifyTable(typeName, toSnakeCase(typeName))
+}
+
+// qualifyTable prefixes an unqualified table with the type's mapped schema
+func (c *SQLConverter) qualifyTable(typeName, table string) string {
+	if strings.Contains(table, ".") {
+		return table
+	}
+	if schemaName, ok := c.schemaMap[typeName]; ok {
+		return schemaName + "." + table
+	}
+	if objType, ok := c.schema.GetType(typeName); ok && objType.SQLSchema != "" {
+		return objType.SQLSchema + "." + table
+	}
+	return table
 }
 
 // getColumnName gets the SQL column name for a GraphQL field
@@ -92,6 +683,112 @@ type SQLSelectResult struct {
 	Options  dialecttypes.PostgreSQLSelectOptions
 	Errors   []dialecttypes.ValidationError
 	Warnings []string
+
+	// ColumnMapping tells where each result column goes in the response
+	// (see SQLConverter.ScanSelect)
+	ColumnMapping []ColumnPath
+}
+
+// ColumnPath maps a result column to its response path, e.g., the joined
+// column "a_aut_name" to ["author", "name"]. A column shared by several
+// aliased selections appears once per path.
+type ColumnPath struct {
+	Column string
+	Path   []string
+
+	// List marks the columns of a hasMany relation: each joined row is one
+	// item of the list at Path[0]
+	List bool
+	// Key marks a primary key column of the root type, projected so rows of
+	// the same parent can be grouped; it has no Path
+	Key bool
+}
+
+// Explain returns the SELECT that ConvertToSelect produces for a field,
+// without executing anything. With a context from WithExplain, the query is
+// also recorded for ExplainedQueries.
+func (c *SQLConverter) Explain(ctx context.Context, info *ResolveInfo) (*SQLSelectResult, error) {
+	result, err := c.ConvertToSelect(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	if log, ok := ctx.Value(explainKey).(*explainLog); ok {
+		log.mu.Lock()
+		log.queries = append(log.queries, ExplainedQuery{
+			Field:  info.ParentType + "." + info.FieldName,
+			Query:  result.Query,
+			Params: result.Params,
+		})
+		log.mu.Unlock()
+	}
+	return result, nil
+}
+
+// QueryPlan is the parsed output of EXPLAIN (FORMAT JSON) for a query
+type QueryPlan struct {
+	NodeType      string  // Root plan node, e.g., "Seq Scan"
+	StartupCost   float64 // Estimated cost before the first row
+	TotalCost     float64 // Estimated cost of the whole query
+	PlanRows      float64 // Estimated number of rows
+	ActualTime    float64 // Milliseconds spent in the root node (ANALYZE only)
+	ActualRows    float64 // Rows actually returned (ANALYZE only)
+	PlanningTime  float64 // Milliseconds (ANALYZE only)
+	ExecutionTime float64 // Milliseconds (ANALYZE only)
+
+	// Plan is the full root plan node, including its sub-plans
+	Plan map[string]interface{}
+}
+
+// ExplainPlan runs a SELECT wrapped in EXPLAIN and parses the plan. The
+// format is always JSON; with opts.Analyze the query is actually executed.
+func (c *SQLConverter) ExplainPlan(ctx context.Context, db DB, result *SQLSelectResult, opts dialecttypes.ExplainOptions) (*QueryPlan, error) {
+	opts.Format = "JSON"
+	rows, err := db.QueryContext(ctx, c.dialect.WrapExplain(result.Query, opts), result.Params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var raw []byte
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("explain returned no plan")
+	}
+	if err := rows.Scan(&raw); err != nil {
+		return nil, err
+	}
+
+	var plans []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		PlanningTime  float64                `json:"Planning Time"`
+		ExecutionTime float64                `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return nil, fmt.Errorf("cannot parse plan: %w", err)
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("explain returned no plan")
+	}
+
+	root := plans[0].Plan
+	number := func(key string) float64 {
+		n, _ := root[key].(float64)
+		return n
+	}
+	nodeType, _ := root["Node Type"].(string)
+	return &QueryPlan{
+		NodeType:      nodeType,
+		StartupCost:   number("Startup Cost"),
+		TotalCost:     number("Total Cost"),
+		PlanRows:      number("Plan Rows"),
+		ActualTime:    number("Actual Total Time"),
+		ActualRows:    number("Actual Rows"),
+		PlanningTime:  plans[0].PlanningTime,
+		ExecutionTime: plans[0].ExecutionTime,
+		Plan:          root,
+	}, nil
 }
 
 // ConvertToSelect converts a GraphQL query to SQL SELECT
@@ -100,6 +797,7 @@ func (c *SQLConverter) ConvertToSelect(
 	info *ResolveInfo,
 ) (*SQLSelectResult, error) {
 	c.marshaler.Reset()
+	c.warnings = nil
 
 	// Determine the root type and table
 	rootType := info.ReturnType
@@ -108,26 +806,89 @@ func (c *SQLConverter) ConvertToSelect(
 	}
 
 	typeName := unwrapTypeName(rootType)
-	tableName := c.getTableName(typeName)
+	tableName := c.getTableName(ctx, typeName)
+	tableAlias := strings.ToLower(typeName[:1])
+
+	// Scalar/enum return types have no selection set; project the single
+	// column configured on the field via @sql(table: ..., column: ...)
+	leafColumn := ""
+	if c.schema.IsLeafType(typeName) {
+		var fieldDef *FieldDefinition
+		if parent, ok := c.schema.GetType(info.ParentType); ok {
+			fieldDef = parent.Fields[info.FieldName]
+		}
+		if fieldDef == nil || fieldDef.SQLTable == "" {
+			return nil, fmt.Errorf("field %s.%s returns scalar type %s; map it with @sql(table: ..., column: ...)",
+				info.ParentType, info.FieldName, typeName)
+		}
+
+		tableName = fieldDef.SQLTable
+		tableAlias = strings.ToLower(tableName[:1])
+		leafColumn = fieldDef.SQLColumn
+		if leafColumn == "" {
+			leafColumn = toSnakeCase(info.FieldName)
+		}
+	}
 
 	// Build select options
 	opts := dialecttypes.PostgreSQLSelectOptions{
-		TableName:  c.dialect.QuoteIdentifier(tableName),
-		TableAlias: strings.ToLower(typeName[:1]),
+		TableName:  c.quoteTable(tableName),
+		TableAlias: tableAlias,
 		Columns:    make([]string, 0),
 		Joins:      make([]ast.JoinColumn, 0),
 		Where:      make([]string, 0),
 	}
 
-	// Collect columns from selection set
-	columns, joins := c.collectColumnsAndJoins(typeName, opts.TableAlias, info.Selection)
-	opts.Columns = columns
-	opts.Joins = joins
+	var mapping []ColumnPath
+	if leafColumn != "" {
+		opts.Columns = append(opts.Columns, tableAlias+"."+c.dialect.QuoteIdentifier(leafColumn))
+	} else {
+		// Collect columns from selection set
+		columns, joins, paths, err := c.collectColumnsAndJoins(ctx, typeName, opts.TableAlias, info.Selection)
+		if err != nil {
+			return nil, err
+		}
+		opts.Columns = columns
+		opts.Joins = joins
+		mapping = paths
+
+		// hasMany joins repeat the parent once per joined row; project its
+		// key so ScanSelect can fold the rows back together
+		if hasListColumns(mapping) {
+			for _, pk := range c.primaryKeyColumns(typeName) {
+				output := "__key_" + pk
+				opts.Columns = append(opts.Columns, fmt.Sprintf("%s.%s AS %s",
+					tableAlias, c.dialect.QuoteIdentifier(pk), c.dialect.QuoteIdentifier(output)))
+				mapping = append(mapping, ColumnPath{Column: output, Key: true})
+			}
+		}
+	}
+
+	// Single-table inheritance: interfaces project the runtime type, and
+	// implementations only match their own rows
+	if cfg, value, ok := c.inheritanceFor(typeName); ok && leafColumn == "" {
+		discriminator := opts.TableAlias + "." + c.dialect.QuoteIdentifier(cfg.DiscriminatorColumn)
+		if value == "" {
+			opts.Columns = append(opts.Columns, c.typenameProjection(cfg, discriminator))
+			mapping = append(mapping, ColumnPath{Column: "__typename", Path: []string{"__typename"}})
+		} else {
+			opts.Where = append(opts.Where, discriminator+" = "+c.marshaler.AddParam(value))
+		}
+	}
+
+	// Leaf selects read a table directly; scope it like the type mapped to it
+	scopeType := typeName
+	if leafColumn != "" {
+		scopeType = c.tenantTypeForTable(ctx, tableName)
+	}
 
-	// Process arguments (filter, pagination, ordering)
-	if err := c.processArguments(info.Arguments, &opts); err != nil {
+	// Process arguments (filter, tenant scope, pagination, ordering)
+	if err := c.processArguments(ctx, typeName, scopeType, c.fieldArgumentDefs(info), info.Arguments, &opts); err != nil {
 		return nil, err
 	}
+	if leafColumn == "" {
+		c.addOrderTiebreaker(typeName, &opts)
+	}
 
 	// Build the query
 	pg, ok := c.dialect.(dialect.PostgreSQLDialect)
@@ -138,36 +899,60 @@ func (c *SQLConverter) ConvertToSelect(
 	query, errors := pg.BuildSelect(opts)
 
 	return &SQLSelectResult{
-		Query:   query,
-		Params:  c.marshaler.Params(),
-		Options: opts,
-		Errors:  errors,
+		Query:    c.Format(query),
+		Params:   c.marshaler.Params(),
+		Options:  opts,
+		Errors:   errors,
+		Warnings: c.warnings,
+
+		ColumnMapping: mapping,
 	}, nil
 }
 
-// collectColumnsAndJoins collects SQL columns and joins from the GraphQL selection
+// collectColumnsAndJoins collects SQL columns and joins from the GraphQL
+// selection, along with the response path of every result column
 func (c *SQLConverter) collectColumnsAndJoins(
+	ctx context.Context,
 	typeName string,
 	tableAlias string,
 	selections *SelectionSet,
-) ([]string, []ast.JoinColumn) {
+) ([]string, []ast.JoinColumn, []ColumnPath, error) {
 	columns := make([]string, 0)
 	joins := make([]ast.JoinColumn, 0)
+	mapping := make([]ColumnPath, 0)
 
 	if selections == nil {
-		return columns, joins
+		return columns, joins, mapping, nil
 	}
 
+	// Aliased selections of the same relation with the same arguments share
+	// one join; signature -> index into joins
+	joinIndex := make(map[string]int)
+
 	for _, field := range selections.Fields {
 		// Check if this is a relation field
 		joinKey := typeName + "." + field.Name
 		if joinCfg, ok := c.joinConfig[joinKey]; ok {
 			// This is a join field
 			joinAlias := field.GetName()[:1] + "_" + field.Name[:min(3, len(field.Name))]
+			signature := joinKey + "|" + fmt.Sprintf("%v", field.Arguments["limit"])
+			if idx, ok := joinIndex[signature]; ok {
+				joinAlias = joins[idx].Alias
+				if joins[idx].JoinType == ast.JoinLeftLateral && field.HasSelection() {
+					for _, subField := range field.Selections.Fields {
+						col := c.sourceColumn(c.schema.FieldBaseTypeName(typeName, field.Name), subField.Name)
+						if !containsString(joins[idx].SubqueryColumns, col) {
+							joins[idx].SubqueryColumns = append(joins[idx].SubqueryColumns, col)
+						}
+					}
+				}
+				c.addJoinColumns(&columns, &mapping, typeName, field, joinAlias, joins[idx].JoinType == ast.JoinLeftLateral)
+				continue
+			}
 
 			join := ast.JoinColumn{
 				JoinType:  joinCfg.JoinType,
-				TableName: c.dialect.QuoteIdentifier(joinCfg.TargetTable),
+				TableName: c.quoteTable(joinCfg.TargetTable),
 				Alias:     joinAlias,
 				On: fmt.Sprintf("%s.%s = %s.%s",
 					tableAlias,
@@ -177,6 +962,10 @@ func (c *SQLConverter) collectColumnsAndJoins(
 				),
 			}
 
+			if on := c.joinOn(typeName, field.Name, joinCfg, tableAlias, joinAlias); on != "" {
+				join.On = on
+			}
+
 			// If it's a lateral subquery for hasMany
 			if joinCfg.RelationType == "hasMany" && field.HasSelection() {
 				join.JoinType = ast.JoinLeftLateral
@@ -184,7 +973,10 @@ func (c *SQLConverter) collectColumnsAndJoins(
 				// Get subquery columns
 				subColumns := make([]string, 0)
 				for _, subField := range field.Selections.Fields {
-					subColumns = append(subColumns, c.getColumnName(unwrapFieldType(typeName, field.Name, c.schema), subField.Name))
+					col := c.sourceColumn(c.schema.FieldBaseTypeName(typeName, field.Name), subField.Name)
+					if !containsString(subColumns, col) {
+						subColumns = append(subColumns, col)
+					}
 				}
 				join.SubqueryColumns = subColumns
 				join.SubqueryWhere = fmt.Sprintf("%s = %s.%s",
@@ -192,56 +984,178 @@ func (c *SQLConverter) collectColumnsAndJoins(
 					tableAlias,
 					c.dialect.QuoteIdentifier(joinCfg.SourceColumn),
 				)
+				// The subquery's table has no alias; its name qualifies columns
+				if on := c.joinOn(typeName, field.Name, joinCfg, tableAlias, join.TableName); on != "" {
+					join.SubqueryWhere = on
+				}
+				// The subquery is correlated; its columns need not include the key
+				join.On = "true"
+
+				// Joined rows are scoped by their own type's tenant column; the
+				// subquery's table is qualified by its name. Bound before the
+				// limit, which follows the WHERE clause in the SQL.
+				condition, err := c.tenantCondition(ctx, c.schema.FieldBaseTypeName(typeName, field.Name), join.TableName)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				if condition != "" {
+					join.SubqueryWhere = "(" + join.SubqueryWhere + ") AND " + condition
+				}
 
-				// Check for limit argument
-				if limit, ok := field.Arguments["limit"]; ok {
-					join.Limit = fmt.Sprintf("%v", limit)
+				// Check for limit argument; bound like the root LIMIT
+				if limit, ok := field.Arguments["limit"]; ok && limit != nil {
+					n, err := nonNegativeInt(field.Name+".limit", limit)
+					if err != nil {
+						return nil, nil, nil, err
+					}
+					join.Limit = c.marshaler.AddParam(n)
+				}
+			} else {
+				// Joined rows are scoped by their own type's tenant column
+				targetType := c.schema.FieldBaseTypeName(typeName, field.Name)
+				condition, err := c.tenantCondition(ctx, targetType, joinAlias)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				if condition != "" {
+					join.On = "(" + join.On + ") AND " + condition
 				}
 			}
 
+			joinIndex[signature] = len(joins)
 			joins = append(joins, join)
 
 			// Add columns from the joined table
-			if field.HasSelection() {
-				for _, subField := range field.Selections.Fields {
-					col := fmt.Sprintf("%s.%s AS %s_%s",
-						joinAlias,
-						c.dialect.QuoteIdentifier(c.getColumnName(unwrapFieldType(typeName, field.Name, c.schema), subField.Name)),
-						joinAlias,
-						subField.GetName(),
-					)
-					columns = append(columns, col)
-				}
-			}
+			c.addJoinColumns(&columns, &mapping, typeName, field, joinAlias, join.JoinType == ast.JoinLeftLateral)
 		} else {
-			// Regular scalar field
-			colName := c.getColumnName(typeName, field.Name)
+			// Regular scalar field; fields from fragments on a concrete type
+			// use that type's column mapping
+			fieldType := typeName
+			if len(field.TypeConditions) > 0 {
+				fieldType = field.TypeConditions[0]
+			}
+			colName := c.getColumnName(fieldType, field.Name)
 			alias := tableAlias + "." + c.dialect.QuoteIdentifier(colName)
+			output := colName
+			extract := c.jsonExtract(fieldType, field.Name, tableAlias)
+			if extract != "" {
+				alias = extract
+			}
+
+			// An invalid cast is reported by the filter builder; the
+			// projection falls back to the plain column
+			castType, err := c.castFor(fieldType, field.Name)
+			if err == nil && castType != "" {
+				alias = alias + "::" + castType
+			}
+
+			if extract != "" {
+				// The expression is named by the field, scanning back onto it
+				output = field.GetName()
+				alias = alias + " AS " + c.dialect.QuoteIdentifier(output)
+			} else if field.Alias != "" && field.Alias != field.Name {
+				output = field.Alias
+				alias = alias + " AS " + c.dialect.QuoteIdentifier(output)
+			} else if err == nil && castType != "" {
+				// Keep the column name so rows scan back onto the field
+				alias = alias + " AS " + c.dialect.QuoteIdentifier(colName)
+			}
 
-			if field.Alias != "" && field.Alias != field.Name {
-				alias = alias + " AS " + c.dialect.QuoteIdentifier(field.Alias)
+			if !containsString(columns, alias) {
+				columns = append(columns, alias)
 			}
+			mapping = append(mapping, ColumnPath{Column: output, Path: []string{field.GetName()}})
+		}
+	}
+
+	return columns, joins, mapping, nil
+}
+
+// addJoinColumns adds the columns selected from a joined relation, skipping
+// ones already selected through the same join. Each column is named
+// joinAlias_subField and mapped to [field, subField]; list marks a hasMany
+// relation.
+func (c *SQLConverter) addJoinColumns(columns *[]string, mapping *[]ColumnPath, typeName string, field *SelectedField, joinAlias string, list bool) {
+	if !field.HasSelection() {
+		return
+	}
+	targetType := c.schema.FieldBaseTypeName(typeName, field.Name)
+	for _, subField := range field.Selections.Fields {
+		output := joinAlias + "_" + subField.GetName()
+		expr := c.jsonExtract(targetType, subField.Name, joinAlias)
+		if expr == "" {
+			expr = joinAlias + "." + c.dialect.QuoteIdentifier(c.getColumnName(targetType, subField.Name))
+		}
+		col := expr + " AS " + c.dialect.QuoteIdentifier(output)
+		if !containsString(*columns, col) {
+			*columns = append(*columns, col)
+		}
+		*mapping = append(*mapping, ColumnPath{Column: output, Path: []string{field.GetName(), subField.GetName()}, List: list})
+	}
+}
+
+// hasListColumns reports whether mapping contains hasMany columns
+func hasListColumns(mapping []ColumnPath) bool {
+	for _, m := range mapping {
+		if m.List {
+			return true
+		}
+	}
+	return false
+}
+
+// typenameProjection maps the discriminator column to the concrete type name
+func (c *SQLConverter) typenameProjection(cfg *InheritanceConfig, discriminator string) string {
+	typeNames := make([]string, 0, len(cfg.TypeValues))
+	for typeName := range cfg.TypeValues {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	var sb strings.Builder
+	sb.WriteString("CASE " + discriminator)
+	for _, typeName := range typeNames {
+		sb.WriteString(" WHEN " + c.marshaler.QuoteString(cfg.TypeValues[typeName]) + " THEN " + c.marshaler.QuoteString(typeName))
+	}
+	sb.WriteString(" END AS " + c.dialect.QuoteIdentifier("__typename"))
+	return sb.String()
+}
 
-			columns = append(columns, alias)
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
 		}
 	}
+	return false
+}
 
-	return columns, joins
+// fieldArgumentDefs returns the declared arguments of the field being resolved
+func (c *SQLConverter) fieldArgumentDefs(info *ResolveInfo) map[string]*ArgumentDefinition {
+	if parent, ok := c.schema.GetType(info.ParentType); ok {
+		if field, ok := parent.Fields[info.FieldName]; ok {
+			return field.Arguments
+		}
+	}
+	return nil
 }
 
-// processArguments processes GraphQL arguments into SQL options
+// processArguments processes GraphQL arguments into SQL options. The WHERE
+// clauses are scoped to scopeType's tenant before HAVING, LIMIT and OFFSET
+// are bound, keeping placeholders in the order they appear in the SQL.
 func (c *SQLConverter) processArguments(
+	ctx context.Context,
+	typeName string,
+	scopeType string,
+	argDefs map[string]*ArgumentDefinition,
 	args map[string]interface{},
 	opts *dialecttypes.PostgreSQLSelectOptions,
 ) error {
-	if args == nil {
-		return nil
-	}
-
 	// Handle 'where' or 'filter' argument
 	if where, ok := args["where"].(map[string]interface{}); ok {
 		whereBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-		if err := c.buildWhereFromFilter(where, opts.TableAlias, whereBuilder); err != nil {
+		if err := c.buildWhereFromFilter(ctx, typeName, where, opts.TableAlias, whereBuilder); err != nil {
 			return err
 		}
 		if clause := whereBuilder.Build(); clause != "" {
@@ -251,7 +1165,7 @@ func (c *SQLConverter) processArguments(
 
 	if filter, ok := args["filter"].(map[string]interface{}); ok {
 		whereBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-		if err := c.buildWhereFromFilter(filter, opts.TableAlias, whereBuilder); err != nil {
+		if err := c.buildWhereFromFilter(ctx, typeName, filter, opts.TableAlias, whereBuilder); err != nil {
 			return err
 		}
 		if clause := whereBuilder.Build(); clause != "" {
@@ -265,58 +1179,226 @@ func (c *SQLConverter) processArguments(
 		opts.Where = append(opts.Where, opts.TableAlias+".id = "+placeholder)
 	}
 
-	// Handle 'limit' and 'first' arguments
-	if limit, ok := args["limit"]; ok {
-		opts.Limit = fmt.Sprintf("%v", limit)
-	} else if first, ok := args["first"]; ok {
-		opts.Limit = fmt.Sprintf("%v", first)
+	// Handle 'search' argument (full-text search)
+	if search, ok := args["search"].(string); ok && search != "" {
+		clause, err := c.buildFullTextSearch(typeName, opts.TableAlias, search)
+		if err != nil {
+			return err
+		}
+		opts.Where = append(opts.Where, clause)
 	}
 
-	// Handle 'offset' and 'skip' arguments
-	if offset, ok := args["offset"]; ok {
-		opts.Offset = fmt.Sprintf("%v", offset)
-	} else if skip, ok := args["skip"]; ok {
-		opts.Offset = fmt.Sprintf("%v", skip)
+	where, err := c.scopeToTenant(ctx, scopeType, opts.TableAlias, opts.Where)
+	if err != nil {
+		return err
+	}
+	opts.Where = where
+
+	// Handle 'groupBy' argument (field names)
+	groupBy, _ := args["groupBy"].([]interface{})
+	if field, ok := args["groupBy"].(string); ok {
+		groupBy = []interface{}{field}
+	}
+	for _, g := range groupBy {
+		field, ok := g.(string)
+		if !ok {
+			return fmt.Errorf("groupBy: expected field names, got %T", g)
+		}
+		if err := c.checkColumnField("groupBy", typeName, field); err != nil {
+			return err
+		}
+		opts.GroupBy = append(opts.GroupBy, opts.TableAlias+"."+c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
+	}
+
+	// Handle 'having' argument (aggregate filters); the groups are the
+	// client's to choose
+	if having, ok := args["having"].(map[string]interface{}); ok {
+		havingBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
+		if err := c.buildHavingFromFilter(typeName, having, opts.TableAlias, havingBuilder); err != nil {
+			return err
+		}
+		if clause := havingBuilder.Build(); clause != "" {
+			if len(opts.GroupBy) == 0 {
+				return fmt.Errorf("having: requires groupBy")
+			}
+			opts.Having = append(opts.Having, clause)
+		}
+	}
+
+	// Handle 'limit'/'first' and 'offset'/'skip' arguments; both are bound
+	// as parameters after validation
+	for _, name := range []string{"limit", "first"} {
+		if value, ok := args[name]; ok && value != nil {
+			n, err := nonNegativeInt(name, value)
+			if err != nil {
+				return err
+			}
+			opts.Limit = c.marshaler.AddParam(n)
+			break
+		}
+	}
+	for _, name := range []string{"offset", "skip"} {
+		if value, ok := args[name]; ok && value != nil {
+			n, err := nonNegativeInt(name, value)
+			if err != nil {
+				return err
+			}
+			opts.Offset = c.marshaler.AddParam(n)
+			break
+		}
+	}
+
+	// Enum-style sort values are validated against the declared enum type
+	orderByEnum := ""
+	if def, ok := argDefs["orderBy"]; ok {
+		orderByEnum = unwrapTypeName(def.Type)
 	}
 
 	// Handle 'orderBy' argument
 	if orderBy, ok := args["orderBy"].([]interface{}); ok {
-		orderBuilder := marshal.NewOrderByBuilder()
 		for _, o := range orderBy {
-			if orderMap, ok := o.(map[string]interface{}); ok {
-				if field, ok := orderMap["field"].(string); ok {
-					direction := "ASC"
-					if dir, ok := orderMap["direction"].(string); ok {
-						direction = dir
-					}
-					orderBuilder.Add(opts.TableAlias+"."+c.dialect.QuoteIdentifier(toSnakeCase(field)), direction)
+			if value, ok := o.(string); ok {
+				col, err := c.orderByFromEnum(typeName, orderByEnum, value, opts.TableAlias)
+				if err != nil {
+					return err
+				}
+				opts.OrderBy = append(opts.OrderBy, col)
+			} else if orderMap, ok := o.(map[string]interface{}); ok {
+				col, ok, err := c.orderByFromMap(typeName, orderMap, opts.TableAlias)
+				if err != nil {
+					return err
+				}
+				if ok {
+					opts.OrderBy = append(opts.OrderBy, col)
 				}
 			}
 		}
-		for _, col := range orderBuilder.Build() {
-			opts.OrderBy = append(opts.OrderBy, dialecttypes.OrderByColumn{
-				Column:    col,
-				Direction: ast.OrderAsc,
-			})
-		}
 	} else if orderBy, ok := args["orderBy"].(map[string]interface{}); ok {
-		if field, ok := orderBy["field"].(string); ok {
-			direction := ast.OrderAsc
-			if dir, ok := orderBy["direction"].(string); ok && strings.ToUpper(dir) == "DESC" {
-				direction = ast.OrderDesc
-			}
-			opts.OrderBy = append(opts.OrderBy, dialecttypes.OrderByColumn{
-				Column:    opts.TableAlias + "." + c.dialect.QuoteIdentifier(toSnakeCase(field)),
-				Direction: direction,
-			})
+		col, ok, err := c.orderByFromMap(typeName, orderBy, opts.TableAlias)
+		if err != nil {
+			return err
+		}
+		if ok {
+			opts.OrderBy = append(opts.OrderBy, col)
+		}
+	} else if value, ok := args["orderBy"].(string); ok {
+		col, err := c.orderByFromEnum(typeName, orderByEnum, value, opts.TableAlias)
+		if err != nil {
+			return err
 		}
+		opts.OrderBy = append(opts.OrderBy, col)
 	}
 
 	return nil
 }
 
+// nonNegativeInt validates a pagination argument as a non-negative integer
+func nonNegativeInt(name string, value interface{}) (int64, error) {
+	var n int64
+	switch v := value.(type) {
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("%s must be an integer, got %v", name, v)
+		}
+		n = int64(v)
+	default:
+		return 0, fmt.Errorf("%s must be an integer, got %T", name, value)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %d", name, n)
+	}
+	return n, nil
+}
+
+// orderByFromEnum maps an enum sort value to an ORDER BY column, using the
+// configured mapping or the <FIELD>_<ASC|DESC> convention (CREATED_AT_DESC -> createdAt DESC)
+func (c *SQLConverter) orderByFromEnum(typeName, enumName, value, tableAlias string) (dialecttypes.OrderByColumn, error) {
+	if enumType, ok := c.schema.GetEnum(enumName); ok {
+		valid := false
+		for _, v := range enumType.Values {
+			if v.Name == value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return dialecttypes.OrderByColumn{}, fmt.Errorf("orderBy: %q is not a value of enum %s", value, enumName)
+		}
+	}
+
+	var field string
+	var direction ast.OrderDirection
+
+	if mapped, ok := c.orderByEnums[enumName][value]; ok {
+		field = mapped.Field
+		direction = mapped.Direction
+	} else {
+		idx := strings.LastIndex(value, "_")
+		if idx <= 0 {
+			return dialecttypes.OrderByColumn{}, fmt.Errorf("orderBy: %q does not match <FIELD>_<ASC|DESC>", value)
+		}
+		switch strings.ToUpper(value[idx+1:]) {
+		case "ASC":
+			direction = ast.OrderAsc
+		case "DESC":
+			direction = ast.OrderDesc
+		default:
+			return dialecttypes.OrderByColumn{}, fmt.Errorf("orderBy: %q does not match <FIELD>_<ASC|DESC>", value)
+		}
+		field = enumToFieldName(value[:idx])
+	}
+
+	target, err := c.sortTarget(typeName, field, tableAlias)
+	if err != nil {
+		return dialecttypes.OrderByColumn{}, err
+	}
+
+	return dialecttypes.OrderByColumn{
+		Column:    target,
+		Direction: direction,
+	}, nil
+}
+
+// orderByFromMap maps a {field, direction} sort input to an ORDER BY column;
+// ok is false when it has no field
+func (c *SQLConverter) orderByFromMap(typeName string, orderMap map[string]interface{}, tableAlias string) (col dialecttypes.OrderByColumn, ok bool, err error) {
+	field, ok := orderMap["field"].(string)
+	if !ok {
+		return col, false, nil
+	}
+	target, err := c.sortTarget(typeName, field, tableAlias)
+	if err != nil {
+		return col, false, err
+	}
+
+	direction := ast.OrderAsc
+	if dir, ok := orderMap["direction"].(string); ok && strings.ToUpper(dir) == "DESC" {
+		direction = ast.OrderDesc
+	}
+	return dialecttypes.OrderByColumn{Column: target, Direction: direction}, true, nil
+}
+
+// enumToFieldName converts an UPPER_SNAKE enum segment to a camelCase field name
+func enumToFieldName(s string) string {
+	parts := strings.Split(strings.ToLower(s), "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
 // buildWhereFromFilter builds WHERE clauses from a filter object
 func (c *SQLConverter) buildWhereFromFilter(
+	ctx context.Context,
+	typeName string,
 	filter map[string]interface{},
 	tableAlias string,
 	builder *marshal.WhereClauseBuilder,
@@ -327,7 +1409,7 @@ func (c *SQLConverter) buildWhereFromFilter(
 			if conditions, ok := value.([]interface{}); ok {
 				for _, cond := range conditions {
 					if condMap, ok := cond.(map[string]interface{}); ok {
-						if err := c.buildWhereFromFilter(condMap, tableAlias, builder); err != nil {
+						if err := c.buildWhereFromFilter(ctx, typeName, condMap, tableAlias, builder); err != nil {
 							return err
 						}
 					}
@@ -340,7 +1422,7 @@ func (c *SQLConverter) buildWhereFromFilter(
 				for _, cond := range conditions {
 					if condMap, ok := cond.(map[string]interface{}); ok {
 						subBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-						if err := c.buildWhereFromFilter(condMap, tableAlias, subBuilder); err != nil {
+						if err := c.buildWhereFromFilter(ctx, typeName, condMap, tableAlias, subBuilder); err != nil {
 							return err
 						}
 						orBuilder.AddRaw(subBuilder.Build())
@@ -352,29 +1434,72 @@ func (c *SQLConverter) buildWhereFromFilter(
 		case "_not", "NOT":
 			if notFilter, ok := value.(map[string]interface{}); ok {
 				subBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-				if err := c.buildWhereFromFilter(notFilter, tableAlias, subBuilder); err != nil {
+				if err := c.buildWhereFromFilter(ctx, typeName, notFilter, tableAlias, subBuilder); err != nil {
 					return err
 				}
-				builder.AddRaw("NOT (" + subBuilder.Build() + ")")
+				if clause := subBuilder.Build(); clause != "" {
+					builder.AddRaw("NOT (" + clause + ")")
+				}
 			}
 
 		default:
 			// Field condition
-			column := tableAlias + "." + c.dialect.QuoteIdentifier(toSnakeCase(key))
+			if !c.isFilterable(typeName, key) {
+				return fmt.Errorf("field %s.%s is not filterable", typeName, key)
+			}
+			c.warnUnindexed("filter", typeName, key)
+
+			// Relation filters: {posts: {_exists: true}} -> EXISTS (SELECT 1 ...)
+			if joinCfg, ok := c.joinConfig[typeName+"."+key]; ok {
+				if relFilter, ok := value.(map[string]interface{}); ok {
+					if _, ok := relFilter["_exists"]; ok {
+						clause, err := c.buildExistsFilter(ctx, typeName, key, joinCfg, relFilter, tableAlias)
+						if err != nil {
+							return err
+						}
+						builder.AddRaw(clause)
+						continue
+					}
+				}
+			}
+			if err := c.checkColumnField("filter", typeName, key); err != nil {
+				return err
+			}
+			column := tableAlias + "." + c.dialect.QuoteIdentifier(c.getColumnName(typeName, key))
+			if extract := c.jsonExtract(typeName, key, tableAlias); extract != "" {
+				column = extract
+			}
+			castType, err := c.castFor(typeName, key)
+			if err != nil {
+				return err
+			}
+
+			// Enum values are bound as their database representation
+			enumName := c.schema.FieldBaseTypeName(typeName, key)
+			_, isEnum := c.schema.GetEnum(enumName)
 
 			switch v := value.(type) {
 			case map[string]interface{}:
 				// Operator-based filter: {age: {_gt: 18}}
 				for op, operand := range v {
-					sqlOp := convertGraphQLOperator(op)
-					if err := builder.AddCondition(column, sqlOp, operand); err != nil {
+					sqlOp, ok := convertGraphQLOperator(op)
+					if !ok {
+						return fmt.Errorf("filter: unknown operator %q on %s.%s", op, typeName, key)
+					}
+					if isEnum {
+						operand = c.enumToDB(enumName, operand)
+					}
+					if err := builder.AddCastCondition(column, sqlOp, operand, castType); err != nil {
 						return err
 					}
 				}
 
 			default:
 				// Direct equality: {name: "John"}
-				if err := builder.AddCondition(column, "eq", value); err != nil {
+				if isEnum {
+					value = c.enumToDB(enumName, value)
+				}
+				if err := builder.AddCastCondition(column, "eq", value, castType); err != nil {
 					return err
 				}
 			}
@@ -384,37 +1509,203 @@ func (c *SQLConverter) buildWhereFromFilter(
 	return nil
 }
 
-// convertGraphQLOperator converts GraphQL filter operators to SQL
-func convertGraphQLOperator(op string) string {
+// buildExistsFilter builds an EXISTS / NOT EXISTS subquery over a relation.
+// Other keys next to _exists filter the related rows.
+func (c *SQLConverter) buildExistsFilter(
+	ctx context.Context,
+	typeName, fieldName string,
+	joinCfg *JoinConfig,
+	filter map[string]interface{},
+	tableAlias string,
+) (string, error) {
+	exists, ok := filter["_exists"].(bool)
+	if !ok {
+		return "", fmt.Errorf("%s._exists must be a boolean", fieldName)
+	}
+	if joinCfg.RelationType == "manyToMany" {
+		return "", fmt.Errorf("_exists is not supported on manyToMany relation %s.%s", typeName, fieldName)
+	}
+
+	subAlias := tableAlias + "_" + toSnakeCase(fieldName)
+	conditions := []string{fmt.Sprintf("%s.%s = %s.%s",
+		subAlias,
+		c.dialect.QuoteIdentifier(joinCfg.TargetColumn),
+		tableAlias,
+		c.dialect.QuoteIdentifier(joinCfg.SourceColumn),
+	)}
+	if on := c.joinOn(typeName, fieldName, joinCfg, tableAlias, subAlias); on != "" {
+		conditions = []string{"(" + on + ")"}
+	}
+	condition, err := c.tenantCondition(ctx, c.schema.FieldBaseTypeName(typeName, fieldName), subAlias)
+	if err != nil {
+		return "", err
+	}
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	rest := make(map[string]interface{}, len(filter))
+	for k, v := range filter {
+		if k != "_exists" {
+			rest[k] = v
+		}
+	}
+	if len(rest) > 0 {
+		subBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
+		if err := c.buildWhereFromFilter(ctx, c.schema.FieldBaseTypeName(typeName, fieldName), rest, subAlias, subBuilder); err != nil {
+			return "", err
+		}
+		if clause := subBuilder.Build(); clause != "" {
+			conditions = append(conditions, "("+clause+")")
+		}
+	}
+
+	op := ast.OpExists
+	if !exists {
+		op = ast.OpNotExists
+	}
+
+	return fmt.Sprintf("%s (SELECT 1 FROM %s %s WHERE %s)",
+		c.dialect.FormatUnaryOp(op, true),
+		c.quoteTable(joinCfg.TargetTable),
+		subAlias,
+		strings.Join(conditions, " AND "),
+	), nil
+}
+
+// buildFullTextSearch builds a tsvector match over the configured searchable columns
+func (c *SQLConverter) buildFullTextSearch(typeName, tableAlias, search string) (string, error) {
+	if !c.dialect.SupportsFullText() {
+		return "", fmt.Errorf("dialect %s does not support full-text search", c.dialect.Name())
+	}
+
+	cfg, ok := c.fullText[typeName]
+	if !ok || len(cfg.Columns) == 0 {
+		return "", fmt.Errorf("full-text search is not configured for type %s", typeName)
+	}
+
+	parts := make([]string, len(cfg.Columns))
+	for i, col := range cfg.Columns {
+		parts[i] = fmt.Sprintf("coalesce(%s.%s, '')", tableAlias, c.dialect.QuoteIdentifier(col))
+	}
+	document := strings.Join(parts, " || ' ' || ")
+
+	placeholder, err := c.marshaler.MarshalValue(search)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Config == "" {
+		return fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(%s)", document, placeholder), nil
+	}
+
+	config := c.dialect.QuoteString(cfg.Config) + "::regconfig"
+	return fmt.Sprintf("to_tsvector(%s, %s) @@ plainto_tsquery(%s, %s)", config, document, config, placeholder), nil
+}
+
+// buildHavingFromFilter builds HAVING conditions from an aggregate filter object
+//
+//	{count: {_gt: 5}}            -> COUNT(*) > $1
+//	{avg: {age: {_gte: 18}}}     -> AVG(u."age") >= $1
+func (c *SQLConverter) buildHavingFromFilter(
+	typeName string,
+	filter map[string]interface{},
+	tableAlias string,
+	builder *marshal.WhereClauseBuilder,
+) error {
+	for key, value := range filter {
+		switch key {
+		case "count", "_count":
+			if err := addAggregateConditions(builder, "COUNT(*)", value); err != nil {
+				return err
+			}
+
+		case "sum", "_sum", "avg", "_avg", "min", "_min", "max", "_max":
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("having: %s expects an object of field filters", key)
+			}
+			fn := strings.ToUpper(strings.TrimPrefix(key, "_"))
+			for field, operand := range fields {
+				if err := c.checkColumnField("having", typeName, field); err != nil {
+					return err
+				}
+				expr := fmt.Sprintf("%s(%s.%s)", fn, tableAlias, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
+				if err := addAggregateConditions(builder, expr, operand); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("having: unsupported aggregate %q", key)
+		}
+	}
+
+	return nil
+}
+
+// addAggregateConditions adds conditions against an aggregate expression
+func addAggregateConditions(builder *marshal.WhereClauseBuilder, expr string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for op, operand := range v {
+			sqlOp, ok := convertGraphQLOperator(op)
+			if !ok {
+				return fmt.Errorf("having: unknown operator %q", op)
+			}
+			if err := builder.AddCondition(expr, sqlOp, operand); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return builder.AddCondition(expr, "eq", value)
+	}
+}
+
+// convertGraphQLOperator converts GraphQL filter operators to the builder's
+// operators; ok is false for operators it does not know
+func convertGraphQLOperator(op string) (string, bool) {
 	switch op {
 	case "_eq", "eq":
-		return "="
+		return "=", true
 	case "_neq", "neq", "_ne", "ne":
-		return "<>"
+		return "<>", true
 	case "_gt", "gt":
-		return ">"
+		return ">", true
 	case "_gte", "gte", "_ge", "ge":
-		return ">="
+		return ">=", true
 	case "_lt", "lt":
-		return "<"
+		return "<", true
 	case "_lte", "lte", "_le", "le":
-		return "<="
+		return "<=", true
 	case "_like", "like":
-		return "like"
+		return "like", true
 	case "_ilike", "ilike":
-		return "ilike"
+		return "ilike", true
+	case "_ieq", "ieq":
+		return "ieq", true
 	case "_in", "in":
-		return "in"
+		return "in", true
 	case "_nin", "nin", "_not_in", "not_in":
-		return "nin"
+		return "nin", true
 	case "_is_null", "is_null", "isNull":
-		return "is_null"
+		return "is_null", true
 	case "_contains", "contains":
-		return "contains"
+		return "contains", true
 	case "_contained_by", "contained_by", "containedBy":
-		return "contained_by"
+		return "contained_by", true
+	case "_array_contains", "array_contains":
+		return "array_contains", true
+	case "_array_contained_by", "array_contained_by":
+		return "array_contained_by", true
+	case "_array_overlap", "array_overlap":
+		return "array_overlap", true
+	case "_array_length", "array_length":
+		return "array_length", true
 	default:
-		return op
+		return "", false
 	}
 }
 
@@ -434,16 +1725,6 @@ func toSnakeCase(s string) string {
 	return result.String()
 }
 
-// unwrapFieldType gets the return type of a field
-func unwrapFieldType(typeName, fieldName string, schema *Schema) string {
-	if objType, ok := schema.GetType(typeName); ok {
-		if field, ok := objType.Fields[fieldName]; ok {
-			return unwrapTypeName(field.Type)
-		}
-	}
-	return ""
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -456,6 +1737,11 @@ type SQLMutationResult struct {
 	Query     string
 	Params    []interface{}
 	Operation string // "INSERT", "UPDATE", "DELETE"
+	Returning bool   // True when the query has a RETURNING clause
+
+	// Idempotent marks a mutation that is safe to run again, letting
+	// ExecuteWithRetry retry it; set by the caller
+	Idempotent bool
 }
 
 // ConvertToInsert converts a GraphQL mutation to SQL INSERT
@@ -465,9 +1751,280 @@ func (c *SQLConverter) ConvertToInsert(
 	input map[string]interface{},
 	returning []string,
 ) (*SQLMutationResult, error) {
+	if err := c.checkWritable(typeName); err != nil {
+		return nil, err
+	}
 	c.marshaler.Reset()
 
-	tableName := c.getTableName(typeName)
+	pg, ok := c.dialect.(dialect.PostgreSQLDialect)
+	if !ok {
+		return nil, fmt.Errorf("dialect does not support PostgreSQL INSERT building")
+	}
+
+	opts, err := c.insertOptions(ctx, typeName, input, returning)
+	if err != nil {
+		return nil, err
+	}
+
+	query := pg.BuildInsert(opts)
+
+	return &SQLMutationResult{
+		Query:     c.Format(query),
+		Params:    c.marshaler.Params(),
+		Operation: "INSERT",
+		Returning: len(opts.Returning) > 0,
+	}, nil
+}
+
+// ConvertToUpsert converts a GraphQL mutation to SQL INSERT ... ON CONFLICT
+// (conflictColumns) DO UPDATE SET, where each of updateColumns is set from
+// EXCLUDED. Without updateColumns, every inserted column except the conflict
+// target is updated; if none is left the conflict does nothing.
+func (c *SQLConverter) ConvertToUpsert(
+	ctx context.Context,
+	typeName string,
+	input map[string]interface{},
+	conflictColumns []string,
+	updateColumns []string,
+	returning []string,
+) (*SQLMutationResult, error) {
+	if !c.dialect.SupportsOnConflict() {
+		return nil, fmt.Errorf("dialect %s does not support ON CONFLICT", c.dialect.Name())
+	}
+	if len(conflictColumns) == 0 {
+		return nil, fmt.Errorf("upsert of %s needs at least one conflict column", typeName)
+	}
+	if err := c.checkWritable(typeName); err != nil {
+		return nil, err
+	}
+	c.marshaler.Reset()
+
+	pg, ok := c.dialect.(dialect.PostgreSQLDialect)
+	if !ok {
+		return nil, fmt.Errorf("dialect does not support PostgreSQL INSERT building")
+	}
+
+	opts, err := c.insertOptions(ctx, typeName, input, returning)
+	if err != nil {
+		return nil, err
+	}
+
+	conflict := make([]string, len(conflictColumns))
+	for i, field := range conflictColumns {
+		conflict[i] = c.dialect.QuoteIdentifier(c.getColumnName(typeName, field))
+	}
+
+	// A conflicting row keeps its tenant: the tenant column is never updated
+	tenantCol := ""
+	if column, ok := c.tenantCols[typeName]; ok {
+		tenantCol = c.dialect.QuoteIdentifier(column)
+	}
+
+	var set []string
+	if len(updateColumns) > 0 {
+		for _, field := range updateColumns {
+			col := c.dialect.QuoteIdentifier(c.getColumnName(typeName, field))
+			if col == tenantCol {
+				return nil, fmt.Errorf("cannot update tenant column %s of %s", c.tenantCols[typeName], typeName)
+			}
+			set = append(set, col)
+		}
+	} else {
+		for _, col := range opts.Columns {
+			if !containsString(conflict, col) && col != tenantCol {
+				set = append(set, col)
+			}
+		}
+	}
+
+	opts.OnConflict = &dialecttypes.OnConflictClause{Columns: conflict}
+	if len(set) == 0 {
+		opts.OnConflict.DoNothing = true
+	} else {
+		values := make([]string, len(set))
+		for i, col := range set {
+			values[i] = "EXCLUDED." + col
+		}
+		opts.OnConflict.DoUpdate = &dialecttypes.DoUpdateClause{
+			SetColumns: set,
+			SetValues:  values,
+		}
+
+		// Only the tenant's own rows may be updated; a conflict with another
+		// tenant's row updates nothing
+		condition, err := c.tenantCondition(ctx, typeName, opts.TableName)
+		if err != nil {
+			return nil, err
+		}
+		if condition != "" {
+			opts.OnConflict.DoUpdate.Where = []string{condition}
+		}
+	}
+
+	query := pg.BuildInsert(opts)
+
+	return &SQLMutationResult{
+		Query:     c.Format(query),
+		Params:    c.marshaler.Params(),
+		Operation: "INSERT",
+		Returning: len(opts.Returning) > 0,
+	}, nil
+}
+
+// ConvertToInsertRecordset converts a batch insert into a single statement
+// that binds rows as one JSONB parameter:
+//
+//	INSERT INTO t (a, b) SELECT x.a, x.b FROM jsonb_to_recordset($1::jsonb) AS x(a integer, b text)
+//
+// which is far cheaper than a multi-row VALUES for large batches. Column types
+// come from @sql(cast: ...) or SetColumnCast, falling back to the field's
+// GraphQL type; custom scalars need a cast. A field missing from some rows is
+// inserted as NULL in those rows.
+func (c *SQLConverter) ConvertToInsertRecordset(
+	ctx context.Context,
+	typeName string,
+	rows []map[string]interface{},
+	returning []string,
+) (*SQLMutationResult, error) {
+	if !c.dialect.SupportsJSON() {
+		return nil, fmt.Errorf("dialect %s does not support JSON recordset inserts", c.dialect.Name())
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("recordset insert of %s needs at least one row", typeName)
+	}
+	if err := c.checkWritable(typeName); err != nil {
+		return nil, err
+	}
+	c.marshaler.Reset()
+
+	pg, ok := c.dialect.(dialect.PostgreSQLDialect)
+	if !ok {
+		return nil, fmt.Errorf("dialect does not support PostgreSQL INSERT building")
+	}
+
+	// Every field given in any row becomes a recordset column
+	present := make(map[string]interface{})
+	for _, row := range rows {
+		for field := range row {
+			present[field] = nil
+		}
+	}
+	fields := make([]string, 0, len(present))
+	for field := range present {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	const alias = "x"
+	columns := make([]string, 0, len(fields))
+	selects := make([]string, 0, len(fields))
+	defs := make([]string, 0, len(fields))
+	for _, field := range fields {
+		sqlType, err := c.recordsetType(typeName, field)
+		if err != nil {
+			return nil, err
+		}
+		col := c.dialect.QuoteIdentifier(c.getColumnName(typeName, field))
+		columns = append(columns, col)
+		selects = append(selects, alias+"."+col)
+		defs = append(defs, col+" "+sqlType)
+	}
+
+	// Fields omitted from every row fall back to their @sql(default: ...) function
+	defaults, err := c.insertDefaults(typeName, present)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defaults {
+		columns = append(columns, c.dialect.QuoteIdentifier(c.getColumnName(typeName, d.field)))
+		selects = append(selects, d.expr)
+	}
+
+	// The recordset matches JSON keys to its column names
+	records := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		record := make(map[string]interface{}, len(row))
+		for field, value := range row {
+			record[c.getColumnName(typeName, field)] = value
+		}
+		records[i] = record
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s rows: %w", typeName, err)
+	}
+	param := c.marshaler.AddParam(string(data))
+
+	returningCols := make([]string, 0, len(returning))
+	for _, field := range returning {
+		returningCols = append(returningCols, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
+	}
+
+	query := pg.BuildInsert(dialecttypes.PostgreSQLInsertOptions{
+		TableName: c.quoteTable(c.getTableName(ctx, typeName)),
+		Columns:   columns,
+		Select: fmt.Sprintf("SELECT %s\nFROM jsonb_to_recordset(%s::jsonb) AS %s(%s)",
+			strings.Join(selects, ", "), param, alias, strings.Join(defs, ", ")),
+		Returning: returningCols,
+	})
+
+	return &SQLMutationResult{
+		Query:     c.Format(query),
+		Params:    c.marshaler.Params(),
+		Operation: "INSERT",
+		Returning: len(returningCols) > 0,
+	}, nil
+}
+
+// recordsetTypes are the SQL types of the built-in scalars in a recordset
+var recordsetTypes = map[string]string{
+	"Int":     "integer",
+	"Float":   "double precision",
+	"String":  "text",
+	"ID":      "text",
+	"Boolean": "boolean",
+}
+
+// recordsetType returns the SQL type of a field's recordset column
+func (c *SQLConverter) recordsetType(typeName, field string) (string, error) {
+	castType, err := c.castFor(typeName, field)
+	if err != nil || castType != "" {
+		return castType, err
+	}
+
+	ref, ok := c.schema.FieldType(typeName, field)
+	if !ok {
+		return "", fmt.Errorf("type %s has no field %s", typeName, field)
+	}
+	// JSON arrays of scalars populate array columns; other lists stay jsonb
+	suffix := ""
+	if ref.IsList {
+		if ref.ListElem == nil || ref.ListElem.IsList {
+			return "jsonb", nil
+		}
+		ref, suffix = ref.ListElem, "[]"
+	}
+	if sqlType, ok := recordsetTypes[ref.Name]; ok {
+		return sqlType + suffix, nil
+	}
+	if _, ok := c.schema.GetEnum(ref.Name); ok {
+		return "text" + suffix, nil
+	}
+	if suffix != "" {
+		return "jsonb", nil
+	}
+	return "", fmt.Errorf("field %s.%s of type %s needs a SQL type; set @sql(cast: ...)", typeName, field, ref.Name)
+}
+
+// insertOptions builds the single-row INSERT of input, marshaling its values
+// into the converter's params
+func (c *SQLConverter) insertOptions(
+	ctx context.Context,
+	typeName string,
+	input map[string]interface{},
+	returning []string,
+) (dialecttypes.PostgreSQLInsertOptions, error) {
+	tableName := c.getTableName(ctx, typeName)
 
 	columns := make([]string, 0, len(input))
 	values := make([]string, 0, len(input))
@@ -476,38 +2033,249 @@ func (c *SQLConverter) ConvertToInsert(
 		columns = append(columns, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
 		placeholder, err := c.marshaler.MarshalValue(value)
 		if err != nil {
-			return nil, err
+			return dialecttypes.PostgreSQLInsertOptions{}, err
 		}
 		values = append(values, placeholder)
 	}
 
+	// Fields omitted from the input fall back to their @sql(default: ...) function
+	defaults, err := c.insertDefaults(typeName, input)
+	if err != nil {
+		return dialecttypes.PostgreSQLInsertOptions{}, err
+	}
+	for _, d := range defaults {
+		columns = append(columns, c.dialect.QuoteIdentifier(c.getColumnName(typeName, d.field)))
+		values = append(values, d.expr)
+	}
+
 	// Build returning clause
 	returningCols := make([]string, 0, len(returning))
 	for _, field := range returning {
 		returningCols = append(returningCols, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
 	}
 
+	return dialecttypes.PostgreSQLInsertOptions{
+		TableName: c.quoteTable(tableName),
+		Columns:   columns,
+		Values:    [][]string{values},
+		Returning: returningCols,
+	}, nil
+}
+
+// ConvertToInsertNested converts an insert whose input contains nested
+// relation inputs (e.g., createUser(input: {name, profile: {bio}})) into a
+// single WITH statement. The parent row is inserted first; each hasOne or
+// hasMany child follows in its own CTE, with the foreign key from the join
+// config filled from the parent's returned row. The query returns the
+// parent row's returning fields, or its primary key when none are given.
+func (c *SQLConverter) ConvertToInsertNested(
+	ctx context.Context,
+	typeName string,
+	input map[string]interface{},
+	returning []string,
+) (*SQLMutationResult, error) {
+	c.marshaler.Reset()
+
 	pg, ok := c.dialect.(dialect.PostgreSQLDialect)
 	if !ok {
 		return nil, fmt.Errorf("dialect does not support PostgreSQL INSERT building")
 	}
 
-	opts := dialecttypes.PostgreSQLInsertOptions{
-		TableName: c.dialect.QuoteIdentifier(tableName),
-		Columns:   columns,
-		Values:    [][]string{values},
-		Returning: returningCols,
+	ctes := make([]string, 0)
+	root, err := c.buildNestedInsert(ctx, pg, &ctes, typeName, input, nil, returning)
+	if err != nil {
+		return nil, err
 	}
 
-	query := pg.BuildInsert(opts)
+	query := "WITH " + strings.Join(ctes, ", ") + "\nSELECT * FROM " + root
 
 	return &SQLMutationResult{
-		Query:     query,
+		Query:     c.Format(query),
 		Params:    c.marshaler.Params(),
 		Operation: "INSERT",
+		Returning: true,
 	}, nil
 }
 
+// nestedForeignKey is the column of a child row pointing at its parent, and
+// the expression reading the parent's key from the parent's CTE
+type nestedForeignKey struct {
+	column string
+	expr   string
+}
+
+// buildNestedInsert appends the CTE inserting input (and, after it, its
+// children) to ctes and returns the CTE's name
+func (c *SQLConverter) buildNestedInsert(
+	ctx context.Context,
+	pg dialect.PostgreSQLDialect,
+	ctes *[]string,
+	typeName string,
+	input map[string]interface{},
+	fk *nestedForeignKey,
+	returning []string,
+) (string, error) {
+	if err := c.checkWritable(typeName); err != nil {
+		return "", err
+	}
+	tableName := c.getTableName(ctx, typeName)
+
+	fields := make([]string, 0, len(input))
+	for field := range input {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	columns := make([]string, 0, len(input))
+	values := make([]string, 0, len(input))
+	relations := make([]string, 0)
+
+	for _, field := range fields {
+		value := input[field]
+		if _, ok := c.joinConfig[typeName+"."+field]; ok {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				relations = append(relations, field)
+				continue
+			}
+		}
+
+		columns = append(columns, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
+		placeholder, err := c.marshaler.MarshalValue(value)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, placeholder)
+	}
+
+	defaults, err := c.insertDefaults(typeName, input)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range defaults {
+		columns = append(columns, c.dialect.QuoteIdentifier(c.getColumnName(typeName, d.field)))
+		values = append(values, d.expr)
+	}
+
+	if fk != nil {
+		columns = append(columns, c.dialect.QuoteIdentifier(fk.column))
+		values = append(values, fk.expr)
+	}
+
+	// Children read the parent's key columns from its RETURNING clause
+	returningCols := make([]string, 0, len(returning)+len(relations))
+	for _, field := range returning {
+		returningCols = append(returningCols, c.dialect.QuoteIdentifier(c.getColumnName(typeName, field)))
+	}
+	for _, field := range relations {
+		col := c.dialect.QuoteIdentifier(c.joinConfig[typeName+"."+field].SourceColumn)
+		if !containsString(returningCols, col) {
+			returningCols = append(returningCols, col)
+		}
+	}
+	// The statement selects the parent row, by default its primary key
+	if fk == nil && len(returning) == 0 {
+		for _, pk := range c.primaryKeyColumns(typeName) {
+			col := c.dialect.QuoteIdentifier(pk)
+			if !containsString(returningCols, col) {
+				returningCols = append(returningCols, col)
+			}
+		}
+	}
+
+	name := fmt.Sprintf("insert_%d", len(*ctes)+1)
+	query := pg.BuildInsert(dialecttypes.PostgreSQLInsertOptions{
+		TableName: c.quoteTable(tableName),
+		Columns:   columns,
+		Values:    [][]string{values},
+		Returning: returningCols,
+	})
+	*ctes = append(*ctes, fmt.Sprintf("%s AS (%s)", name, query))
+
+	for _, field := range relations {
+		joinCfg := c.joinConfig[typeName+"."+field]
+		if joinCfg.RelationType != "hasOne" && joinCfg.RelationType != "hasMany" {
+			return "", fmt.Errorf("nested insert into %s.%s: %q relations are not supported", typeName, field, joinCfg.RelationType)
+		}
+
+		childType := c.schema.FieldBaseTypeName(typeName, field)
+		childFK := &nestedForeignKey{
+			column: joinCfg.TargetColumn,
+			expr:   fmt.Sprintf("(SELECT %s FROM %s)", c.dialect.QuoteIdentifier(joinCfg.SourceColumn), name),
+		}
+
+		var children []interface{}
+		switch v := input[field].(type) {
+		case map[string]interface{}:
+			children = []interface{}{v}
+		case []interface{}:
+			children = v
+		}
+
+		for _, child := range children {
+			childInput, ok := child.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("nested insert into %s.%s: expected an input object", typeName, field)
+			}
+			if _, err := c.buildNestedInsert(ctx, pg, ctes, childType, childInput, childFK, nil); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return name, nil
+}
+
+// sqlDefaultFunctions whitelists the SQL functions allowed in @sql(default: ...).
+// Defaults are emitted unquoted, so anything else is rejected.
+var sqlDefaultFunctions = map[string]string{
+	"now()":                   "now()",
+	"current_timestamp":       "CURRENT_TIMESTAMP",
+	"current_date":            "CURRENT_DATE",
+	"current_time":            "CURRENT_TIME",
+	"localtimestamp":          "LOCALTIMESTAMP",
+	"clock_timestamp()":       "clock_timestamp()",
+	"statement_timestamp()":   "statement_timestamp()",
+	"transaction_timestamp()": "transaction_timestamp()",
+	"gen_random_uuid()":       "gen_random_uuid()",
+	"uuid_generate_v4()":      "uuid_generate_v4()",
+}
+
+// insertDefault is a column filled by a default function on insert
+type insertDefault struct {
+	field string
+	expr  string
+}
+
+// insertDefaults returns the whitelisted default functions for fields of
+// typeName that are missing from input, sorted by field name
+func (c *SQLConverter) insertDefaults(typeName string, input map[string]interface{}) ([]insertDefault, error) {
+	objType, ok := c.schema.GetType(typeName)
+	if !ok {
+		return nil, nil
+	}
+
+	defaults := make([]insertDefault, 0)
+	for name, field := range objType.Fields {
+		if field.SQLDefault == "" {
+			continue
+		}
+		if _, ok := input[name]; ok {
+			continue
+		}
+		expr, ok := sqlDefaultFunctions[strings.ToLower(strings.TrimSpace(field.SQLDefault))]
+		if !ok {
+			return nil, fmt.Errorf("field %s.%s: default %q is not an allowed SQL function", typeName, name, field.SQLDefault)
+		}
+		defaults = append(defaults, insertDefault{field: name, expr: expr})
+	}
+
+	sort.Slice(defaults, func(i, j int) bool {
+		return defaults[i].field < defaults[j].field
+	})
+	return defaults, nil
+}
+
 // ConvertToUpdate converts a GraphQL mutation to SQL UPDATE
 func (c *SQLConverter) ConvertToUpdate(
 	ctx context.Context,
@@ -516,24 +2284,55 @@ func (c *SQLConverter) ConvertToUpdate(
 	set map[string]interface{},
 	returning []string,
 ) (*SQLMutationResult, error) {
+	return c.ConvertToBulkUpdate(ctx, typeName, where, set, false, returning)
+}
+
+// ConvertToBulkUpdate converts a filter-based update to SQL UPDATE. An empty
+// filter would update every row, so it is rejected unless all is true.
+func (c *SQLConverter) ConvertToBulkUpdate(
+	ctx context.Context,
+	typeName string,
+	where map[string]interface{},
+	set map[string]interface{},
+	all bool,
+	returning []string,
+) (*SQLMutationResult, error) {
+	if err := c.checkWritable(typeName); err != nil {
+		return nil, err
+	}
 	c.marshaler.Reset()
 
-	tableName := c.getTableName(typeName)
+	tableName := c.getTableName(ctx, typeName)
 	tableAlias := strings.ToLower(typeName[:1])
 
-	// Build SET clause
-	setMap := make(map[string]string)
+	// Build SET clause. Columns are marshaled in sorted order, the same order
+	// BuildUpdate writes them, so placeholders appear as $1, $2, ... in the SQL
+	setValues := make(map[string]interface{}, len(set))
 	for field, value := range set {
-		placeholder, err := c.marshaler.MarshalValue(value)
+		column := c.getColumnName(typeName, field)
+		if tenantCol, ok := c.tenantCols[typeName]; ok && column == tenantCol {
+			return nil, fmt.Errorf("cannot update tenant column %s of %s", column, typeName)
+		}
+		setValues[c.dialect.QuoteIdentifier(column)] = value
+	}
+	setColumns := make([]string, 0, len(setValues))
+	for col := range setValues {
+		setColumns = append(setColumns, col)
+	}
+	sort.Strings(setColumns)
+
+	setMap := make(map[string]string, len(setColumns))
+	for _, col := range setColumns {
+		placeholder, err := c.marshaler.MarshalValue(setValues[col])
 		if err != nil {
 			return nil, err
 		}
-		setMap[c.dialect.QuoteIdentifier(c.getColumnName(typeName, field))] = placeholder
+		setMap[col] = placeholder
 	}
 
 	// Build WHERE clause
 	whereBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-	if err := c.buildWhereFromFilter(where, tableAlias, whereBuilder); err != nil {
+	if err := c.buildWhereFromFilter(ctx, typeName, where, tableAlias, whereBuilder); err != nil {
 		return nil, err
 	}
 
@@ -548,20 +2347,32 @@ func (c *SQLConverter) ConvertToUpdate(
 		return nil, fmt.Errorf("dialect does not support PostgreSQL UPDATE building")
 	}
 
+	var whereClauses []string
+	if clause := whereBuilder.Build(); clause != "" {
+		whereClauses = append(whereClauses, clause)
+	} else if !all {
+		return nil, fmt.Errorf("refusing to update %s without a filter; set all: true to update every row", tableName)
+	}
+	whereClauses, err := c.scopeToTenant(ctx, typeName, tableAlias, whereClauses)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := dialecttypes.PostgreSQLUpdateOptions{
-		TableName:  c.dialect.QuoteIdentifier(tableName),
+		TableName:  c.quoteTable(tableName),
 		TableAlias: tableAlias,
 		Set:        setMap,
-		Where:      []string{whereBuilder.Build()},
+		Where:      whereClauses,
 		Returning:  returningCols,
 	}
 
 	query := pg.BuildUpdate(opts)
 
 	return &SQLMutationResult{
-		Query:     query,
+		Query:     c.Format(query),
 		Params:    c.marshaler.Params(),
 		Operation: "UPDATE",
+		Returning: len(returningCols) > 0,
 	}, nil
 }
 
@@ -572,14 +2383,30 @@ func (c *SQLConverter) ConvertToDelete(
 	where map[string]interface{},
 	returning []string,
 ) (*SQLMutationResult, error) {
+	return c.ConvertToBulkDelete(ctx, typeName, where, false, returning)
+}
+
+// ConvertToBulkDelete converts a filter-based delete (e.g., deleteUsers(where: {...}))
+// to SQL DELETE. The filter supports operators and _and/_or/_not groups. An
+// empty filter would delete every row, so it is rejected unless all is true.
+func (c *SQLConverter) ConvertToBulkDelete(
+	ctx context.Context,
+	typeName string,
+	where map[string]interface{},
+	all bool,
+	returning []string,
+) (*SQLMutationResult, error) {
+	if err := c.checkWritable(typeName); err != nil {
+		return nil, err
+	}
 	c.marshaler.Reset()
 
-	tableName := c.getTableName(typeName)
+	tableName := c.getTableName(ctx, typeName)
 	tableAlias := strings.ToLower(typeName[:1])
 
 	// Build WHERE clause
 	whereBuilder := marshal.NewWhereClauseBuilder(c.marshaler)
-	if err := c.buildWhereFromFilter(where, tableAlias, whereBuilder); err != nil {
+	if err := c.buildWhereFromFilter(ctx, typeName, where, tableAlias, whereBuilder); err != nil {
 		return nil, err
 	}
 
@@ -594,18 +2421,135 @@ func (c *SQLConverter) ConvertToDelete(
 		return nil, fmt.Errorf("dialect does not support PostgreSQL DELETE building")
 	}
 
+	var whereClauses []string
+	if clause := whereBuilder.Build(); clause != "" {
+		whereClauses = append(whereClauses, clause)
+	} else if !all {
+		return nil, fmt.Errorf("refusing to delete from %s without a filter; set all: true to delete every row", tableName)
+	}
+	whereClauses, err := c.scopeToTenant(ctx, typeName, tableAlias, whereClauses)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := dialecttypes.PostgreSQLDeleteOptions{
-		TableName:  c.dialect.QuoteIdentifier(tableName),
+		TableName:  c.quoteTable(tableName),
 		TableAlias: tableAlias,
-		Where:      []string{whereBuilder.Build()},
+		Where:      whereClauses,
 		Returning:  returningCols,
 	}
 
 	query := pg.BuildDelete(opts)
 
 	return &SQLMutationResult{
-		Query:     query,
+		Query:     c.Format(query),
 		Params:    c.marshaler.Params(),
 		Operation: "DELETE",
+		Returning: len(returningCols) > 0,
 	}, nil
 }
+
+// CombineMutations chains several mutations into a single statement so they
+// run in one round trip. All but the last run as data-modifying CTEs
+// (WITH mutation_1 AS (...), ...) and the last one produces the result rows.
+// Placeholders of each statement are renumbered to follow the params of the
+// statements before it, and params are concatenated in the same order. The
+// combined query is laid out in the converter's SQL format. Statements with
+// their own WITH clause (e.g., from ConvertToInsertNested) can't be nested
+// in the combined WITH and are rejected.
+func (c *SQLConverter) CombineMutations(mutations []*SQLMutationResult) (*SQLMutationResult, error) {
+	if len(mutations) == 0 {
+		return nil, fmt.Errorf("no mutations to combine")
+	}
+
+	var sb strings.Builder
+	params := make([]interface{}, 0)
+
+	for i, m := range mutations {
+		if m == nil {
+			return nil, fmt.Errorf("mutation %d is nil", i)
+		}
+
+		query := renumberPlaceholders(strings.TrimSuffix(strings.TrimSpace(m.Query), ";"), len(params))
+		params = append(params, m.Params...)
+
+		// A data-modifying WITH must be at the top level of the statement
+		if len(mutations) > 1 && startsWithKeyword(query, "WITH") {
+			return nil, fmt.Errorf("mutation %d has its own WITH clause and cannot be combined", i+1)
+		}
+
+		switch {
+		case i == len(mutations)-1:
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(query)
+		case i == 0:
+			fmt.Fprintf(&sb, "WITH mutation_%d AS (%s)", i+1, query)
+		default:
+			fmt.Fprintf(&sb, ", mutation_%d AS (%s)", i+1, query)
+		}
+	}
+
+	return &SQLMutationResult{
+		Query:     c.Format(sb.String()),
+		Params:    params,
+		Operation: mutations[len(mutations)-1].Operation,
+		Returning: mutations[len(mutations)-1].Returning,
+	}, nil
+}
+
+// startsWithKeyword reports whether query begins with keyword, ignoring case
+func startsWithKeyword(query, keyword string) bool {
+	if len(query) < len(keyword) || !strings.EqualFold(query[:len(keyword)], keyword) {
+		return false
+	}
+	rest := query[len(keyword):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\n' || rest[0] == '\t' || rest[0] == '('
+}
+
+// renumberPlaceholders shifts every $n placeholder outside quoted strings and
+// identifiers by offset
+func renumberPlaceholders(query string, offset int) string {
+	if offset == 0 {
+		return query
+	}
+
+	var sb strings.Builder
+	var quote byte
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		if quote != 0 {
+			if ch == quote {
+				quote = 0
+			}
+			sb.WriteByte(ch)
+			continue
+		}
+
+		if ch == '\'' || ch == '"' {
+			quote = ch
+			sb.WriteByte(ch)
+			continue
+		}
+
+		if ch == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9' {
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err == nil {
+				fmt.Fprintf(&sb, "$%d", n+offset)
+				i = j - 1
+				continue
+			}
+		}
+
+		sb.WriteByte(ch)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,1524 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eddieafk/goinmonster/sql/ast"
+	"github.com/eddieafk/goinmonster/sql/dialect"
+)
+
+// sqlDirective declares @sql for test schemas
+const sqlDirective = `
+directive @sql(
+	column: String
+	table: String
+	relation: String
+	filterable: Boolean
+	sortable: Boolean
+	default: String
+	cast: String
+	index: String
+	readonly: Boolean
+	schema: String
+	primaryKey: String
+	joinOn: String
+	jsonExtract: String
+) on FIELD_DEFINITION | OBJECT
+`
+
+const testSchema = `
+type Query {
+	users(where: UserFilter, orderBy: [UserOrder], limit: Int): [User]
+	posts: [Post]
+}
+
+input UserFilter { fullName: String }
+input UserOrder { field: String direction: String }
+
+type User {
+	id: ID!
+	fullName: String
+	tenantId: ID
+	posts: [Post]
+}
+
+type Post {
+	id: ID!
+	title: String
+	author: User
+}
+`
+
+// newTestConverter builds a PostgreSQL converter over sdl that lays out
+// queries compactly
+func newTestConverter(t *testing.T, sdl string) *SQLConverter {
+	t.Helper()
+	schema, err := NewSchema(sdl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSQLConverter(schema, dialect.PostgreSQL)
+	c.SetSQLFormat(SQLFormatCompact)
+	return c
+}
+
+// listInfo resolves Query.field returning [typeName] with the given fields selected
+func listInfo(field, typeName string, args map[string]interface{}, fields ...*SelectedField) *ResolveInfo {
+	return &ResolveInfo{
+		FieldName:  field,
+		ParentType: "Query",
+		ReturnType: &TypeRef{IsList: true, ListElem: &TypeRef{Name: typeName}},
+		Arguments:  args,
+		Selection:  &SelectionSet{Fields: fields},
+	}
+}
+
+func TestFilterRejectsUnknownKeys(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	tests := []struct {
+		name  string
+		where map[string]interface{}
+	}{
+		{"unknown field", map[string]interface{}{"bogus": "x"}},
+		{"quoted field", map[string]interface{}{`full_name" OR 1=1 --`: "x"}},
+		{"relation without _exists", map[string]interface{}{"posts": "x"}},
+		{"operator injection", map[string]interface{}{"fullName": map[string]interface{}{"IS NOT NULL OR 1=1 OR name =": "x"}}},
+		{"parenthesis", map[string]interface{}{"fullName": map[string]interface{}{") OR (1=1": "x"}}},
+	}
+	for _, tt := range tests {
+		info := listInfo("users", "User", map[string]interface{}{"where": tt.where}, &SelectedField{Name: "id"})
+		if _, err := c.ConvertToSelect(context.Background(), info); err == nil {
+			t.Errorf("%s: filter was accepted", tt.name)
+		}
+	}
+}
+
+func TestFilterUsesMappedColumns(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapFieldToColumn("User", "fullName", "display_name")
+	info := listInfo("users", "User", map[string]interface{}{
+		"where": map[string]interface{}{"fullName": map[string]interface{}{"_eq": "Ann"}},
+	}, &SelectedField{Name: "id"})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `u."display_name" = $1`) {
+		t.Errorf("filter does not use the mapped column:\n%s", result.Query)
+	}
+}
+
+// tenantConverter scopes User and Post to a tenant_id column and relates them
+func tenantConverter(t *testing.T) *SQLConverter {
+	t.Helper()
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Post", "posts")
+	c.ConfigureTenantColumn("User", "tenant_id")
+	c.ConfigureTenantColumn("Post", "tenant_id")
+	c.ConfigureJoin("User", "posts", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "posts", TargetColumn: "author_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+	c.ConfigureJoin("Post", "author", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "author_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinLeft, RelationType: "belongsTo",
+	})
+	return c
+}
+
+func TestTenantScopesJoinsAndExists(t *testing.T) {
+	c := tenantConverter(t)
+	ctx := WithTenant(context.Background(), "t1")
+
+	// belongsTo join
+	info := listInfo("posts", "Post", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "author", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "fullName"}}}})
+	result, err := c.ConvertToSelect(ctx, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `a_aut."tenant_id" = $`) || !strings.Contains(result.Query, `p."tenant_id" = $`) {
+		t.Errorf("join is not tenant-scoped:\n%s", result.Query)
+	}
+
+	// hasMany lateral join
+	info = listInfo("users", "User", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}})
+	result, err = c.ConvertToSelect(ctx, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `"posts"."tenant_id" = $`) {
+		t.Errorf("lateral join is not tenant-scoped:\n%s", result.Query)
+	}
+
+	// EXISTS subquery
+	info = listInfo("users", "User", map[string]interface{}{
+		"where": map[string]interface{}{"posts": map[string]interface{}{"_exists": true}},
+	}, &SelectedField{Name: "id"})
+	result, err = c.ConvertToSelect(ctx, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `u_posts."tenant_id" = $`) {
+		t.Errorf("EXISTS subquery is not tenant-scoped:\n%s", result.Query)
+	}
+
+	// Without a tenant, joins to tenant-scoped types fail
+	if _, err := c.ConvertToSelect(context.Background(), info); err == nil {
+		t.Error("select without a tenant was accepted")
+	}
+}
+
+func TestTenantScopesLeafSelects(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+		type Query { userNames: [String] @sql(table: "users", column: "full_name") }
+		type User { id: ID fullName: String }
+	`)
+	c.MapTypeToTable("User", "users")
+	c.ConfigureTenantColumn("User", "tenant_id")
+	info := &ResolveInfo{FieldName: "userNames", ParentType: "Query", ReturnType: &TypeRef{IsList: true, ListElem: &TypeRef{Name: "String"}}}
+	result, err := c.ConvertToSelect(WithTenant(context.Background(), "t1"), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `u."tenant_id" = $1`) {
+		t.Errorf("leaf select is not tenant-scoped:\n%s", result.Query)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	ctx := context.Background()
+	input := map[string]interface{}{"id": "1", "fullName": "Ann", "tenantId": "t1"}
+
+	result, err := c.ConvertToUpsert(ctx, "User", input, []string{"id"}, []string{"fullName"}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `ON CONFLICT ("id") DO UPDATE SET "full_name" = EXCLUDED."full_name" RETURNING "id"`; !strings.HasSuffix(result.Query, want) {
+		t.Errorf("upsert:\n%s\nwant suffix %s", result.Query, want)
+	}
+	if !strings.HasPrefix(result.Query, `INSERT INTO "users" (`) || len(result.Params) != 3 || !result.Returning {
+		t.Errorf("upsert insert: %s %v", result.Query, result.Params)
+	}
+	checkPlaceholders(t, result.Query, result.Params)
+
+	// Without update columns every inserted column but the conflict target is updated
+	result, err = c.ConvertToUpsert(ctx, "User", input, []string{"id"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, set, _ := strings.Cut(result.Query, "DO UPDATE SET ")
+	for _, col := range []string{`"full_name" = EXCLUDED."full_name"`, `"tenant_id" = EXCLUDED."tenant_id"`} {
+		if !strings.Contains(set, col) {
+			t.Errorf("default update columns miss %s:\n%s", col, result.Query)
+		}
+	}
+	if strings.Contains(set, `"id"`) {
+		t.Errorf("the conflict column is updated:\n%s", result.Query)
+	}
+
+	// Nothing left to update
+	result, err = c.ConvertToUpsert(ctx, "User", map[string]interface{}{"id": "1"}, []string{"id"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "users" ("id") VALUES ($1) ON CONFLICT ("id") DO NOTHING`; result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+
+	if _, err := c.ConvertToUpsert(ctx, "User", input, nil, nil, nil); err == nil {
+		t.Error("upsert without conflict columns was accepted")
+	}
+}
+
+func TestUpsertIsTenantScoped(t *testing.T) {
+	c := tenantConverter(t)
+	ctx := WithTenant(context.Background(), "t1")
+	input := map[string]interface{}{"id": "1", "fullName": "Ann", "tenantId": "t1"}
+
+	result, err := c.ConvertToUpsert(ctx, "User", input, []string{"id"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `DO UPDATE SET "full_name" = EXCLUDED."full_name" WHERE "users"."tenant_id" = $`) {
+		t.Errorf("upsert update is not tenant-scoped:\n%s", result.Query)
+	}
+
+	if _, err := c.ConvertToUpsert(ctx, "User", input, []string{"id"}, []string{"tenantId"}, nil); err == nil {
+		t.Error("upsert updating the tenant column was accepted")
+	}
+	if _, err := c.ConvertToUpsert(context.Background(), "User", input, []string{"id"}, nil, nil); err == nil {
+		t.Error("upsert without a tenant was accepted")
+	}
+}
+
+func TestJSONExtractOnJoins(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+		type Query { posts: [Post] }
+		type User {
+			id: ID!
+			bio: String @sql(column: "data", jsonExtract: "profile.bio")
+		}
+		type Post { id: ID! author: User comments: [Comment] }
+		type Comment {
+			id: ID!
+			lang: String @sql(column: "meta", jsonExtract: "lang")
+			mood: String @sql(column: "meta", jsonExtract: "mood")
+		}
+	`)
+	c.ConfigureJoin("Post", "author", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "author_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinLeft, RelationType: "belongsTo",
+	})
+	c.ConfigureJoin("Post", "comments", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "id", TargetTable: "comments", TargetColumn: "post_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+
+	info := listInfo("posts", "Post", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "author", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "bio"}}}},
+		&SelectedField{Name: "comments", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "lang"}, {Name: "mood"}}}})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`(a_aut."data" #>> '{profile,bio}') AS "a_aut_bio"`,
+		// The lateral subquery selects the shared JSON column once
+		"(SELECT meta FROM",
+		`(c_com."meta" #>> '{lang}') AS "c_com_lang"`,
+		`(c_com."meta" #>> '{mood}') AS "c_com_mood"`,
+	} {
+		if !strings.Contains(result.Query, want) {
+			t.Errorf("query is missing %s:\n%s", want, result.Query)
+		}
+	}
+}
+
+func TestConvertToInsertNested(t *testing.T) {
+	c := newTestConverter(t, `
+		type Query { users: [User] }
+		type User { id: ID! name: String profile: Profile }
+		type Profile { id: ID! bio: String }
+	`)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Profile", "profiles")
+	c.ConfigureJoin("User", "profile", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "profiles", TargetColumn: "user_id",
+		JoinType: ast.JoinLeft, RelationType: "hasOne",
+	})
+	input := map[string]interface{}{"name": "Ann", "profile": map[string]interface{}{"bio": "hi"}}
+
+	result, err := c.ConvertToInsertNested(context.Background(), "User", input, []string{"name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WITH insert_1 AS (INSERT INTO "users" ("name") VALUES ($1) RETURNING "name", "id"), ` +
+		`insert_2 AS (INSERT INTO "profiles" ("bio", "user_id") VALUES ($2, (SELECT "id" FROM insert_1))) ` +
+		`SELECT * FROM insert_1`
+	if result.Query != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.Query, want)
+	}
+	if len(result.Params) != 2 || result.Params[0] != "Ann" || result.Params[1] != "hi" {
+		t.Errorf("params = %v", result.Params)
+	}
+
+	// Without returning fields the parent's primary key is returned
+	result, err = c.ConvertToInsertNested(context.Background(), "User", map[string]interface{}{"name": "Ann"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `RETURNING "id") SELECT * FROM insert_1`) {
+		t.Errorf("parent without returning fields returns nothing:\n%s", result.Query)
+	}
+}
+
+func TestHaving(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	args := map[string]interface{}{
+		"groupBy": []interface{}{"fullName"},
+		"having":  map[string]interface{}{"count": map[string]interface{}{"_gt": 5}},
+	}
+	result, err := c.ConvertToSelect(context.Background(), listInfo("users", "User", args, &SelectedField{Name: "fullName"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `GROUP BY u."full_name" HAVING COUNT(*) > $1`) {
+		t.Errorf("query does not group and filter groups:\n%s", result.Query)
+	}
+	if len(result.Params) != 1 || result.Params[0] != 5 {
+		t.Errorf("params = %v", result.Params)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"without groupBy", map[string]interface{}{
+			"having": map[string]interface{}{"count": map[string]interface{}{"_gt": 5}},
+		}},
+		{"unknown aggregate field", map[string]interface{}{
+			"groupBy": "fullName",
+			"having":  map[string]interface{}{"max": map[string]interface{}{"secret": map[string]interface{}{"_gt": 1}}},
+		}},
+		{"unknown groupBy field", map[string]interface{}{"groupBy": []interface{}{"secret"}}},
+	}
+	for _, tt := range tests {
+		if _, err := c.ConvertToSelect(context.Background(), listInfo("users", "User", tt.args, &SelectedField{Name: "fullName"})); err == nil {
+			t.Errorf("%s: query was accepted", tt.name)
+		}
+	}
+}
+
+func TestIndexWarnings(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+		type Query { users(where: UserFilter): [User] }
+		input UserFilter { fullName: String email: String }
+		type User {
+			id: ID!
+			fullName: String @sql(index: "users_full_name_idx")
+			email: String
+		}
+	`)
+	c.SetIndexWarnings(true)
+
+	if objType, _ := c.schema.GetType("User"); objType.Fields["fullName"].SQLIndex != "users_full_name_idx" {
+		t.Errorf("index name = %q", objType.Fields["fullName"].SQLIndex)
+	}
+
+	filter := func(field string) []string {
+		info := listInfo("users", "User", map[string]interface{}{
+			"where": map[string]interface{}{field: map[string]interface{}{"_eq": "x"}},
+		}, &SelectedField{Name: "id"})
+		result, err := c.ConvertToSelect(context.Background(), info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Warnings
+	}
+	if warnings := filter("fullName"); len(warnings) != 0 {
+		t.Errorf("indexed column warned: %v", warnings)
+	}
+	if warnings := filter("email"); len(warnings) != 1 || !strings.Contains(warnings[0], `"email"`) {
+		t.Errorf("unindexed column: got warnings %v", warnings)
+	}
+}
+
+func TestRelationLimitIsBound(t *testing.T) {
+	c := tenantConverter(t)
+	ctx := WithTenant(context.Background(), "t1")
+	posts := func(limit interface{}) *ResolveInfo {
+		return listInfo("users", "User", nil, &SelectedField{Name: "id"}, &SelectedField{
+			Name:       "posts",
+			Arguments:  map[string]interface{}{"limit": limit},
+			Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}},
+		})
+	}
+
+	result, err := c.ConvertToSelect(ctx, posts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, "LIMIT $2)") {
+		t.Errorf("relation limit is not a parameter:\n%s", result.Query)
+	}
+	if len(result.Params) != 3 || result.Params[1] != int64(3) {
+		t.Errorf("params = %#v", result.Params)
+	}
+
+	for _, limit := range []interface{}{"1; DROP TABLE users", -1, 1.5} {
+		if _, err := c.ConvertToSelect(ctx, posts(limit)); err == nil {
+			t.Errorf("limit %v was accepted", limit)
+		}
+	}
+}
+
+func TestFullTextSearch(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	info := listInfo("users", "User", map[string]interface{}{"search": "ann lee"}, &SelectedField{Name: "id"})
+
+	c.ConfigureFullTextSearch("User", []string{"full_name"}, "")
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `WHERE to_tsvector(coalesce(u."full_name", '')) @@ plainto_tsquery($1)`; !strings.Contains(result.Query, want) {
+		t.Errorf("single column search:\n%s", result.Query)
+	}
+
+	c.ConfigureFullTextSearch("User", []string{"full_name", "bio"}, "english")
+	result, err = c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WHERE to_tsvector('english'::regconfig, coalesce(u."full_name", '') || ' ' || coalesce(u."bio", '')) ` +
+		`@@ plainto_tsquery('english'::regconfig, $1)`
+	if !strings.Contains(result.Query, want) {
+		t.Errorf("multi-column search:\n%s", result.Query)
+	}
+	if len(result.Params) != 1 || result.Params[0] != "ann lee" {
+		t.Errorf("params = %v", result.Params)
+	}
+
+	if _, err := newTestConverter(t, testSchema).ConvertToSelect(context.Background(), info); err == nil {
+		t.Error("search without configuration was accepted")
+	}
+}
+
+func TestCaseInsensitiveEquality(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	info := listInfo("users", "User", map[string]interface{}{
+		"where": map[string]interface{}{"fullName": map[string]interface{}{"_ieq": "Ann"}},
+	}, &SelectedField{Name: "id"})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `LOWER(u."full_name") = LOWER($1)`) {
+		t.Errorf("_ieq does not lower both sides:\n%s", result.Query)
+	}
+	if len(result.Params) != 1 || result.Params[0] != "Ann" {
+		t.Errorf("params = %v", result.Params)
+	}
+}
+
+func TestScalarListField(t *testing.T) {
+	sdl := sqlDirective + `
+type Query {
+	tags: [String!] @sql(table: "tags", column: "name")
+	colors: [String!]
+}
+`
+	c := newTestConverter(t, sdl)
+	result, err := c.ConvertToSelect(context.Background(), &ResolveInfo{
+		FieldName:  "tags",
+		ParentType: "Query",
+		ReturnType: &TypeRef{IsList: true, ListElem: &TypeRef{Name: "String", NonNull: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `SELECT t."name" FROM "tags" t`) {
+		t.Errorf("scalar list does not project its column:\n%s", result.Query)
+	}
+
+	_, err = c.ConvertToSelect(context.Background(), &ResolveInfo{
+		FieldName:  "colors",
+		ParentType: "Query",
+		ReturnType: &TypeRef{IsList: true, ListElem: &TypeRef{Name: "String", NonNull: true}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "@sql") {
+		t.Errorf("unmapped scalar list: err = %v", err)
+	}
+}
+
+func TestOrderByEnum(t *testing.T) {
+	sdl := `
+type Query { users(orderBy: UserSort): [User] }
+enum UserSort { NAME_ASC CREATED_AT_DESC NEWEST }
+type User { id: ID! name: String createdAt: String }
+`
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"NAME_ASC", `ORDER BY u."name" ASC`},
+		{"CREATED_AT_DESC", `ORDER BY u."created_at" DESC`},
+		{"NEWEST", `ORDER BY u."created_at" DESC`},
+	}
+	for _, tt := range tests {
+		c := newTestConverter(t, sdl)
+		c.MapTypeToTable("User", "users")
+		c.ConfigureOrderByEnum("UserSort", "NEWEST", "createdAt", ast.OrderDesc)
+		info := listInfo("users", "User", map[string]interface{}{"orderBy": tt.value}, &SelectedField{Name: "id"})
+		result, err := c.ConvertToSelect(context.Background(), info)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.value, err)
+		}
+		if !strings.Contains(result.Query, tt.want) {
+			t.Errorf("%s:\n%s", tt.value, result.Query)
+		}
+	}
+
+	c := newTestConverter(t, sdl)
+	info := listInfo("users", "User", map[string]interface{}{"orderBy": "ID_ASC"}, &SelectedField{Name: "id"})
+	if _, err := c.ConvertToSelect(context.Background(), info); err == nil {
+		t.Error("a value outside the enum was accepted")
+	}
+}
+
+const restrictedSchema = sqlDirective + `
+type Query { users(where: UserFilter, orderBy: [UserOrder]): [User] }
+input UserFilter { fullName: String email: String }
+input UserOrder { field: String direction: String }
+type User {
+	id: ID!
+	fullName: String
+	email: String @sql(filterable: false, sortable: false)
+}
+`
+
+func TestFilterable(t *testing.T) {
+	filters := []map[string]interface{}{
+		{"email": map[string]interface{}{"_eq": "a@b.c"}},
+		{"_or": []interface{}{
+			map[string]interface{}{"fullName": map[string]interface{}{"_eq": "Ann"}},
+			map[string]interface{}{"email": map[string]interface{}{"_eq": "a@b.c"}},
+		}},
+	}
+	for _, where := range filters {
+		c := newTestConverter(t, restrictedSchema)
+		info := listInfo("users", "User", map[string]interface{}{"where": where}, &SelectedField{Name: "id"})
+		if _, err := c.ConvertToSelect(context.Background(), info); err == nil || !strings.Contains(err.Error(), "User.email is not filterable") {
+			t.Errorf("%v: err = %v", where, err)
+		}
+	}
+
+	c := newTestConverter(t, restrictedSchema)
+	info := listInfo("users", "User", map[string]interface{}{
+		"where": map[string]interface{}{"fullName": map[string]interface{}{"_eq": "Ann"}},
+	}, &SelectedField{Name: "id"})
+	if _, err := c.ConvertToSelect(context.Background(), info); err != nil {
+		t.Errorf("unrestricted field: %v", err)
+	}
+
+	c.SetFilterable("User", "email", true)
+	info.Arguments = map[string]interface{}{"where": filters[0]}
+	if _, err := c.ConvertToSelect(context.Background(), info); err != nil {
+		t.Errorf("SetFilterable override: %v", err)
+	}
+}
+
+func TestSortable(t *testing.T) {
+	orderBy := func(field string) map[string]interface{} {
+		return map[string]interface{}{"orderBy": []interface{}{
+			map[string]interface{}{"field": field, "direction": "DESC"},
+		}}
+	}
+
+	c := newTestConverter(t, restrictedSchema)
+	result, err := c.ConvertToSelect(context.Background(), listInfo("users", "User", orderBy("fullName"), &SelectedField{Name: "id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `ORDER BY u."full_name" DESC`) {
+		t.Errorf("allowed sort:\n%s", result.Query)
+	}
+
+	_, err = c.ConvertToSelect(context.Background(), listInfo("users", "User", orderBy("email"), &SelectedField{Name: "id"}))
+	if err == nil || !strings.Contains(err.Error(), "User.email is not sortable") {
+		t.Errorf("disallowed sort: err = %v", err)
+	}
+
+	c.SetSortable("User", "email", true)
+	if _, err := c.ConvertToSelect(context.Background(), listInfo("users", "User", orderBy("email"), &SelectedField{Name: "id"})); err != nil {
+		t.Errorf("SetSortable override: %v", err)
+	}
+}
+
+func TestCombineMutationsRenumbersPlaceholders(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	result, err := c.CombineMutations([]*SQLMutationResult{
+		{Query: `INSERT INTO "users" ("name", "note") VALUES ($1, '$1 stays') RETURNING "id"`, Params: []interface{}{"Ann"}},
+		{Query: `INSERT INTO "posts" ("title", "body") VALUES ($1, $2);`, Params: []interface{}{"Hi", "..."}},
+		{Query: `UPDATE "users" SET "posts" = $2 WHERE "id" = $1 RETURNING "id"`, Params: []interface{}{7, 1}, Returning: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WITH mutation_1 AS (INSERT INTO "users" ("name", "note") VALUES ($1, '$1 stays') RETURNING "id"), ` +
+		`mutation_2 AS (INSERT INTO "posts" ("title", "body") VALUES ($2, $3)) ` +
+		`UPDATE "users" SET "posts" = $5 WHERE "id" = $4 RETURNING "id"`
+	if result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+	wantParams := []interface{}{"Ann", "Hi", "...", 7, 1}
+	if len(result.Params) != len(wantParams) {
+		t.Fatalf("params = %v, want %v", result.Params, wantParams)
+	}
+	for i := range wantParams {
+		if result.Params[i] != wantParams[i] {
+			t.Errorf("params = %v, want %v", result.Params, wantParams)
+			break
+		}
+	}
+	if !result.Returning {
+		t.Error("combined statement does not return rows")
+	}
+
+	if got := renumberPlaceholders(`"$9" = $9 OR x = $10`, 10); got != `"$9" = $19 OR x = $20` {
+		t.Errorf("renumberPlaceholders = %s", got)
+	}
+}
+
+// checkPlaceholders fails unless the placeholders outside quotes read
+// $1, $2, ... left to right, one per param
+func checkPlaceholders(t *testing.T, query string, params []interface{}) {
+	t.Helper()
+	next := 1
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		switch ch := query[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(query[i+1 : j]); err == nil {
+				if n != next {
+					t.Errorf("placeholder $%d where $%d was expected:\n%s", n, next, query)
+					return
+				}
+				next++
+			}
+		}
+	}
+	if next-1 != len(params) {
+		t.Errorf("%d placeholders for %d params:\n%s", next-1, len(params), query)
+	}
+}
+
+func TestPlaceholdersIncrease(t *testing.T) {
+	c := tenantConverter(t)
+	ctx := WithTenant(context.Background(), "t1")
+	where := map[string]interface{}{
+		"fullName": map[string]interface{}{"_is_null": false},
+		"_or": []interface{}{
+			map[string]interface{}{"fullName": map[string]interface{}{"_eq": "Ann"}},
+			map[string]interface{}{"_and": []interface{}{
+				map[string]interface{}{"fullName": map[string]interface{}{"_like": "A%"}},
+				map[string]interface{}{"id": map[string]interface{}{"_in": []interface{}{1, 2}}},
+			}},
+		},
+		"_not": map[string]interface{}{"id": map[string]interface{}{"_eq": 3}},
+	}
+	info := listInfo("users", "User", map[string]interface{}{"where": where, "limit": 10},
+		&SelectedField{Name: "id"},
+		&SelectedField{Name: "posts", Arguments: map[string]interface{}{"limit": 5}, Selections: &SelectionSet{
+			Fields: []*SelectedField{{Name: "title"}},
+		}},
+	)
+	result, err := c.ConvertToSelect(ctx, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, result.Query, result.Params)
+
+	update, err := c.ConvertToUpdate(ctx, "User", map[string]interface{}{"id": map[string]interface{}{"_eq": 1}},
+		map[string]interface{}{"fullName": "Ann", "id": 1}, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, update.Query, update.Params)
+}
+
+func TestEnumValueMapping(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { users(where: UserFilter): [User] me: User }
+input UserFilter { status: String }
+enum Status { ACTIVE BANNED }
+type User { id: ID! status: Status history: [Status] }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSQLConverter(es.Schema, dialect.PostgreSQL)
+	c.MapTypeToTable("User", "users")
+	if err := c.MapEnumValue("Status", "ACTIVE", "active"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MapEnumValue("Status", "BANNED", "banned"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MapEnumValue("Status", "DELETED", "deleted"); err == nil {
+		t.Error("mapping an unknown enum value was accepted")
+	}
+
+	// GraphQL -> SQL: filters bind the database value
+	info := listInfo("users", "User", map[string]interface{}{"where": map[string]interface{}{
+		"status": map[string]interface{}{"_in": []interface{}{"ACTIVE", "BANNED"}},
+	}}, &SelectedField{Name: "id"})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, ok := result.Params[0].([]interface{})
+	if !ok || len(params) != 2 || params[0] != "active" || params[1] != "banned" {
+		t.Errorf("params = %#v", result.Params)
+	}
+
+	// SQL -> GraphQL: results are mapped back to the enum value name
+	es.RegisterResolver("Query", "me", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"id": "1", "status": []byte("banned"), "history": []string{"active", "banned"}}, nil
+	})
+	if got, want := execute(t, es, `{ me { history status } }`, nil), `{"data":{"me":{"history":["ACTIVE","BANNED"],"status":"BANNED"}}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestInsertDefaultFunctions(t *testing.T) {
+	sdl := sqlDirective + `
+type Query { posts: [Post] }
+type Post {
+	id: ID! @sql(default: "gen_random_uuid()")
+	title: String
+	createdAt: String @sql(default: "NOW()")
+}
+`
+	c := newTestConverter(t, sdl)
+	c.MapTypeToTable("Post", "posts")
+	result, err := c.ConvertToInsert(context.Background(), "Post", map[string]interface{}{"title": "Hi"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "posts" ("title", "created_at", "id") VALUES ($1, now(), gen_random_uuid())`
+	if result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+
+	// A value given by the client is bound as usual
+	result, err = c.ConvertToInsert(context.Background(), "Post", map[string]interface{}{"createdAt": "now()"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "posts" ("created_at", "id") VALUES ($1, gen_random_uuid())`; result.Query != want {
+		t.Errorf("client value is not bound:\ngot  %s\nwant %s", result.Query, want)
+	}
+
+	c = newTestConverter(t, sqlDirective+`
+type Query { posts: [Post] }
+type Post { id: ID! title: String @sql(default: "now()); DROP TABLE posts; --") }
+`)
+	if _, err := c.ConvertToInsert(context.Background(), "Post", map[string]interface{}{"id": 1}, nil); err == nil {
+		t.Error("a default outside the whitelist was accepted")
+	}
+}
+
+func TestBulkDelete(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	where := map[string]interface{}{"_or": []interface{}{
+		map[string]interface{}{"fullName": map[string]interface{}{"_ilike": "%bot%"}},
+		map[string]interface{}{"_and": []interface{}{
+			map[string]interface{}{"tenantId": map[string]interface{}{"_is_null": true}},
+			map[string]interface{}{"id": map[string]interface{}{"_gt": 100}},
+		}},
+	}}
+	result, err := c.ConvertToBulkDelete(context.Background(), "User", where, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `DELETE FROM "users" u WHERE (u."full_name" ILIKE $1 OR u."tenant_id" IS NULL AND u."id" > $2)`
+	if result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+
+	for _, where := range []map[string]interface{}{nil, {}, {"_not": map[string]interface{}{}}} {
+		if _, err := c.ConvertToDelete(context.Background(), "User", where, nil); err == nil || !strings.Contains(err.Error(), "all: true") {
+			t.Errorf("empty filter %v: err = %v", where, err)
+		}
+	}
+
+	result, err = c.ConvertToBulkDelete(context.Background(), "User", nil, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query != `DELETE FROM "users" u` {
+		t.Errorf("delete all: %s", result.Query)
+	}
+}
+
+func TestBulkUpdateRequiresFilter(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	set := map[string]interface{}{"fullName": "Ann"}
+
+	for _, where := range []map[string]interface{}{nil, {}} {
+		if _, err := c.ConvertToUpdate(context.Background(), "User", where, set, nil); err == nil || !strings.Contains(err.Error(), "all: true") {
+			t.Errorf("empty filter %v: err = %v", where, err)
+		}
+	}
+
+	result, err := c.ConvertToBulkUpdate(context.Background(), "User", nil, set, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "users" u SET "full_name" = $1`; result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+
+	result, err = c.ConvertToUpdate(context.Background(), "User", map[string]interface{}{"id": map[string]interface{}{"_eq": 1}}, set, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "users" u SET "full_name" = $1 WHERE u."id" = $2`; result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+}
+
+func TestTableOverride(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	info := listInfo("users", "User", nil, &SelectedField{Name: "id"})
+
+	ctx := WithTableOverride(context.Background(), "User", "active_users")
+	ctx = WithTableOverride(ctx, "Post", "published_posts")
+	result, err := c.ConvertToSelect(ctx, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `FROM "active_users" u`) {
+		t.Errorf("override is not used:\n%s", result.Query)
+	}
+	if table, ok := GetTableOverride(ctx, "Post"); !ok || table != "published_posts" {
+		t.Errorf("Post override = %q, %v", table, ok)
+	}
+
+	// Other conversions keep the static mapping
+	result, err = c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `FROM "users" u`) {
+		t.Errorf("override leaked into another query:\n%s", result.Query)
+	}
+}
+
+func TestColumnCast(t *testing.T) {
+	sdl := sqlDirective + `
+type Query { users(where: UserFilter): [User] }
+input UserFilter { id: ID }
+type User { id: ID! @sql(cast: "uuid") fullName: String }
+`
+	c := newTestConverter(t, sdl)
+	c.MapTypeToTable("User", "users")
+	info := listInfo("users", "User", map[string]interface{}{
+		"where": map[string]interface{}{"id": map[string]interface{}{"_eq": "6f1c"}},
+	}, &SelectedField{Name: "id"}, &SelectedField{Name: "fullName"})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT u."id"::uuid AS "id", u."full_name" FROM "users" u WHERE u."id" = $1::uuid`
+	if !strings.HasPrefix(result.Query, want) {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+
+	c.SetColumnCast("User", "fullName", "text); DROP TABLE users; --")
+	info.Arguments = map[string]interface{}{"where": map[string]interface{}{"fullName": map[string]interface{}{"_eq": "x"}}}
+	if _, err := c.ConvertToSelect(context.Background(), info); err == nil {
+		t.Error("an invalid cast type was accepted")
+	}
+}
+
+func TestIdenticalJoinsAreMerged(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Post", "posts")
+	c.ConfigureJoin("User", "posts", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "posts", TargetColumn: "author_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+	posts := func(alias string, limit interface{}, fields ...string) *SelectedField {
+		sel := &SelectionSet{}
+		for _, f := range fields {
+			sel.Fields = append(sel.Fields, &SelectedField{Name: f})
+		}
+		field := &SelectedField{Name: "posts", Alias: alias, Selections: sel}
+		if limit != nil {
+			field.Arguments = map[string]interface{}{"limit": limit}
+		}
+		return field
+	}
+
+	info := listInfo("users", "User", nil, &SelectedField{Name: "id"},
+		posts("", nil, "title"), posts("again", nil, "id", "title"))
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(result.Query, "LEFT JOIN LATERAL"); n != 1 {
+		t.Errorf("%d joins for identical selections, want 1:\n%s", n, result.Query)
+	}
+	if !strings.Contains(result.Query, "(SELECT title, id FROM") {
+		t.Errorf("merged join does not select both field sets:\n%s", result.Query)
+	}
+	paths := make(map[string]bool)
+	for _, m := range result.ColumnMapping {
+		paths[strings.Join(m.Path, ".")] = true
+	}
+	for _, path := range []string{"posts.title", "again.id", "again.title"} {
+		if !paths[path] {
+			t.Errorf("no column maps to %s: %+v", path, result.ColumnMapping)
+		}
+	}
+
+	// Different arguments need their own join
+	info = listInfo("users", "User", nil, &SelectedField{Name: "id"},
+		posts("", 1, "title"), posts("recent", 5, "title"))
+	result, err = c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(result.Query, "LEFT JOIN LATERAL"); n != 2 {
+		t.Errorf("%d joins for selections with different limits, want 2:\n%s", n, result.Query)
+	}
+}
+
+func TestSchemaQualifiedTables(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "auth.users")
+	c.MapTypeToTable("Post", "posts")
+	c.MapTypeToSchema("Post", "blog")
+	c.ConfigureJoin("User", "posts", &JoinConfig{
+		SourceTable: "auth.users", SourceColumn: "id", TargetTable: "blog.posts", TargetColumn: "author_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+
+	info := listInfo("users", "User", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `FROM "auth"."users" u`) || !strings.Contains(result.Query, `FROM "blog"."posts" WHERE`) {
+		t.Errorf("tables are not schema-qualified:\n%s", result.Query)
+	}
+
+	insert, err := c.ConvertToInsert(context.Background(), "Post", map[string]interface{}{"title": "Hi"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(insert.Query, `INSERT INTO "blog"."posts" `) {
+		t.Errorf("MapTypeToSchema is not applied: %s", insert.Query)
+	}
+}
+
+func TestLimitOffsetAreBound(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	page := func(args map[string]interface{}) *ResolveInfo {
+		return listInfo("users", "User", args, &SelectedField{Name: "id"})
+	}
+
+	result, err := c.ConvertToSelect(context.Background(), page(map[string]interface{}{"limit": 10, "offset": float64(20)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(result.Query, "LIMIT $1 OFFSET $2") {
+		t.Errorf("limit/offset are not parameters:\n%s", result.Query)
+	}
+	if len(result.Params) != 2 || result.Params[0] != int64(10) || result.Params[1] != int64(20) {
+		t.Errorf("params = %#v", result.Params)
+	}
+
+	result, err = c.ConvertToSelect(context.Background(), page(map[string]interface{}{"first": 5, "skip": 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(result.Query, "LIMIT $1 OFFSET $2") {
+		t.Errorf("first/skip are not parameters:\n%s", result.Query)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"limit": "10; DROP TABLE users"},
+		{"limit": 2.5},
+		{"limit": -1},
+		{"offset": "0"},
+		{"offset": -5},
+	} {
+		if _, err := c.ConvertToSelect(context.Background(), page(args)); err == nil {
+			t.Errorf("%v was accepted", args)
+		}
+	}
+}
+
+func TestExistsFilter(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Post", "posts")
+	c.ConfigureJoin("User", "posts", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "posts", TargetColumn: "author_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+	filter := func(posts map[string]interface{}) *ResolveInfo {
+		return listInfo("users", "User", map[string]interface{}{
+			"where": map[string]interface{}{"posts": posts},
+		}, &SelectedField{Name: "id"})
+	}
+
+	tests := []struct {
+		posts map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{"_exists": true},
+			`WHERE EXISTS (SELECT 1 FROM "posts" u_posts WHERE u_posts."author_id" = u."id")`},
+		{map[string]interface{}{"_exists": false},
+			`WHERE NOT EXISTS (SELECT 1 FROM "posts" u_posts WHERE u_posts."author_id" = u."id")`},
+		{map[string]interface{}{"_exists": true, "title": map[string]interface{}{"_eq": "Hi"}},
+			`WHERE EXISTS (SELECT 1 FROM "posts" u_posts WHERE u_posts."author_id" = u."id" AND (u_posts."title" = $1))`},
+	}
+	for _, tt := range tests {
+		result, err := c.ConvertToSelect(context.Background(), filter(tt.posts))
+		if err != nil {
+			t.Fatalf("%v: %v", tt.posts, err)
+		}
+		if !strings.Contains(result.Query, tt.want) {
+			t.Errorf("%v:\ngot  %s\nwant %s", tt.posts, result.Query, tt.want)
+		}
+	}
+
+	if _, err := c.ConvertToSelect(context.Background(), filter(map[string]interface{}{"_exists": "yes"})); err == nil {
+		t.Error("a non-boolean _exists was accepted")
+	}
+}
+
+func TestReadonlyType(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+type Query { reports: [Report] }
+type Report @sql(readonly: true) { id: ID! total: Int }
+`)
+	ctx := context.Background()
+	where := map[string]interface{}{"id": map[string]interface{}{"_eq": 1}}
+
+	info := listInfo("reports", "Report", nil, &SelectedField{Name: "total"})
+	if _, err := c.ConvertToSelect(ctx, info); err != nil {
+		t.Errorf("select on a readonly type failed: %v", err)
+	}
+
+	mutations := map[string]func() (*SQLMutationResult, error){
+		"insert": func() (*SQLMutationResult, error) {
+			return c.ConvertToInsert(ctx, "Report", map[string]interface{}{"total": 1}, nil)
+		},
+		"update": func() (*SQLMutationResult, error) {
+			return c.ConvertToUpdate(ctx, "Report", where, map[string]interface{}{"total": 2}, nil)
+		},
+		"delete": func() (*SQLMutationResult, error) {
+			return c.ConvertToDelete(ctx, "Report", where, nil)
+		},
+	}
+	for name, mutate := range mutations {
+		if _, err := mutate(); err == nil || !strings.Contains(err.Error(), "readonly") {
+			t.Errorf("%s: err = %v, want a readonly error", name, err)
+		}
+	}
+
+	c.SetReadonly("Report", false)
+	if _, err := c.ConvertToInsert(ctx, "Report", map[string]interface{}{"total": 1}, nil); err != nil {
+		t.Errorf("SetReadonly(false) did not override the directive: %v", err)
+	}
+}
+
+func TestOrderTiebreaker(t *testing.T) {
+	orderBy := func(fields ...string) map[string]interface{} {
+		order := make([]interface{}, len(fields))
+		for i, field := range fields {
+			order[i] = map[string]interface{}{"field": field, "direction": "DESC"}
+		}
+		return map[string]interface{}{"orderBy": order}
+	}
+	selectOrder := func(c *SQLConverter, args map[string]interface{}) string {
+		t.Helper()
+		result, err := c.ConvertToSelect(context.Background(), listInfo("users", "User", args, &SelectedField{Name: "id"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i := strings.Index(result.Query, "ORDER BY"); i >= 0 {
+			return strings.SplitN(result.Query[i:], " LIMIT", 2)[0]
+		}
+		return ""
+	}
+
+	c := newTestConverter(t, testSchema)
+	if got := selectOrder(c, orderBy("fullName")); got != `ORDER BY u."full_name" DESC` {
+		t.Errorf("tiebreaker added while disabled: %s", got)
+	}
+
+	c.SetOrderTiebreaker(true)
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{orderBy("fullName"), `ORDER BY u."full_name" DESC, u."id" ASC`},
+		{orderBy("id", "fullName"), `ORDER BY u."id" DESC, u."full_name" DESC`},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := selectOrder(c, tt.args); got != tt.want {
+			t.Errorf("orderBy %v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	c.SetPrimaryKey("User", "tenant_id", "id")
+	if got, want := selectOrder(c, orderBy("fullName")), `ORDER BY u."full_name" DESC, u."tenant_id" ASC, u."id" ASC`; got != want {
+		t.Errorf("composite key: got %q, want %q", got, want)
+	}
+}
+
+func TestCustomJoinOn(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+type Query { posts: [Post] users: [User] }
+type User {
+	id: ID!
+	posts: [Post] @sql(joinOn: "{source}.id = {target}.author_id AND {source}.tenant_id = {target}.tenant_id")
+}
+type Post {
+	id: ID!
+	title: String
+	author: User @sql(joinOn: "{source}.author_id = {target}.id AND {source}.tenant_id = {target}.tenant_id")
+}
+`)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Post", "posts")
+	c.ConfigureJoin("Post", "author", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "author_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinLeft, RelationType: "belongsTo",
+	})
+	c.ConfigureJoin("User", "posts", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "posts", TargetColumn: "author_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	})
+
+	info := listInfo("posts", "Post", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "author", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "id"}}}})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `ON p.author_id = a_aut.id AND p.tenant_id = a_aut.tenant_id`) {
+		t.Errorf("belongsTo join doesn't use the custom condition:\n%s", result.Query)
+	}
+
+	c.ConfigureJoin("Post", "author", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "author_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinLeft, RelationType: "belongsTo",
+		On: "{source}.writer_id = {target}.id",
+	})
+	result, err = c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `ON p.writer_id = a_aut.id`) {
+		t.Errorf("JoinConfig.On doesn't override the directive:\n%s", result.Query)
+	}
+
+	posts := listInfo("users", "User", nil, &SelectedField{Name: "id"},
+		&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}})
+	result, err = c.ConvertToSelect(context.Background(), posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `FROM "posts" WHERE u.id = "posts".author_id AND u.tenant_id = "posts".tenant_id) p_pos ON true`; !strings.Contains(result.Query, want) {
+		t.Errorf("hasMany subquery doesn't use the custom condition:\n%s", result.Query)
+	}
+
+	where := map[string]interface{}{"posts": map[string]interface{}{"_exists": true}}
+	result, err = c.ConvertToSelect(context.Background(), listInfo("users", "User", map[string]interface{}{"where": where}, &SelectedField{Name: "id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `WHERE EXISTS (SELECT 1 FROM "posts" u_posts WHERE (u.id = u_posts.author_id AND u.tenant_id = u_posts.tenant_id))`; !strings.Contains(result.Query, want) {
+		t.Errorf("exists filter doesn't use the custom condition:\n%s", result.Query)
+	}
+}
+
+func TestTypeLevelSQLDirective(t *testing.T) {
+	sdl := sqlDirective + `
+type Query { accounts(orderBy: [AccountOrder]): [Account] }
+input AccountOrder { field: String direction: String }
+type Account @sql(table: "accounts", schema: "billing", primaryKey: "uuid") { uuid: ID! name: String }
+`
+	info := listInfo("accounts", "Account", map[string]interface{}{"orderBy": []interface{}{
+		map[string]interface{}{"field": "name", "direction": "ASC"},
+	}}, &SelectedField{Name: "name"})
+
+	c := newTestConverter(t, sdl)
+	c.SetOrderTiebreaker(true)
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `FROM "billing"."accounts" a ORDER BY a."name" ASC, a."uuid" ASC`; !strings.Contains(result.Query, want) {
+		t.Errorf("directive table and key:\n%s\nwant %s", result.Query, want)
+	}
+
+	c = newTestConverter(t, sdl)
+	c.MapTypeToTable("Account", "legacy_accounts")
+	result, err = c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `FROM "billing"."legacy_accounts" a`; !strings.Contains(result.Query, want) {
+		t.Errorf("MapTypeToTable override:\n%s\nwant %s", result.Query, want)
+	}
+}
+
+func TestSortExpression(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	if err := c.SetSortExpression("User", "fullName", "LOWER({table}.full_name)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetSortExpression("User", "displayName", "COALESCE({table}.nickname, {table}.full_name)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetSortExpression("User", "bad", "name; DELETE FROM users"); err == nil {
+		t.Error("an expression with a terminator was accepted")
+	}
+
+	info := listInfo("users", "User", map[string]interface{}{"orderBy": []interface{}{
+		map[string]interface{}{"field": "fullName", "direction": "DESC"},
+		map[string]interface{}{"field": "displayName"},
+	}}, &SelectedField{Name: "id"})
+	result, err := c.ConvertToSelect(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `ORDER BY LOWER(u.full_name) DESC, COALESCE(u.nickname, u.full_name) ASC`; !strings.Contains(result.Query, want) {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+}
+
+func TestInferInverseJoins(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+type Query { users: [User] posts: [Post] }
+type User {
+	id: ID!
+	name: String
+	posts: [Post]
+	profile: Profile
+}
+type Post { id: ID! title: String author: User }
+type Profile { id: ID! bio: String user: User }
+`)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Post", "posts")
+	c.MapTypeToTable("Profile", "profiles")
+	c.ConfigureJoin("Post", "author", &JoinConfig{
+		SourceTable: "posts", SourceColumn: "author_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinInner, RelationType: "belongsTo",
+	})
+	c.ConfigureJoin("Profile", "user", &JoinConfig{
+		SourceTable: "profiles", SourceColumn: "user_id", TargetTable: "users", TargetColumn: "id",
+		JoinType: ast.JoinInner, RelationType: "belongsTo",
+	})
+	if err := c.InferInverseJoins(); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := c.joinConfig["User.posts"]
+	if posts == nil || posts.RelationType != "hasMany" || posts.SourceColumn != "id" || posts.TargetColumn != "author_id" || posts.JoinType != ast.JoinLeft {
+		t.Errorf("User.posts = %+v, want a left hasMany join on posts.author_id", posts)
+	}
+	if profile := c.joinConfig["User.profile"]; profile == nil || profile.RelationType != "hasOne" {
+		t.Errorf("User.profile = %+v, want hasOne", profile)
+	}
+
+	// Both directions are queryable
+	for _, info := range []*ResolveInfo{
+		listInfo("users", "User", nil, &SelectedField{Name: "name"},
+			&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}}),
+		listInfo("posts", "Post", nil, &SelectedField{Name: "title"},
+			&SelectedField{Name: "author", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "name"}}}}),
+	} {
+		result, err := c.ConvertToSelect(context.Background(), info)
+		if err != nil {
+			t.Fatalf("%s: %v", info.FieldName, err)
+		}
+		if !strings.Contains(result.Query, "author_id") {
+			t.Errorf("%s does not join on author_id:\n%s", info.FieldName, result.Query)
+		}
+	}
+}
+
+func TestInferInverseJoinsAmbiguous(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+type Query { users: [User] }
+type User { id: ID! messages: [Message] }
+type Message { id: ID! sender: User recipient: User }
+`)
+	for _, field := range []string{"sender", "recipient"} {
+		c.ConfigureJoin("Message", field, &JoinConfig{
+			SourceTable: "messages", SourceColumn: field + "_id", TargetTable: "users", TargetColumn: "id",
+			JoinType: ast.JoinInner, RelationType: "belongsTo",
+		})
+	}
+	err := c.InferInverseJoins()
+	if err == nil || !strings.Contains(err.Error(), "configure User.messages explicitly") {
+		t.Fatalf("err = %v, want the ambiguous inverse reported", err)
+	}
+	if _, ok := c.joinConfig["User.messages"]; ok {
+		t.Error("an ambiguous inverse join was inferred")
+	}
+
+	// Naming the inverse explicitly resolves the ambiguity
+	explicit := &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "messages", TargetColumn: "recipient_id",
+		JoinType: ast.JoinLeft, RelationType: "hasMany",
+	}
+	c.ConfigureJoin("User", "messages", explicit)
+	if err := c.InferInverseJoins(); err != nil {
+		t.Fatal(err)
+	}
+	if c.joinConfig["User.messages"] != explicit {
+		t.Error("an explicit join was replaced")
+	}
+}
+
+// noJSONDialect is PostgreSQL without JSON support
+type noJSONDialect struct{ dialect.PostgreSQLDialect }
+
+func (noJSONDialect) SupportsJSON() bool { return false }
+
+func TestConvertToInsertRecordset(t *testing.T) {
+	c := newTestConverter(t, sqlDirective+`
+scalar Money
+enum Status { ACTIVE ARCHIVED }
+type Query { items: [Item] }
+type Item {
+	id: ID! @sql(default: "gen_random_uuid()")
+	qty: Int
+	price: Float @sql(cast: "numeric")
+	tags: [String]
+	status: Status
+	unitName: String
+	cost: Money
+}
+`)
+	c.MapTypeToTable("Item", "items")
+	ctx := context.Background()
+	rows := []map[string]interface{}{
+		{"qty": 1, "price": 2.5, "tags": []interface{}{"a"}, "status": "ACTIVE"},
+		{"qty": 2, "unitName": "box"},
+	}
+
+	result, err := c.ConvertToInsertRecordset(ctx, "Item", rows, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "items" ("price", "qty", "status", "tags", "unit_name", "id")` +
+		` SELECT x."price", x."qty", x."status", x."tags", x."unit_name", gen_random_uuid()` +
+		` FROM jsonb_to_recordset($1::jsonb) AS x("price" numeric, "qty" integer, "status" text, "tags" text[], "unit_name" text)` +
+		` RETURNING "id"`
+	if result.Query != want {
+		t.Errorf("got  %s\nwant %s", result.Query, want)
+	}
+	if len(result.Params) != 1 || !result.Returning || result.Operation != "INSERT" {
+		t.Fatalf("result = %+v", result)
+	}
+	wantParam := `[{"price":2.5,"qty":1,"status":"ACTIVE","tags":["a"]},{"qty":2,"unit_name":"box"}]`
+	if result.Params[0] != wantParam {
+		t.Errorf("param = %v, want %s", result.Params[0], wantParam)
+	}
+
+	if _, err := c.ConvertToInsertRecordset(ctx, "Item", []map[string]interface{}{{"cost": 1}}, nil); err == nil || !strings.Contains(err.Error(), "cast") {
+		t.Errorf("custom scalar without a cast: err = %v", err)
+	}
+	if _, err := c.ConvertToInsertRecordset(ctx, "Item", nil, nil); err == nil {
+		t.Error("an empty batch was accepted")
+	}
+
+	schema, err := NewSchema(sqlDirective + `type Query { items: [Item] } type Item { id: ID! }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noJSON := NewSQLConverter(schema, noJSONDialect{dialect.PostgreSQL})
+	if _, err := noJSON.ConvertToInsertRecordset(ctx, "Item", rows, nil); err == nil {
+		t.Error("a dialect without JSON support built a recordset insert")
+	}
+}
+
+func TestOrderByRejectsUnknownFields(t *testing.T) {
+	orderBy := func(field string) map[string]interface{} {
+		return map[string]interface{}{"orderBy": []interface{}{
+			map[string]interface{}{"field": field, "direction": "ASC"},
+		}}
+	}
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	ctx := context.Background()
+
+	for _, field := range []string{
+		"full_name; DROP TABLE users; --",
+		`fullName" DESC, (SELECT password FROM admins) --`,
+		"password",
+		"posts", // a relation, not a column
+	} {
+		result, err := c.ConvertToSelect(ctx, listInfo("users", "User", orderBy(field), &SelectedField{Name: "id"}))
+		if err == nil || !strings.Contains(err.Error(), "is not a field of User") {
+			t.Errorf("orderBy %q: err = %v", field, err)
+		}
+		if result != nil {
+			t.Errorf("orderBy %q built a query:\n%s", field, result.Query)
+		}
+	}
+
+	result, err := c.ConvertToSelect(ctx, listInfo("users", "User", orderBy("fullName"), &SelectedField{Name: "id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `ORDER BY u."full_name" ASC`) {
+		t.Errorf("schema field:\n%s", result.Query)
+	}
+
+	// A column mapped on the converter may be sorted by without a schema field
+	c.MapFieldToColumn("User", "signupDate", "created_at")
+	result, err = c.ConvertToSelect(ctx, listInfo("users", "User", orderBy("signupDate"), &SelectedField{Name: "id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `ORDER BY u."created_at" ASC`) {
+		t.Errorf("mapped column:\n%s", result.Query)
+	}
+}
+
+func TestCombineMutationsRejectsNestedWith(t *testing.T) {
+	c := newTestConverter(t, `
+		type Query { users: [User] }
+		type User { id: ID! name: String profile: Profile }
+		type Profile { id: ID! bio: String }
+	`)
+	c.MapTypeToTable("User", "users")
+	c.MapTypeToTable("Profile", "profiles")
+	c.ConfigureJoin("User", "profile", &JoinConfig{
+		SourceTable: "users", SourceColumn: "id", TargetTable: "profiles", TargetColumn: "user_id",
+		JoinType: ast.JoinLeft, RelationType: "hasOne",
+	})
+	ctx := context.Background()
+	nested, err := c.ConvertToInsertNested(ctx, "User", map[string]interface{}{"name": "Ann", "profile": map[string]interface{}{"bio": "hi"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove := &SQLMutationResult{Query: `DELETE FROM "profiles" WHERE "bio" = $1`, Params: []interface{}{"old"}}
+
+	for _, mutations := range [][]*SQLMutationResult{{nested, remove}, {remove, nested}} {
+		if result, err := c.CombineMutations(mutations); err == nil || !strings.Contains(err.Error(), "WITH") {
+			t.Errorf("nested WITH was combined: %v\n%v", err, result)
+		}
+	}
+
+	// A lone statement is returned as it is
+	result, err := c.CombineMutations([]*SQLMutationResult{nested})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query != nested.Query {
+		t.Errorf("single statement changed:\n%s", result.Query)
+	}
+}
@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// DecodeInput coerces args as the input object typeName and decodes it into
+// dst, a pointer to a struct whose fields are matched by json tag. Defaults
+// declared in the schema are applied, missing required fields and unknown
+// fields are errors, and custom scalars go through their UnmarshalGraphQL.
+func (s *Schema) DecodeInput(args map[string]interface{}, typeName string, dst interface{}) error {
+	typeRef := &TypeRef{Name: typeName, NonNull: true}
+	coerced, err := s.coerceInputValue(typeRef, args, typeName)
+	if err != nil {
+		return err
+	}
+	coerced, err = s.UnmarshalInput(typeRef, coerced)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(coerced)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", typeName, err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", typeName, err)
+	}
+	return nil
+}
+
+// coerceInputValue applies defaults to the input objects within value and
+// checks their required fields; path names the value in errors
+func (s *Schema) coerceInputValue(typeRef *TypeRef, value interface{}, path string) (interface{}, error) {
+	if value == nil {
+		if typeRef.NonNull {
+			return nil, fmt.Errorf("%s: required value is missing", path)
+		}
+		return nil, nil
+	}
+
+	if typeRef.IsList {
+		items, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one
+			item, err := s.coerceInputValue(typeRef.ListElem, value, path)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := s.coerceInputValue(typeRef.ListElem, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	}
+
+	inputType, ok := s.GetInputType(typeRef.Name)
+	if !ok {
+		return value, nil
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected an input object of type %s, got %T", path, inputType.Name, value)
+	}
+
+	for name := range fields {
+		if _, ok := inputType.Fields[name]; !ok {
+			return nil, fmt.Errorf("%s: unknown field %q", path, name)
+		}
+	}
+
+	result := make(map[string]interface{}, len(inputType.Fields))
+	for name, field := range inputType.Fields {
+		v, given := fields[name]
+		if !given {
+			def, ok := s.inputFieldDefault(inputType.Name, name)
+			if !ok {
+				if field.Type.NonNull {
+					return nil, fmt.Errorf("%s: field %q is required", path, name)
+				}
+				continue
+			}
+			v = def
+		}
+
+		coerced, err := s.coerceInputValue(field.Type, v, path+"."+name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = coerced
+	}
+	return result, nil
+}
+
+// inputFieldDefault returns the default value declared for an input field
+func (s *Schema) inputFieldDefault(typeName, fieldName string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	def := s.schema.Types[typeName]
+	if def == nil {
+		return nil, false
+	}
+	field := def.Fields.ForName(fieldName)
+	if field == nil || field.DefaultValue == nil {
+		return nil, false
+	}
+	v, err := field.DefaultValue.Value(nil)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// coerceVariableNumbers converts the json.Number values of variables decoded
+// with UseNumber by their declared types: int64 for Int, float64 for Float
+// and a string for ID and String. Custom scalars with a registered marshaler
+// receive the json.Number itself so they can keep its full precision.
+func (s *Schema) coerceVariableNumbers(defs ast.VariableDefinitionList, variables map[string]interface{}) map[string]interface{} {
+	if len(variables) == 0 {
+		return variables
+	}
+
+	result := make(map[string]interface{}, len(variables))
+	for name, v := range variables {
+		if def := defs.ForName(name); def != nil {
+			result[name] = s.coerceNumbers(def.Type, v)
+		} else {
+			result[name] = s.coerceNumbers(nil, v)
+		}
+	}
+	return result
+}
+
+// coerceNumbers converts the json.Number values within value by typ; with a
+// nil typ numbers become int64 when integral and float64 otherwise
+func (s *Schema) coerceNumbers(typ *ast.Type, value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		name := ""
+		if typ != nil {
+			name = typ.Name()
+		}
+		switch name {
+		case "Int":
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		case "Float":
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		case "ID", "String":
+			return v.String()
+		default:
+			if scalar, ok := s.GetScalar(name); ok && scalar.Marshaler != nil {
+				return v
+			}
+		}
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+
+	case []interface{}:
+		var elem *ast.Type
+		if typ != nil {
+			elem = typ.Elem
+		}
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = s.coerceNumbers(elem, item)
+		}
+		return result
+
+	case map[string]interface{}:
+		var def *ast.Definition
+		if typ != nil && typ.Elem == nil {
+			def = s.GetSchema().Types[typ.NamedType]
+		}
+		result := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			var fieldType *ast.Type
+			if def != nil {
+				if field := def.Fields.ForName(name); field != nil {
+					fieldType = field.Type
+				}
+			}
+			result[name] = s.coerceNumbers(fieldType, item)
+		}
+		return result
+
+	default:
+		return value
+	}
+}
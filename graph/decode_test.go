@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type createUserInput struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email"`
+	Role    string   `json:"role"`
+	Age     *int     `json:"age"`
+	Tags    []string `json:"tags"`
+	Address *struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	} `json:"address"`
+}
+
+func TestDecodeInput(t *testing.T) {
+	schema, err := NewSchema(`
+scalar Code
+enum Role { ADMIN MEMBER }
+input CreateUserInput {
+	name: String!
+	email: String!
+	role: Role = MEMBER
+	age: Int
+	tags: [String!]
+	address: AddressInput
+}
+input AddressInput { city: String! zip: Code }
+type Query { ok: Boolean }
+type Mutation { createUser(input: CreateUserInput!): Boolean }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.RegisterScalar("Code", upperMarshaler{})
+
+	var user createUserInput
+	err = schema.DecodeInput(map[string]interface{}{
+		"name":    "Ann",
+		"email":   "ann@example.com",
+		"tags":    "admin",
+		"address": map[string]interface{}{"city": "Oslo", "zip": "n0150"},
+	}, "CreateUserInput", &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Ann" || user.Email != "ann@example.com" || user.Age != nil {
+		t.Errorf("user = %+v", user)
+	}
+	if user.Role != "MEMBER" {
+		t.Errorf("role = %q, want the MEMBER default", user.Role)
+	}
+	if !reflect.DeepEqual(user.Tags, []string{"admin"}) {
+		t.Errorf("tags = %v, want a single value coerced to a list", user.Tags)
+	}
+	if user.Address == nil || user.Address.City != "Oslo" || user.Address.Zip != "N0150" {
+		t.Errorf("address = %+v, want a nested input with the Code scalar applied", user.Address)
+	}
+
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"name": "Ann"}, `CreateUserInput: field "email" is required`},
+		{map[string]interface{}{"name": "Ann", "email": nil}, `CreateUserInput.email: required value is missing`},
+		{map[string]interface{}{"name": "Ann", "email": "a@b", "nick": "an"}, `CreateUserInput: unknown field "nick"`},
+		{map[string]interface{}{"name": "Ann", "email": "a@b", "address": map[string]interface{}{}}, `CreateUserInput.address: field "city" is required`},
+	}
+	for _, tt := range tests {
+		var dst createUserInput
+		if err := schema.DecodeInput(tt.args, "CreateUserInput", &dst); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DecodeInput(%v) = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
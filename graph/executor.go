@@ -1,11 +1,22 @@
 package graph
 
 import (
+	"bytes"
 	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/eddieafk/goinmonster/graph/marshal"
 	"github.com/vektah/gqlparser/v2"
 	"github.com/vektah/gqlparser/v2/ast"
 )
@@ -18,8 +29,33 @@ type Executor struct {
 	middleware   []MiddlewareFunc
 	mu           sync.RWMutex
 
+	// Optional mapping applied to response keys (e.g., camelCase -> snake_case)
+	responseKeyTransformer func(string) string
+
+	// Maximum number of operations ExecuteBatch runs at once
+	batchConcurrency int
+
+	// Databases made available to resolvers through DBFromContext
+	dbPool *DBPool
+
+	// Computes RequestContext.NormalizedQuery (e.g., NormalizeQuery)
+	queryNormalizer func(*ast.QueryDocument) string
+
+	// Errors collected per operation when ExecuteParams.MaxErrors is unset
+	maxErrors int
+
+	// Field accessors of registered models: GraphQL type -> accessors
+	models map[string]*modelAccessors
+
+	// Field accessors of struct types met by default resolution
+	structCacheEnabled bool
+	structFields       sync.Map // map[reflect.Type]*modelAccessors
+
 	// AST cache: map[query string] *ast.QueryDocument
 	astCache sync.Map // map[string]*ast.QueryDocument
+
+	// Compiled operations: map[compiledKey]*CompiledOperation
+	compiledCache sync.Map
 }
 
 // NewExecutor creates a new query executor
@@ -28,6 +64,8 @@ func NewExecutor(schema *Schema) *Executor {
 		schema:      schema,
 		resolverMap: NewResolverMap(),
 		middleware:  make([]MiddlewareFunc, 0),
+
+		batchConcurrency: 4,
 	}
 }
 
@@ -52,6 +90,63 @@ func (e *Executor) Use(mw MiddlewareFunc) {
 	e.middleware = append(e.middleware, mw)
 }
 
+// SetResponseKeyTransformer sets a function that maps field names/aliases to
+// the keys used in the response data. A nil transformer keeps keys unchanged.
+func (e *Executor) SetResponseKeyTransformer(fn func(string) string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.responseKeyTransformer = fn
+}
+
+// SetBatchConcurrency sets how many operations ExecuteBatch runs concurrently
+func (e *Executor) SetBatchConcurrency(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	e.batchConcurrency = n
+}
+
+// SetDBPool sets the primary/replica pair added to the context of every
+// operation, unless the caller's context already has one
+func (e *Executor) SetDBPool(pool *DBPool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dbPool = pool
+}
+
+// SetQueryNormalizer sets the function computing the canonical form of each
+// executed query, exposed as RequestContext.NormalizedQuery; nil disables it.
+// It receives a document holding only the executed operation and the
+// fragments that operation uses, so each operation of a document gets its
+// own key.
+func (e *Executor) SetQueryNormalizer(fn func(*ast.QueryDocument) string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queryNormalizer = fn
+}
+
+// SetMaxErrors caps the errors collected per operation, for operations whose
+// ExecuteParams.MaxErrors is unset; 0 means no limit
+func (e *Executor) SetMaxErrors(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxErrors = n
+}
+
+// responseKey returns the response key for a selected field
+func (e *Executor) responseKey(field *SelectedField) string {
+	e.mu.RLock()
+	transform := e.responseKeyTransformer
+	e.mu.RUnlock()
+
+	if transform == nil {
+		return field.GetName()
+	}
+	return transform(field.GetName())
+}
+
 // ExecuteParams contains parameters for query execution
 type ExecuteParams struct {
 	Query         string
@@ -59,6 +154,22 @@ type ExecuteParams struct {
 	Variables     map[string]interface{}
 	Context       context.Context
 	RootValue     interface{}
+
+	// AllowedOperations restricts the operation types that may run
+	// (e.g., []string{"query"}); empty allows all
+	AllowedOperations []string
+
+	// MaxFields and MaxAliases reject operations selecting more fields or
+	// aliases in total (fragment repeats included); zero means no limit
+	MaxFields  int
+	MaxAliases int
+
+	// RequireOperationName rejects anonymous operations
+	RequireOperationName bool
+
+	// MaxErrors caps the errors collected; past it, errors are replaced by a
+	// single "too many errors" error. Zero uses the executor's SetMaxErrors.
+	MaxErrors int
 }
 
 // Execute executes a GraphQL operation
@@ -69,30 +180,147 @@ func (e *Executor) Execute(params ExecuteParams) *Response {
 	}
 
 	// Create request context
-	rc := NewRequestContext()
-	rc.Query = params.Query
-	rc.OperationName = params.OperationName
-	rc.Variables = params.Variables
-	ctx = WithRequestContext(ctx, rc)
+	ctx, rc := newOperationContext(ctx, params)
 
-	// Parse the query
-	doc, err := e.parseQuery(params.Query)
+	// Parse the query and find the operation to execute
+	op, err := e.Compile(params.Query, params.OperationName)
 	if err != nil {
 		rc.AddError(&Error{Message: err.Error()})
 		return NewResponse(rc)
 	}
 
-	// Find the operation to execute
-	operation, err := e.findOperation(doc, params.OperationName)
+	return e.executeCompiled(ctx, rc, op, params)
+}
+
+// subscriptionPhase is the root field value of a subscription: the source
+// channel its resolver returned, or the event being completed
+type subscriptionPhase struct {
+	isEvent bool
+	value   interface{}
+}
+
+// Subscribe executes a subscription. The resolver of its root field returns
+// a channel (of any element type) as the source stream; each value received
+// is completed against the selection set and sent as a response. The returned
+// channel is closed once the source is closed or the context is done. Other
+// operations are executed once and their response is the only one sent.
+func (e *Executor) Subscribe(params ExecuteParams) <-chan *Response {
+	ctx := params.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := make(chan *Response, 1)
+
+	ctx, rc := newOperationContext(ctx, params)
+	op, err := e.Compile(params.Query, params.OperationName)
 	if err != nil {
 		rc.AddError(&Error{Message: err.Error()})
+		out <- NewResponse(rc)
+		close(out)
+		return out
+	}
+	if op.operation.Operation != ast.Subscription {
+		out <- e.executeCompiled(ctx, rc, op, params)
+		close(out)
+		return out
+	}
+
+	// Resolve the source stream
+	phase := &subscriptionPhase{}
+	resp := e.executeCompiled(context.WithValue(ctx, subscriptionKey, phase), rc, op, params)
+	source := reflect.ValueOf(phase.value)
+	if len(resp.Errors) == 0 && (source.Kind() != reflect.Chan || source.Type().ChanDir()&reflect.RecvDir == 0) {
+		rc.AddError(&Error{Message: fmt.Sprintf("subscription resolver returned %T, not a channel", phase.value)})
+		resp = NewResponse(rc)
+	}
+	if len(resp.Errors) > 0 {
+		out <- resp
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: source},
+		}
+		for {
+			chosen, event, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				return
+			}
+			eventCtx, eventRC := newOperationContext(ctx, params)
+			eventCtx = context.WithValue(eventCtx, subscriptionKey, &subscriptionPhase{isEvent: true, value: event.Interface()})
+			select {
+			case out <- e.executeCompiled(eventCtx, eventRC, op, params):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// newOperationContext creates the request context for executing params,
+// carrying over the parent's request ID and the context deadline
+func newOperationContext(ctx context.Context, params ExecuteParams) (context.Context, *RequestContext) {
+	rc := NewRequestContext()
+	rc.Query = params.Query
+	rc.OperationName = params.OperationName
+	rc.Variables = params.Variables
+	if parent := GetRequestContext(ctx); parent != nil {
+		rc.RequestID = parent.RequestID
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		rc.SetDeadline(deadline)
+	}
+	return WithRequestContext(ctx, rc), rc
+}
+
+// executeCompiled runs a compiled operation with the request's variables
+func (e *Executor) executeCompiled(ctx context.Context, rc *RequestContext, op *CompiledOperation, params ExecuteParams) *Response {
+	operation := op.operation
+
+	maxErrors := params.MaxErrors
+	if maxErrors == 0 {
+		e.mu.RLock()
+		maxErrors = e.maxErrors
+		e.mu.RUnlock()
+	}
+	rc.SetMaxErrors(maxErrors)
+
+	// Variables decoded with UseNumber hold json.Number; settle them by the
+	// declared types before anything reads them
+	params.Variables = e.schema.coerceVariableNumbers(operation.VariableDefinitions, params.Variables)
+	rc.Variables = params.Variables
+
+	e.mu.RLock()
+	dbPool := e.dbPool
+	normalizer := e.queryNormalizer
+	e.mu.RUnlock()
+	if normalizer != nil {
+		rc.NormalizedQuery = normalizer(operationDocument(operation, op.fragments))
+	}
+
+	if !operationAllowed(operation.Operation, params.AllowedOperations) {
+		rc.AddError(&Error{
+			Message: fmt.Sprintf("%s operations are not allowed", operation.Operation),
+			Extensions: map[string]interface{}{
+				"code": "OPERATION_NOT_ALLOWED",
+			},
+		})
 		return NewResponse(rc)
 	}
 
-	// Build fragment map
-	fragments := make(map[string]*ast.FragmentDefinition)
-	for _, def := range doc.Fragments {
-		fragments[def.Name] = def
+	if params.RequireOperationName && operation.Name == "" {
+		rc.AddError(&Error{
+			Message: "anonymous operations are not allowed; operations must be named",
+			Extensions: map[string]interface{}{
+				"code": "OPERATION_NAME_REQUIRED",
+			},
+		})
+		return NewResponse(rc)
 	}
 
 	// Create operation context
@@ -102,33 +330,208 @@ func (e *Executor) Execute(params ExecuteParams) *Response {
 		Variables:     params.Variables,
 		Schema:        e.schema,
 		RootResolver:  e.rootResolver,
+		RootValue:     params.RootValue,
 	}
 	rc.Operation = opCtx
 	ctx = WithOperationContext(ctx, opCtx)
 
-	// Collect fields
-	collector := NewFieldCollector(e.schema, fragments, params.Variables)
+	if dbPool != nil && GetDBPool(ctx) == nil {
+		ctx = WithDBPool(ctx, dbPool)
+	}
+
+	// Collect fields, unless they were collected at compile time
+	selections, fieldCount, aliasCount := op.selections, op.fieldCount, op.aliasCount
+	if selections == nil {
+		collector := NewFieldCollector(e.schema, op.fragments, params.Variables)
+		selections = collector.CollectFields(operation.SelectionSet, op.rootType)
+		fieldCount, aliasCount = collector.FieldCount(), collector.AliasCount()
+	}
+
+	if params.MaxFields > 0 && fieldCount > params.MaxFields {
+		rc.AddError(&Error{
+			Message: fmt.Sprintf("operation selects %d fields, exceeding the limit of %d", fieldCount, params.MaxFields),
+			Extensions: map[string]interface{}{
+				"code": "MAX_FIELDS_EXCEEDED",
+			},
+		})
+		return NewResponse(rc)
+	}
+	if params.MaxAliases > 0 && aliasCount > params.MaxAliases {
+		rc.AddError(&Error{
+			Message: fmt.Sprintf("operation uses %d aliases, exceeding the limit of %d", aliasCount, params.MaxAliases),
+			Extensions: map[string]interface{}{
+				"code": "MAX_ALIASES_EXCEEDED",
+			},
+		})
+		return NewResponse(rc)
+	}
+
+	// Execute the operation
+	data, err := e.executeSelectionSet(ctx, selections, op.rootType, params.RootValue, nil)
+	if err != nil {
+		rc.AddError(AsError(err, nil))
+	}
+
+	rc.Data = data
+	resp := NewResponse(rc)
+	resp.executed = true
+	return resp
+}
+
+// CompiledOperation is a parsed and validated operation that can be executed
+// repeatedly with different variables
+type CompiledOperation struct {
+	executor  *Executor
+	query     string
+	operation *ast.OperationDefinition
+	fragments map[string]*ast.FragmentDefinition
+	rootType  string
+
+	// Collected once when the operation declares no variables, since
+	// @skip/@include and arguments can't change between executions
+	selections *SelectionSet
+	fieldCount int
+	aliasCount int
+}
+
+// compiledKey identifies a compiled operation in the cache
+type compiledKey struct {
+	query         string
+	operationName string
+}
+
+// Compile parses and validates a query and selects the operation to run.
+// Compiled operations are cached by (query, operationName).
+func (e *Executor) Compile(query, operationName string) (*CompiledOperation, error) {
+	key := compiledKey{query: query, operationName: operationName}
+	if cached, ok := e.compiledCache.Load(key); ok {
+		return cached.(*CompiledOperation), nil
+	}
+
+	doc, err := e.parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	operation, err := e.findOperation(doc, operationName)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &CompiledOperation{
+		executor:  e,
+		query:     query,
+		operation: operation,
+		fragments: make(map[string]*ast.FragmentDefinition, len(doc.Fragments)),
+	}
+	for _, def := range doc.Fragments {
+		op.fragments[def.Name] = def
+	}
 
-	var rootType string
 	switch operation.Operation {
 	case ast.Query:
-		rootType = "Query"
+		op.rootType = "Query"
 	case ast.Mutation:
-		rootType = "Mutation"
+		op.rootType = "Mutation"
 	case ast.Subscription:
-		rootType = "Subscription"
+		op.rootType = "Subscription"
 	}
 
-	selections := collector.CollectFields(operation.SelectionSet, rootType)
+	if len(operation.VariableDefinitions) == 0 {
+		collector := NewFieldCollector(e.schema, op.fragments, nil)
+		op.selections = collector.CollectFields(operation.SelectionSet, op.rootType)
+		op.fieldCount, op.aliasCount = collector.FieldCount(), collector.AliasCount()
+	}
 
-	// Execute the operation
-	data, err := e.executeSelectionSet(ctx, selections, rootType, params.RootValue, nil)
-	if err != nil {
-		rc.AddError(&Error{Message: err.Error()})
+	// Only cache against the current schema; a concurrent Reload drops the
+	// parsed document and this operation with it
+	e.mu.RLock()
+	if cached, ok := e.astCache.Load(query); ok && cached == doc {
+		e.compiledCache.Store(key, op)
 	}
+	e.mu.RUnlock()
 
-	rc.Data = data
-	return NewResponse(rc)
+	return op, nil
+}
+
+// Execute runs the compiled operation with the given variables
+func (op *CompiledOperation) Execute(ctx context.Context, variables map[string]interface{}) *Response {
+	return op.ExecuteWithParams(ExecuteParams{
+		Context:   ctx,
+		Variables: variables,
+	})
+}
+
+// ExecuteWithParams runs the compiled operation with full execution
+// parameters; Query and OperationName are taken from the compiled operation
+func (op *CompiledOperation) ExecuteWithParams(params ExecuteParams) *Response {
+	ctx := params.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	params.Query = op.query
+	params.OperationName = op.operation.Name
+
+	ctx, rc := newOperationContext(ctx, params)
+
+	return op.executor.executeCompiled(ctx, rc, op, params)
+}
+
+// ExecuteString executes a query and returns the JSON-encoded response
+func (e *Executor) ExecuteString(ctx context.Context, query string, variables map[string]interface{}) ([]byte, error) {
+	resp := e.Execute(ExecuteParams{
+		Query:     query,
+		Variables: variables,
+		Context:   ctx,
+	})
+	return json.Marshal(resp)
+}
+
+// ExecuteBatch executes several operations concurrently and returns their
+// responses in the same order. Operations without their own Context use ctx,
+// and a panic in one operation only fails that operation's response
+// with an INTERNAL_SERVER_ERROR; the panic value is not exposed.
+func (e *Executor) ExecuteBatch(ctx context.Context, batch []ExecuteParams) []*Response {
+	e.mu.RLock()
+	concurrency := e.batchConcurrency
+	e.mu.RUnlock()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	responses := make([]*Response, len(batch))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, params := range batch {
+		if params.Context == nil {
+			params.Context = ctx
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, params ExecuteParams) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				if rec := recover(); rec != nil {
+					responses[i] = &Response{
+						Errors: []*Error{{
+							Message: "internal server error",
+							Extensions: map[string]interface{}{
+								"code": "INTERNAL_SERVER_ERROR",
+							},
+						}},
+					}
+				}
+			}()
+
+			responses[i] = e.Execute(params)
+		}(i, params)
+	}
+
+	wg.Wait()
+	return responses
 }
 
 // parseQuery parses a GraphQL query document
@@ -140,6 +543,11 @@ func (e *Executor) parseQuery(query string) (*ast.QueryDocument, error) {
 		}
 	}
 
+	// Hold the read lock so a concurrent Reload can't leave a document parsed
+	// against the old schema in the cache
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
 	doc, errs := gqlparser.LoadQuery(e.schema.GetSchema(), query)
 	if len(errs) > 0 {
 		return nil, errs[0]
@@ -149,6 +557,31 @@ func (e *Executor) parseQuery(query string) (*ast.QueryDocument, error) {
 	return doc, nil
 }
 
+// clearCache drops all cached query documents and compiled operations
+func (e *Executor) clearCache() {
+	e.astCache.Range(func(key, _ interface{}) bool {
+		e.astCache.Delete(key)
+		return true
+	})
+	e.compiledCache.Range(func(key, _ interface{}) bool {
+		e.compiledCache.Delete(key)
+		return true
+	})
+}
+
+// operationAllowed reports whether the operation type is in the allowed list (empty allows all)
+func operationAllowed(op ast.Operation, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, string(op)) {
+			return true
+		}
+	}
+	return false
+}
+
 // findOperation finds the operation to execute
 func (e *Executor) findOperation(doc *ast.QueryDocument, operationName string) (*ast.OperationDefinition, error) {
 	if len(doc.Operations) == 0 {
@@ -179,30 +612,37 @@ func (e *Executor) executeSelectionSet(
 	parentValue interface{},
 	path []interface{},
 ) (map[string]interface{}, error) {
-	if selections == nil || len(selections.Fields) == 0 {
+	if selections == nil || (len(selections.Fields) == 0 && !selections.Typename) {
 		return nil, nil
 	}
 
 	result := make(map[string]interface{})
 
+	// Interfaces and unions resolve fields against the concrete runtime type
+	if e.schema.IsAbstractType(parentType) {
+		parentType = e.runtimeType(parentType, parentValue)
+	}
+
 	for _, field := range selections.Fields {
-		fieldPath := append(path, field.GetName())
+		if !field.AppliesTo(parentType) {
+			continue
+		}
+
+		key := e.responseKey(field)
+		fieldPath := append(path, key)
 
 		value, err := e.executeField(ctx, field, parentType, parentValue, fieldPath)
 		if err != nil {
 			// Continue execution but record error
 			rc := GetRequestContext(ctx)
 			if rc != nil {
-				rc.AddError(&Error{
-					Message: err.Error(),
-					Path:    fieldPath,
-				})
+				rc.AddError(AsError(err, fieldPath))
 			}
-			result[field.GetName()] = nil
+			result[key] = nil
 			continue
 		}
 
-		result[field.GetName()] = value
+		result[key] = value
 	}
 
 	// Add __typename if requested
@@ -213,6 +653,17 @@ func (e *Executor) executeSelectionSet(
 	return result, nil
 }
 
+// runtimeType determines the concrete type of a value returned for an abstract
+// type from its __typename, falling back to the abstract type itself
+func (e *Executor) runtimeType(abstractType string, value interface{}) string {
+	if name, _ := e.defaultResolve(abstractType, value, "__typename"); name != nil {
+		if typeName, ok := name.(string); ok && e.schema.isPossibleType(abstractType, typeName) {
+			return typeName
+		}
+	}
+	return abstractType
+}
+
 // executeField executes a single field
 func (e *Executor) executeField(
 	ctx context.Context,
@@ -235,24 +686,51 @@ func (e *Executor) executeField(
 
 	// Build resolve info
 	info := &ResolveInfo{
-		FieldName:  field.Name,
-		ParentType: parentType,
-		Arguments:  field.Arguments,
-		Variables:  GetRequestContext(ctx).Variables,
-		Selection:  field.Selections,
-		Path:       toStringPath(path),
-		RootValue:  GetRequestContext(ctx).Data,
+		FieldName:   field.Name,
+		ParentType:  parentType,
+		Arguments:   field.Arguments,
+		Variables:   GetRequestContext(ctx).Variables,
+		Selection:   field.Selections,
+		Directives:  field.Directives,
+		Path:        toStringPath(path),
+		ParentValue: parentValue,
 	}
 
 	if opCtx := GetOperationContext(ctx); opCtx != nil {
 		info.OperationCtx = opCtx
+		info.RootValue = opCtx.RootValue
 	}
 
 	// Get the field type
+	args := field.Arguments
+	var err error
 	objType, _ := e.schema.GetType(parentType)
 	if objType != nil {
 		if fieldDef, ok := objType.Fields[field.Name]; ok {
 			info.ReturnType = fieldDef.Type
+
+			// Literals are checked by validation; variables are only known
+			// here. Arguments are copied since compiled selections are shared.
+			if len(field.Arguments) > 0 {
+				coerced := make(map[string]interface{}, len(field.Arguments))
+				for name, v := range field.Arguments {
+					coerced[name] = v
+				}
+				for name, argDef := range fieldDef.Arguments {
+					v, ok := field.Arguments[name]
+					if !ok {
+						continue
+					}
+					if err := e.schema.ValidateOneOf(argDef.Type, v); err != nil {
+						return nil, err
+					}
+					if coerced[name], err = e.schema.UnmarshalInput(argDef.Type, v); err != nil {
+						return nil, err
+					}
+				}
+				args = coerced
+				info.Arguments = args
+			}
 		}
 	}
 
@@ -260,34 +738,61 @@ func (e *Executor) executeField(
 
 	// Try to resolve the field
 	var value interface{}
-	var err error
 
 	// Check for registered resolver
 	e.mu.RLock()
 	resolver, hasResolver := e.resolverMap.Get(parentType, field.Name)
 	e.mu.RUnlock()
 
-	if hasResolver {
-		value, err = resolver.Resolve(ctx, field.Arguments)
+	// Subscriptions resolve their root field to the source channel once, then
+	// complete each event received from it (see Subscribe)
+	phase, subscribing := ctx.Value(subscriptionKey).(*subscriptionPhase)
+	subscribing = subscribing && len(path) == 1
+
+	if subscribing && phase.isEvent {
+		value = phase.value
+	} else if hasResolver {
+		// Don't start resolvers once the request has run out of time
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &Error{
+				Message: "request deadline exceeded",
+				Extensions: map[string]interface{}{
+					"code": "DEADLINE_EXCEEDED",
+				},
+			}
+		}
+		value, err = resolver.Resolve(ctx, args)
 	} else {
 		// Default field resolution (from parent value)
-		value, err = e.defaultResolve(parentValue, field.Name)
+		value, err = e.defaultResolve(parentType, parentValue, field.Name)
 	}
 
 	if err != nil {
 		return nil, err
 	}
+	if subscribing && !phase.isEvent {
+		phase.value = value
+		return nil, nil
+	}
 
 	// Complete the value (handle lists, objects, etc.)
 	return e.completeValue(ctx, field, parentType, value, path)
 }
 
 // defaultResolve resolves a field from the parent value using reflection
-func (e *Executor) defaultResolve(parent interface{}, fieldName string) (interface{}, error) {
+func (e *Executor) defaultResolve(parentType string, parent interface{}, fieldName string) (interface{}, error) {
 	if parent == nil {
 		return nil, nil
 	}
 
+	// Registered models have precomputed accessors
+	if value, ok, err := e.resolveModelField(parentType, parent, fieldName); ok {
+		return value, err
+	}
+	if value, ok, err := e.resolveCachedField(parent, fieldName); ok {
+		return value, err
+	}
+
 	val := reflect.ValueOf(parent)
 
 	// Handle pointers
@@ -309,10 +814,19 @@ func (e *Executor) defaultResolve(parent interface{}, fieldName string) (interfa
 		}
 
 	case reflect.Struct:
+		// Go names are exported, so GraphQL names are tried as written and
+		// with the first letter upper-cased (name -> Name)
+		goNames := []string{fieldName}
+		if capitalized := capitalize(fieldName); capitalized != fieldName {
+			goNames = append(goNames, capitalized)
+		}
+
 		// Try field by name
-		fieldVal := val.FieldByName(fieldName)
-		if fieldVal.IsValid() && fieldVal.CanInterface() {
-			return fieldVal.Interface(), nil
+		for _, name := range goNames {
+			fieldVal := val.FieldByName(name)
+			if fieldVal.IsValid() && fieldVal.CanInterface() {
+				return fieldVal.Interface(), nil
+			}
 		}
 
 		// Try field by tag
@@ -326,12 +840,20 @@ func (e *Executor) defaultResolve(parent interface{}, fieldName string) (interfa
 			}
 		}
 
-		// Try method
-		method := reflect.ValueOf(parent).MethodByName(capitalize(fieldName))
-		if method.IsValid() {
-			results := method.Call(nil)
-			if len(results) > 0 {
-				return results[0].Interface(), nil
+		// Try method; values are copied to reach pointer-receiver methods,
+		// as the struct field cache does
+		recv := reflect.ValueOf(parent)
+		if recv.Kind() != reflect.Ptr {
+			recv = reflect.New(val.Type())
+			recv.Elem().Set(val)
+		}
+		for _, name := range goNames {
+			method := recv.MethodByName(name)
+			if method.IsValid() && method.Type().NumIn() == 0 {
+				results := method.Call(nil)
+				if len(results) > 0 {
+					return results[0].Interface(), nil
+				}
 			}
 		}
 	}
@@ -350,6 +872,7 @@ func (e *Executor) completeValue(
 	if value == nil {
 		return nil, nil
 	}
+	original := value
 
 	val := reflect.ValueOf(value)
 
@@ -362,15 +885,65 @@ func (e *Executor) completeValue(
 		value = val.Interface()
 	}
 
+	fieldType, known := e.schema.FieldType(parentType, field.Name)
+
+	// A jsonb column scanned as []byte or json.RawMessage is JSON text, which
+	// would otherwise be encoded as a base64 string
+	if known && !fieldType.IsList && jsonScalarNames[fieldType.Name] {
+		if raw, ok := rawJSON(value); ok {
+			decoded, err := decodeRawJSON(raw)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: invalid JSON: %w", field.Name, err)
+			}
+			if scalar, ok := e.schema.GetScalar(fieldType.Name); ok && scalar.Marshaler != nil {
+				return scalar.Marshaler.MarshalGraphQL(decoded)
+			}
+			return decoded, nil
+		}
+	}
+
+	isList := val.Kind() == reflect.Slice || val.Kind() == reflect.Array
+
+	// Custom scalars are serialized by their registered marshaler. A slice is
+	// only the scalar value itself (e.g., JSON) when the field isn't a list.
+	if scalar, ok := e.schema.GetScalar(unwrapTypeName(fieldType)); ok && scalar.Marshaler != nil {
+		if !isList || !known || !fieldType.IsList {
+			return scalar.Marshaler.MarshalGraphQL(value)
+		}
+	}
+
+	// Values that write their own GraphQL JSON (marshal.Marshaler)
+	if m, ok := original.(marshal.Marshaler); ok {
+		var buf bytes.Buffer
+		if err := m.MarshalGQL(&buf); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(buf.Bytes()), nil
+	}
+
+	// Built-in scalars are serialized as the field's declared type (e.g., an
+	// integer id column as an ID string); list items arrive here one by one
+	if fieldType != nil && builtinScalars[unwrapTypeName(fieldType)] && (!isList || !fieldType.IsList) {
+		return coerceOutputScalar(unwrapTypeName(fieldType), value)
+	}
+
+	// Enum values are stored by their internal value (see Schema.SetEnumValue);
+	// a text column may scan as []byte
+	if !isList || !known || !fieldType.IsList {
+		if name, ok := e.schema.EnumValueName(unwrapTypeName(fieldType), value); ok {
+			return name, nil
+		}
+	}
+
 	// Handle slices/arrays
-	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+	if isList {
 		return e.completeListValue(ctx, field, parentType, val, path)
 	}
 
 	// Handle maps and structs (object types)
 	if val.Kind() == reflect.Map || val.Kind() == reflect.Struct {
-		if field.HasSelection() {
-			fieldType := e.getFieldTypeName(parentType, field.Name)
+		if field.HasSelection() || (field.Selections != nil && field.Selections.Typename) {
+			fieldType := e.schema.FieldBaseTypeName(parentType, field.Name)
 			return e.executeSelectionSet(ctx, field.Selections, fieldType, value, path)
 		}
 	}
@@ -379,6 +952,37 @@ func (e *Executor) completeValue(
 	return value, nil
 }
 
+// jsonScalarNames are the scalars whose raw JSON values are decoded
+var jsonScalarNames = map[string]bool{
+	"JSON":  true,
+	"JSONB": true,
+}
+
+// rawJSON returns the bytes of a value scanned from a JSON column
+func rawJSON(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case json.RawMessage:
+		return v, true
+	case []byte:
+		return v, true
+	}
+	return nil, false
+}
+
+// decodeRawJSON decodes JSON text, keeping numbers exact
+func decodeRawJSON(raw []byte) (interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 // completeListValue completes a list value
 func (e *Executor) completeListValue(
 	ctx context.Context,
@@ -387,6 +991,10 @@ func (e *Executor) completeListValue(
 	val reflect.Value,
 	path []interface{},
 ) ([]interface{}, error) {
+	if result, ok := e.completeScalarList(field, parentType, val); ok {
+		return result, nil
+	}
+
 	result := make([]interface{}, val.Len())
 
 	for i := 0; i < val.Len(); i++ {
@@ -404,32 +1012,218 @@ func (e *Executor) completeListValue(
 	return result, nil
 }
 
-// getFieldTypeName gets the return type name for a field
-func (e *Executor) getFieldTypeName(parentType, fieldName string) string {
-	objType, ok := e.schema.GetType(parentType)
-	if !ok {
-		return ""
+// completeScalarList copies lists of built-in scalars without completing each
+// element. It reports false when the list needs the per-element path
+func (e *Executor) completeScalarList(field *SelectedField, parentType string, val reflect.Value) ([]interface{}, bool) {
+	if field.HasSelection() || val.Kind() != reflect.Slice {
+		return nil, false
 	}
 
-	field, ok := objType.Fields[fieldName]
-	if !ok {
-		return ""
+	typeName := e.schema.FieldBaseTypeName(parentType, field.Name)
+	switch typeName {
+	case "Int", "Float", "String", "Boolean", "ID":
+	default:
+		return nil, false
+	}
+	if scalar, ok := e.schema.GetScalar(typeName); ok && scalar.Marshaler != nil {
+		return nil, false
 	}
 
-	return unwrapTypeName(field.Type)
+	// Only elements already in the output representation of the type can
+	// skip coercion (e.g., not []int for [ID])
+	if items, ok := val.Interface().([]interface{}); ok {
+		for _, item := range items {
+			if item != nil && !scalarFits(typeName, item) {
+				return nil, false
+			}
+		}
+		return copyScalars(items), true
+	}
+	if val.Len() > 0 && !scalarFits(typeName, val.Index(0).Interface()) {
+		return nil, false
+	}
+
+	switch items := val.Interface().(type) {
+	case []string:
+		return copyScalars(items), true
+	case []int:
+		return copyScalars(items), true
+	case []int32:
+		return copyScalars(items), true
+	case []int64:
+		return copyScalars(items), true
+	case []float32:
+		return copyScalars(items), true
+	case []float64:
+		return copyScalars(items), true
+	case []bool:
+		return copyScalars(items), true
+	}
+	return nil, false
 }
 
-// unwrapTypeName unwraps a TypeRef to get the underlying type name
-func unwrapTypeName(t *TypeRef) string {
-	if t == nil {
-		return ""
+// scalarFits reports whether v is already serialized as the built-in scalar
+// typeName requires
+func scalarFits(typeName string, v interface{}) bool {
+	switch v.(type) {
+	case string:
+		return typeName == "String" || typeName == "ID"
+	case int32:
+		return typeName == "Int" || typeName == "Float"
+	case int, int64:
+		return typeName == "Float"
+	case float32, float64:
+		return typeName == "Float"
+	case bool:
+		return typeName == "Boolean"
+	}
+	return false
+}
+
+// builtinScalars are the scalars serialized by coerceOutputScalar
+var builtinScalars = map[string]bool{
+	"Int":     true,
+	"Float":   true,
+	"String":  true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// coerceOutputScalar serializes a value as the built-in scalar typeName, as
+// the spec requires: ID as a string, Int as a 32-bit integer (rejecting
+// fractional and out-of-range values), Float as a number and Boolean as a bool.
+// Text marshalers such as time.Time serialize as their text form.
+func coerceOutputScalar(typeName string, value interface{}) (interface{}, error) {
+	if scalarFits(typeName, value) {
+		return value, nil
+	}
+	if n, ok := value.(json.Number); ok {
+		value = string(n)
+		if typeName != "String" && typeName != "ID" {
+			if i, err := n.Int64(); err == nil {
+				value = i
+			} else if f, err := n.Float64(); err == nil {
+				value = f
+			}
+		}
 	}
 
-	if t.IsList {
-		return unwrapTypeName(t.ListElem)
+	rv := reflect.ValueOf(value)
+	switch typeName {
+	case "ID", "String":
+		switch rv.Kind() {
+		case reflect.String:
+			return rv.String(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+		case reflect.Bool:
+			if typeName == "String" {
+				return strconv.FormatBool(rv.Bool()), nil
+			}
+		}
+		if b, ok := value.([]byte); ok {
+			return string(b), nil
+		}
+		if m, ok := value.(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				return nil, fmt.Errorf("%s cannot represent value: %w", typeName, err)
+			}
+			return string(text), nil
+		}
+		if s, ok := value.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+
+	case "Int":
+		if i, ok, err := intScalar(rv, value); err != nil || ok {
+			return i, err
+		}
+
+	case "Float":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), nil
+		case reflect.String:
+			if f, err := strconv.ParseFloat(rv.String(), 64); err == nil {
+				return f, nil
+			}
+		case reflect.Bool:
+			if rv.Bool() {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		}
+
+	case "Boolean":
+		switch rv.Kind() {
+		case reflect.Bool:
+			return rv.Bool(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int() != 0, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return rv.Uint() != 0, nil
+		}
 	}
 
-	return t.Name
+	return nil, fmt.Errorf("%s cannot represent value: %v", typeName, value)
+}
+
+// intScalar converts rv to a GraphQL Int, reporting whether rv holds an
+// integral value and rejecting values outside the signed 32-bit range
+func intScalar(rv reflect.Value, value interface{}) (interface{}, bool, error) {
+	var i int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt32 {
+			return nil, false, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %v", value)
+		}
+		i = int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, false, fmt.Errorf("Int cannot represent non-integer value: %v", value)
+		}
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, false, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %v", value)
+		}
+		i = int64(f)
+	case reflect.String:
+		parsed, err := strconv.ParseInt(rv.String(), 10, 64)
+		if err != nil {
+			return nil, false, nil
+		}
+		i = parsed
+	case reflect.Bool:
+		if rv.Bool() {
+			i = 1
+		}
+	default:
+		return nil, false, nil
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return nil, false, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %v", value)
+	}
+	return i, true, nil
+}
+
+// copyScalars copies a typed scalar slice into a result list
+func copyScalars[T any](items []T) []interface{} {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+	return result
 }
 
 // toStringPath converts an interface path to a string path
@@ -441,12 +1235,13 @@ func toStringPath(path []interface{}) []string {
 	return result
 }
 
-// capitalize capitalizes the first letter
+// capitalize upper-cases the first rune, leaving the rest unchanged
 func capitalize(s string) string {
-	if s == "" {
-		return ""
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
 	}
-	return string(s[0]-32) + s[1:]
+	return string(unicode.ToUpper(r)) + s[size:]
 }
 
 // ExecutableSchema combines schema and executor for execution
@@ -476,6 +1271,25 @@ func (es *ExecutableSchema) Execute(ctx context.Context, params ExecuteParams) *
 	return es.Executor.Execute(params)
 }
 
+// Subscribe executes a subscription operation (see Executor.Subscribe)
+func (es *ExecutableSchema) Subscribe(ctx context.Context, params ExecuteParams) <-chan *Response {
+	params.Context = ctx
+	return es.Executor.Subscribe(params)
+}
+
+// Reload swaps in a new schema SDL and clears the query cache. Resolvers and
+// middleware are kept; an invalid SDL is rejected and the old schema stays active.
+func (es *ExecutableSchema) Reload(schemaString string) error {
+	es.Executor.mu.Lock()
+	defer es.Executor.mu.Unlock()
+
+	if err := es.Schema.Reload(schemaString); err != nil {
+		return err
+	}
+	es.Executor.clearCache()
+	return nil
+}
+
 // SetResolvers sets the resolver map
 func (es *ExecutableSchema) SetResolvers(rm *ResolverMap) {
 	es.Executor.SetResolverMap(rm)
@@ -520,18 +1334,30 @@ func (e *Executor) introspectSchema(ctx context.Context, field *SelectedField) (
 					result["subscriptionType"] = nil
 				}
 			case "types":
-				types := make([]map[string]interface{}, 0)
-				for name, typeDef := range schema.Types {
+				// Types and directives are maps; sort them so the output is
+				// stable for clients that cache or diff it
+				names := make([]string, 0, len(schema.Types))
+				for name := range schema.Types {
 					if len(name) >= 2 && name[:2] == "__" {
 						continue // skip introspection types
 					}
-					types = append(types, e.buildFullType(typeDef, sel, name))
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				types := make([]map[string]interface{}, 0, len(names))
+				for _, name := range names {
+					types = append(types, e.buildFullType(schema.Types[name], sel, name))
 				}
 				result["types"] = types
 			case "directives":
-				directives := make([]map[string]interface{}, 0)
-				for _, dir := range schema.Directives {
-					directives = append(directives, e.buildDirective(dir, sel))
+				names := make([]string, 0, len(schema.Directives))
+				for name := range schema.Directives {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				directives := make([]map[string]interface{}, 0, len(names))
+				for _, name := range names {
+					directives = append(directives, e.buildDirective(schema.Directives[name], sel))
 				}
 				result["directives"] = directives
 			case "description":
@@ -570,6 +1396,8 @@ func (e *Executor) buildTypeRef(def *ast.Definition, field *SelectedField) map[s
 				result["interfaces"] = e.buildInterfaces(def, sel)
 			case "possibleTypes":
 				result["possibleTypes"] = nil
+			case "specifiedByURL":
+				result["specifiedByURL"] = getSpecifiedByURL(def.Directives)
 			case "enumValues":
 				result["enumValues"] = e.buildEnumValues(def, sel)
 			case "inputFields":
@@ -599,6 +1427,8 @@ func (e *Executor) buildFullType(def *ast.Definition, field *SelectedField, name
 				result["interfaces"] = e.buildInterfaces(def, sel)
 			case "possibleTypes":
 				result["possibleTypes"] = e.buildPossibleTypes(def, sel)
+			case "specifiedByURL":
+				result["specifiedByURL"] = getSpecifiedByURL(def.Directives)
 			case "enumValues":
 				result["enumValues"] = e.buildEnumValues(def, sel)
 			case "inputFields":
@@ -765,23 +1595,12 @@ func (e *Executor) buildPossibleTypes(def *ast.Definition, field *SelectedField)
 	types := make([]map[string]interface{}, 0)
 	schema := e.schema.GetSchema()
 
-	if def.Kind == ast.Union {
-		for _, t := range def.Types {
-			if typeDef, ok := schema.Types[t]; ok {
-				types = append(types, e.buildTypeRef(typeDef, field))
-			}
-		}
-	} else {
-		// For interfaces, find implementing types
-		for _, typeDef := range schema.Types {
-			if typeDef.Kind == ast.Object {
-				for _, iface := range typeDef.Interfaces {
-					if iface == def.Name {
-						types = append(types, e.buildTypeRef(typeDef, field))
-						break
-					}
-				}
-			}
+	// Implementations are collected from a map; keep them in name order
+	names := e.schema.PossibleTypes(def.Name)
+	sort.Strings(names)
+	for _, name := range names {
+		if typeDef, ok := schema.Types[name]; ok {
+			types = append(types, e.buildTypeRef(typeDef, field))
 		}
 	}
 
@@ -907,6 +1726,16 @@ func getDeprecationReason(directives ast.DirectiveList) interface{} {
 	return nil
 }
 
+// getSpecifiedByURL returns the url of a scalar's @specifiedBy directive, or nil
+func getSpecifiedByURL(directives ast.DirectiveList) interface{} {
+	if d := directives.ForName("specifiedBy"); d != nil {
+		if arg := d.Arguments.ForName("url"); arg != nil && arg.Value != nil {
+			return arg.Value.Raw
+		}
+	}
+	return nil
+}
+
 // kindToIntrospection converts gqlparser's DefinitionKind to GraphQL introspection kind
 func kindToIntrospection(kind ast.DefinitionKind) string {
 	switch kind {
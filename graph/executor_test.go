@@ -0,0 +1,967 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoerceOutputScalar(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		typeName string
+		value    interface{}
+		want     interface{}
+	}{
+		{"String", ts, "2024-05-01T12:30:00Z"},
+		{"ID", ts, "2024-05-01T12:30:00Z"},
+		{"ID", 42, "42"},
+		{"Int", int64(math.MaxInt32), int64(math.MaxInt32)},
+		{"Int", 3.0, int64(3)},
+		{"Int", "7", int64(7)},
+		{"Float", 2, 2},
+	}
+	for _, tt := range tests {
+		got, err := coerceOutputScalar(tt.typeName, tt.value)
+		if err != nil {
+			t.Errorf("%s(%v): %v", tt.typeName, tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("%s(%v) = %#v, want %#v", tt.typeName, tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []interface{}{int64(math.MaxInt32) + 1, math.MinInt32 - 1, uint64(1 << 40), 1e10, 1.5} {
+		if got, err := coerceOutputScalar("Int", value); err == nil {
+			t.Errorf("Int(%v) = %v, want an error", value, got)
+		}
+	}
+}
+
+func TestExecuteTypenameOnlySelection(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { user: User } type User { id: ID }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"id": "1"}, nil
+	})
+
+	for _, query := range []string{`{ __typename }`, `{ user { __typename } }`} {
+		resp := es.Execute(context.Background(), ExecuteParams{Query: query})
+		if len(resp.Errors) > 0 {
+			t.Fatalf("%s: %v", query, resp.Errors)
+		}
+		data, _ := json.Marshal(resp.Data)
+		want := map[string]string{
+			`{ __typename }`:          `{"__typename":"Query"}`,
+			`{ user { __typename } }`: `{"user":{"__typename":"User"}}`,
+		}[query]
+		if string(data) != want {
+			t.Errorf("%s: got %s, want %s", query, data, want)
+		}
+	}
+}
+
+// execute runs query against es and returns the response as JSON
+func execute(t *testing.T, es *ExecutableSchema, query string, vars map[string]interface{}) string {
+	t.Helper()
+	resp := es.Execute(context.Background(), ExecuteParams{Query: query, Variables: vars})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestResponseKeyTransformer(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { currentUser: User } type User { fullName: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "currentUser", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"fullName": "Ann"}, nil
+	})
+	query := `{ currentUser { fullName } }`
+
+	es.Executor.SetResponseKeyTransformer(func(s string) string { return s })
+	if got, want := execute(t, es, query, nil), `{"data":{"currentUser":{"fullName":"Ann"}}}`; got != want {
+		t.Errorf("identity: got %s, want %s", got, want)
+	}
+
+	es.Executor.SetResponseKeyTransformer(toSnakeCase)
+	if got, want := execute(t, es, query, nil), `{"data":{"current_user":{"full_name":"Ann"}}}`; got != want {
+		t.Errorf("snake_case: got %s, want %s", got, want)
+	}
+
+	es.Executor.SetResponseKeyTransformer(nil)
+	if got, want := execute(t, es, query, nil), `{"data":{"currentUser":{"fullName":"Ann"}}}`; got != want {
+		t.Errorf("nil: got %s, want %s", got, want)
+	}
+}
+
+// dateMarshaler formats times as a bare date, unlike encoding/json
+type dateMarshaler struct{}
+
+func (dateMarshaler) MarshalGraphQL(v interface{}) (interface{}, error) {
+	return v.(time.Time).Format("2006-01-02"), nil
+}
+
+func (dateMarshaler) UnmarshalGraphQL(v interface{}) (interface{}, error) { return v, nil }
+
+func TestCustomScalarOutput(t *testing.T) {
+	es, err := NewExecutableSchema(`scalar Date type Query { born: Date holidays: [Date] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Schema.RegisterScalar("Date", dateMarshaler{})
+	day := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	es.RegisterResolver("Query", "born", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return day, nil
+	})
+	es.RegisterResolver("Query", "holidays", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return []time.Time{day, day.AddDate(0, 0, 1)}, nil
+	})
+
+	if got, want := execute(t, es, `{ born holidays }`, nil), `{"data":{"born":"2024-05-01","holidays":["2024-05-01","2024-05-02"]}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSkipIncludeOnInlineFragments(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { me: User } type User { id: ID name: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "me", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"id": "1", "name": "Ann"}, nil
+	})
+
+	const (
+		with    = `{"data":{"me":{"id":"1","name":"Ann"}}}`
+		without = `{"data":{"me":{"id":"1"}}}`
+	)
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{`query($d: Boolean!) { me { id ... on User @include(if: $d) { name } } }`, map[string]interface{}{"d": true}, with},
+		{`query($d: Boolean!) { me { id ... on User @include(if: $d) { name } } }`, map[string]interface{}{"d": false}, without},
+		{`query($d: Boolean!) { me { id ... @skip(if: $d) { name } } }`, map[string]interface{}{"d": true}, without},
+		{`query($d: Boolean!) { me { id ... @skip(if: $d) { name } } }`, map[string]interface{}{"d": false}, with},
+		// A sibling selection of the same field must not carry the excluded block
+		{`query($d: Boolean!) { me { id } me { ... on User @include(if: $d) { name } } }`, map[string]interface{}{"d": false}, without},
+		{`query($d: Boolean!) { me { ... @skip(if: $d) { name } } me { id } }`, map[string]interface{}{"d": true}, without},
+		// Omitted variables fall back to their declared default
+		{`query($d: Boolean = false) { me { id ... on User @include(if: $d) { name } } }`, nil, without},
+		{`query($d: Boolean = false) { me { id ... @skip(if: $d) { name } } }`, nil, with},
+	}
+	for _, tt := range tests {
+		if got := execute(t, es, tt.query, tt.vars); got != tt.want {
+			t.Errorf("%s %v:\ngot  %s\nwant %s", tt.query, tt.vars, got, tt.want)
+		}
+	}
+}
+
+func TestResolveInfoParentAndPath(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { users: [User] } type User { name: String greeting: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "users", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return []interface{}{map[string]interface{}{"name": "Ann"}, map[string]interface{}{"name": "Bo"}}, nil
+	})
+	var paths []string
+	es.RegisterResolver("User", "greeting", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		info := GetResolveInfo(ctx)
+		paths = append(paths, info.PathString())
+		parent, ok := info.ParentValue.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parent = %#v", info.ParentValue)
+		}
+		if info.RootValue != "root" {
+			return nil, fmt.Errorf("root = %#v", info.RootValue)
+		}
+		return "hi " + parent["name"].(string), nil
+	})
+
+	resp := es.Execute(context.Background(), ExecuteParams{Query: `{ users { greeting } }`, RootValue: "root"})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"data":{"users":[{"greeting":"hi Ann"},{"greeting":"hi Bo"}]}}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	sort.Strings(paths)
+	if got := strings.Join(paths, " "); got != "users.0.greeting users.1.greeting" {
+		t.Errorf("paths = %s", got)
+	}
+}
+
+func TestExecuteBatch(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { echo(s: String): String fail: String boom: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	active, peak := 0, 0
+	es.RegisterResolver("Query", "echo", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return args["s"], nil
+	})
+	es.RegisterResolver("Query", "fail", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("nope")
+	})
+	es.RegisterResolver("Query", "boom", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		panic("secret")
+	})
+	es.Executor.SetBatchConcurrency(2)
+
+	batch := []ExecuteParams{
+		{Query: `{ echo(s: "a") }`},
+		{Query: `{ fail }`},
+		{Query: `{ echo(s: "c") }`},
+		{Query: `{ boom }`},
+		{Query: `{ echo(s: "e") }`},
+		{Query: `{ echo(s: "f") }`},
+	}
+	responses := es.Executor.ExecuteBatch(context.Background(), batch)
+	want := []string{
+		`{"data":{"echo":"a"}}`,
+		`{"data":{"fail":null},"errors":[{"message":"nope","path":["fail"]}]}`,
+		`{"data":{"echo":"c"}}`,
+		`{"errors":[{"message":"internal server error","extensions":{"code":"INTERNAL_SERVER_ERROR"}}]}`,
+		`{"data":{"echo":"e"}}`,
+		`{"data":{"echo":"f"}}`,
+	}
+	if len(responses) != len(want) {
+		t.Fatalf("%d responses for %d operations", len(responses), len(want))
+	}
+	for i, resp := range responses {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want[i] {
+			t.Errorf("operation %d: got %s, want %s", i, data, want[i])
+		}
+	}
+	if peak > 2 {
+		t.Errorf("%d operations ran at once, want at most 2", peak)
+	}
+}
+
+func TestMaxFieldsAndAliases(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { me: User } type User { name: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	es.RegisterResolver("Query", "me", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		calls++
+		return map[string]interface{}{"name": "Ann"}, nil
+	})
+
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&sb, " a%d: me { name }", i)
+	}
+	sb.WriteString(" }")
+	query := sb.String()
+
+	run := func(params ExecuteParams) string {
+		params.Query = query
+		data, err := json.Marshal(es.Execute(context.Background(), params))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got := run(ExecuteParams{MaxAliases: 10}); !strings.Contains(got, "MAX_ALIASES_EXCEEDED") {
+		t.Errorf("alias limit: %s", got)
+	}
+	// 50 aliased fields, each with a nested name
+	if got := run(ExecuteParams{MaxFields: 99}); !strings.Contains(got, "MAX_FIELDS_EXCEEDED") {
+		t.Errorf("field limit: %s", got)
+	}
+	if calls != 0 {
+		t.Errorf("rejected operations ran %d resolvers", calls)
+	}
+	if got := run(ExecuteParams{MaxFields: 100, MaxAliases: 50}); strings.Contains(got, "errors") {
+		t.Errorf("operation at the limits was rejected: %s", got)
+	}
+}
+
+func TestResponseDataPresence(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { name: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "name", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("no name")
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"parse error", `{ name`, `{"errors":[`},
+		{"validation error", `{ unknown }`, `{"errors":[`},
+		{"field error", `{ name }`, `{"data":{"name":null},"errors":[`},
+	}
+	for _, tt := range tests {
+		got := execute(t, es, tt.query, nil)
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %s, want prefix %s", tt.name, got, tt.want)
+		}
+		if strings.HasPrefix(tt.want, `{"errors"`) && strings.Contains(got, `"data"`) {
+			t.Errorf("%s: request error reports data: %s", tt.name, got)
+		}
+	}
+
+	if got := execute(t, es, `{ __typename }`, nil); got != `{"data":{"__typename":"Query"}}` {
+		t.Errorf("success: %s", got)
+	}
+
+	// Data nulled out during execution is still reported
+	data, err := json.Marshal(&Response{Errors: []*Error{{Message: "x"}}, executed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != `{"data":null,"errors":[{"message":"x"}]}` {
+		t.Errorf("nulled data: %s", got)
+	}
+}
+
+func TestExecuteString(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { greet(name: String!): String fail: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "greet", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "hi " + args["name"].(string), nil
+	})
+	es.RegisterResolver("Query", "fail", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("nope")
+	})
+
+	var resp struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []struct {
+			Message string        `json:"message"`
+			Path    []interface{} `json:"path"`
+		} `json:"errors"`
+	}
+	data, err := es.Executor.ExecuteString(context.Background(), `query($n: String!) { greet(name: $n) fail }`, map[string]interface{}{"n": "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["greet"] != "hi Ann" || resp.Data["fail"] != nil {
+		t.Errorf("data = %v", resp.Data)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "nope" || len(resp.Errors[0].Path) != 1 || resp.Errors[0].Path[0] != "fail" {
+		t.Errorf("errors = %+v", resp.Errors)
+	}
+
+	// Request errors carry no data
+	data, err = es.Executor.ExecuteString(context.Background(), `{ greet }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["data"]; ok || raw["errors"] == nil {
+		t.Errorf("request error response = %s", data)
+	}
+}
+
+func TestOneOfInput(t *testing.T) {
+	es, err := NewExecutableSchema(`
+directive @oneOf on INPUT_OBJECT
+input UserBy @oneOf { id: ID email: String }
+type Query { user(by: UserBy!): String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	})
+
+	query := `query($by: UserBy!) { user(by: $by) }`
+	tests := []struct {
+		name string
+		by   map[string]interface{}
+		ok   bool
+	}{
+		{"zero fields", map[string]interface{}{}, false},
+		{"one field", map[string]interface{}{"email": "a@b.c"}, true},
+		{"two fields", map[string]interface{}{"id": "1", "email": "a@b.c"}, false},
+		{"one null field", map[string]interface{}{"id": nil}, false},
+	}
+	for _, tt := range tests {
+		got := execute(t, es, query, map[string]interface{}{"by": tt.by})
+		if ok := got == `{"data":{"user":"found"}}`; ok != tt.ok {
+			t.Errorf("%s: %s", tt.name, got)
+		}
+		if !tt.ok && !strings.Contains(got, "oneOf input UserBy") {
+			t.Errorf("%s: unclear error: %s", tt.name, got)
+		}
+	}
+}
+
+func TestCompiledOperation(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { user(id: ID!): User } type User { id: ID name: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{"1": "Ann", "2": "Bo"}
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		id := args["id"].(string)
+		return map[string]interface{}{"id": id, "name": names[id]}, nil
+	})
+
+	query := `query A($id: ID!, $full: Boolean = false) { user(id: $id) { id name @include(if: $full) } } query B { user(id: "2") { name } }`
+	op, err := es.Executor.Compile(query, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := es.Executor.Compile(query, "A"); err != nil || again != op {
+		t.Errorf("compiled operation is not cached: %p, %p, %v", op, again, err)
+	}
+
+	run := func(op *CompiledOperation, vars map[string]interface{}) string {
+		data, err := json.Marshal(op.Execute(context.Background(), vars))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got, want := run(op, map[string]interface{}{"id": "1"}), `{"data":{"user":{"id":"1"}}}`; got != want {
+		t.Errorf("first run: got %s, want %s", got, want)
+	}
+	if got, want := run(op, map[string]interface{}{"id": "2", "full": true}), `{"data":{"user":{"id":"2","name":"Bo"}}}`; got != want {
+		t.Errorf("second run: got %s, want %s", got, want)
+	}
+
+	// Operations without variables are collected once and reused
+	opB, err := es.Executor.Compile(query, "B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if got, want := run(opB, nil), `{"data":{"user":{"name":"Bo"}}}`; got != want {
+			t.Errorf("B run %d: got %s, want %s", i, got, want)
+		}
+	}
+
+	if _, err := es.Executor.Compile(query, "C"); err == nil {
+		t.Error("compiling an unknown operation succeeded")
+	}
+	if _, err := es.Executor.Compile(`{ nope }`, ""); err == nil {
+		t.Error("compiling an invalid query succeeded")
+	}
+}
+
+// cents writes its own GraphQL JSON; Cents has no registered marshaler
+type cents int64
+
+func (c cents) MarshalGQL(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `"%d.%02d"`, c/100, c%100)
+	return err
+}
+
+// moneyMarshaler converts between "12.34" strings and cents
+type moneyMarshaler struct{}
+
+func (moneyMarshaler) MarshalGraphQL(v interface{}) (interface{}, error) {
+	c := v.(int64)
+	return fmt.Sprintf("%d.%02d", c/100, c%100), nil
+}
+
+func (moneyMarshaler) UnmarshalGraphQL(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string, got %T", v)
+	}
+	var whole, frac int64
+	if _, err := fmt.Sscanf(s, "%d.%02d", &whole, &frac); err != nil {
+		return nil, err
+	}
+	return whole*100 + frac, nil
+}
+
+func TestScalarInputAndOutput(t *testing.T) {
+	es, err := NewExecutableSchema(`
+scalar Money
+scalar Cents
+input Order { total: Money }
+type Query { double(amount: Money!): Money orderTotal(order: Order!): Money fee: Cents }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Schema.RegisterScalar("Money", moneyMarshaler{})
+	es.RegisterResolver("Query", "double", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return args["amount"].(int64) * 2, nil
+	})
+	es.RegisterResolver("Query", "orderTotal", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return args["order"].(map[string]interface{})["total"].(int64), nil
+	})
+	es.RegisterResolver("Query", "fee", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return cents(5), nil
+	})
+
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{`{ double(amount: "1.25") }`, nil, `{"data":{"double":"2.50"}}`},
+		{`query($a: Money!) { double(amount: $a) }`, map[string]interface{}{"a": "0.60"}, `{"data":{"double":"1.20"}}`},
+		{`query($o: Order!) { orderTotal(order: $o) }`, map[string]interface{}{"o": map[string]interface{}{"total": "3.07"}}, `{"data":{"orderTotal":"3.07"}}`},
+		{`{ fee }`, nil, `{"data":{"fee":"0.05"}}`},
+	}
+	for _, tt := range tests {
+		if got := execute(t, es, tt.query, tt.vars); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	if got := execute(t, es, `{ double(amount: "lots") }`, nil); !strings.Contains(got, "invalid Money value") {
+		t.Errorf("bad input: %s", got)
+	}
+}
+
+func TestCompleteListValue(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { ints: [Int!] ids: [ID] names: [String] mixed: [Float] tags: [Tag] }
+type Tag { name: String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(field string, v interface{}) {
+		es.RegisterResolver("Query", field, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return v, nil
+		})
+	}
+	resolve("ints", []int32{1, 2, 3})
+	resolve("ids", []int{7, 8})
+	resolve("names", []interface{}{"a", nil, "c"})
+	resolve("mixed", []interface{}{1, 2.5})
+	resolve("tags", []map[string]interface{}{{"name": "go"}, {"name": "sql"}})
+
+	got := execute(t, es, `{ ints ids names mixed tags { name } }`, nil)
+	want := `{"data":{"ids":["7","8"],"ints":[1,2,3],"mixed":[1,2.5],"names":["a",null,"c"],"tags":[{"name":"go"},{"name":"sql"}]}}`
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func BenchmarkCompleteScalarList(b *testing.B) {
+	es, err := NewExecutableSchema(`type Query { ints: [Int!] }`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ints := make([]int32, 10000)
+	for i := range ints {
+		ints[i] = int32(i)
+	}
+	es.RegisterResolver("Query", "ints", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return ints, nil
+	})
+	b.ReportAllocs()
+	for b.Loop() {
+		if resp := es.Execute(context.Background(), ExecuteParams{Query: `{ ints }`}); len(resp.Errors) > 0 {
+			b.Fatal(resp.Errors)
+		}
+	}
+}
+
+func TestRemainingBudget(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { budget: Boolean slow: Boolean late: Boolean }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second time.Duration
+	var hasBudget bool
+	es.RegisterResolver("Query", "budget", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		rc := GetRequestContext(ctx)
+		first, hasBudget = rc.RemainingBudget()
+		time.Sleep(5 * time.Millisecond)
+		second, _ = rc.RemainingBudget()
+		return true, nil
+	})
+	es.RegisterResolver("Query", "slow", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return true, nil
+	})
+	es.RegisterResolver("Query", "late", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		t.Error("resolver started after the deadline")
+		return true, nil
+	})
+
+	execute(t, es, `{ budget }`, nil)
+	if hasBudget {
+		t.Error("an operation without a deadline reported a budget")
+	}
+
+	timeout := time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	es.Execute(ctx, ExecuteParams{Query: `{ budget }`})
+	if !hasBudget {
+		t.Fatal("the context deadline was not propagated")
+	}
+	if first > timeout || first <= 0 {
+		t.Errorf("first budget = %s, want within the %s timeout", first, timeout)
+	}
+	if second >= first {
+		t.Errorf("budget did not decrease: %s then %s", first, second)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	resp := es.Execute(ctx, ExecuteParams{Query: `{ slow late }`})
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "DEADLINE_EXCEEDED" {
+		t.Errorf("errors = %+v, want one DEADLINE_EXCEEDED", resp.Errors)
+	}
+}
+
+func TestSpecifiedByURL(t *testing.T) {
+	es, err := NewExecutableSchema(`
+scalar DateTime @specifiedBy(url: "https://scalars.graphql.org/andimarek/date-time")
+scalar Money
+type Query { now: DateTime price: Money }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://scalars.graphql.org/andimarek/date-time"
+
+	specifiedBy := func(typeName string) interface{} {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				Type map[string]interface{} `json:"__type"`
+			}
+		}
+		query := fmt.Sprintf(`{ __type(name: %q) { specifiedByURL } }`, typeName)
+		if err := json.Unmarshal([]byte(execute(t, es, query, nil)), &resp); err != nil {
+			t.Fatal(err)
+		}
+		value, ok := resp.Data.Type["specifiedByURL"]
+		if !ok {
+			t.Errorf("%s: specifiedByURL was not returned", typeName)
+		}
+		return value
+	}
+	if got := specifiedBy("DateTime"); got != url {
+		t.Errorf("DateTime specifiedByURL = %v, want %s", got, url)
+	}
+	if got := specifiedBy("Money"); got != nil {
+		t.Errorf("Money specifiedByURL = %v, want null", got)
+	}
+
+	if scalar, _ := es.Schema.GetScalar("DateTime"); scalar.SpecifiedByURL != url {
+		t.Errorf("ScalarType.SpecifiedByURL = %q", scalar.SpecifiedByURL)
+	}
+}
+
+func TestCapitalize(t *testing.T) {
+	tests := map[string]string{
+		"":       "",
+		"id":     "Id",
+		"URL":    "URL",
+		"name":   "Name",
+		"1st":    "1st",
+		"ñandú":  "Ñandú",
+		"éclair": "Éclair",
+	}
+	for in, want := range tests {
+		if got := capitalize(in); got != want {
+			t.Errorf("capitalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// resolveNames is resolved without resolvers or a registered model
+type resolveNames struct {
+	URL string
+	Id  string
+}
+
+func (r resolveNames) Greeting() string { return "hi " + r.Id }
+
+func TestDefaultResolveFieldNames(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { item: Item } type Item { URL: String id: ID greeting: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "item", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return resolveNames{URL: "https://example.com", Id: "7"}, nil
+	})
+
+	got := execute(t, es, `{ item { URL id greeting } }`, nil)
+	want := `{"data":{"item":{"URL":"https://example.com","greeting":"hi 7","id":"7"}}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestCompleteRawJSON(t *testing.T) {
+	es, err := NewExecutableSchema(`
+scalar JSON
+type Query { settings: Settings }
+type Settings { meta: JSON raw: JSON empty: JSON blob: String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The jsonb column comes back from the driver as []byte
+	db := &fakeDB{
+		columns: []string{"meta"},
+		rows:    [][]driver.Value{{[]byte(`{"big": 9007199254740993, "tags": ["a"]}`)}},
+	}
+	es.RegisterResolver("Query", "settings", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		var meta []byte
+		if err := db.open(t).QueryRowContext(ctx, "SELECT meta FROM settings").Scan(&meta); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"meta":  meta,
+			"raw":   json.RawMessage(`[1, 2.5]`),
+			"empty": []byte{},
+			"blob":  "text",
+		}, nil
+	})
+
+	got := execute(t, es, `{ settings { meta raw empty blob } }`, nil)
+	want := `{"data":{"settings":{"blob":"text","empty":null,"meta":{"big":9007199254740993,"tags":["a"]},"raw":[1,2.5]}}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	es.RegisterResolver("Query", "settings", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"meta": []byte(`{"broken"`)}, nil
+	})
+	if got := execute(t, es, `{ settings { meta } }`, nil); !strings.Contains(got, "invalid JSON") {
+		t.Errorf("invalid JSON: %s", got)
+	}
+}
+
+func TestResolveInfoDirectives(t *testing.T) {
+	es, err := NewExecutableSchema(`
+directive @lang(code: String!) on FIELD
+type Query { greeting: String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "greeting", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		info := GetResolveInfo(ctx)
+		if info.Directive("skip") != nil {
+			return nil, fmt.Errorf("@skip is not a directive of the field")
+		}
+		lang := info.Directive("lang")
+		if lang == nil {
+			return "hello", nil
+		}
+		if lang.Arguments["code"] == "fr" {
+			return "bonjour", nil
+		}
+		return fmt.Sprintf("hello (%v)", lang.Arguments["code"]), nil
+	})
+
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{`{ greeting }`, nil, `{"data":{"greeting":"hello"}}`},
+		{`{ greeting @lang(code: "fr") }`, nil, `{"data":{"greeting":"bonjour"}}`},
+		{`query($code: String!) { greeting @lang(code: $code) }`, map[string]interface{}{"code": "de"}, `{"data":{"greeting":"hello (de)"}}`},
+		{`{ greeting @lang(code: "fr") @include(if: true) }`, nil, `{"data":{"greeting":"bonjour"}}`},
+	}
+	for _, tt := range tests {
+		if got := execute(t, es, tt.query, tt.vars); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIntrospectionOrderIsStable(t *testing.T) {
+	es, err := NewExecutableSchema(`
+directive @cached(ttl: Int) on FIELD_DEFINITION
+directive @auth on FIELD_DEFINITION
+interface Node { id: ID! }
+type Zebra implements Node { id: ID! }
+type Apple implements Node { id: ID! }
+type Mango implements Node { id: ID! }
+enum Color { RED GREEN BLUE }
+type Query { node: Node color: Color }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const query = `{ __schema {
+		types { name }
+		directives { name }
+	}
+	__type(name: "Node") { possibleTypes { name } } }`
+
+	first := execute(t, es, query, nil)
+	for i := 0; i < 20; i++ {
+		if got := execute(t, es, query, nil); got != first {
+			t.Fatalf("run %d differs:\n%s\n%s", i, first, got)
+		}
+	}
+
+	var resp struct {
+		Data struct {
+			Schema struct {
+				Types      []struct{ Name string }
+				Directives []struct{ Name string }
+			} `json:"__schema"`
+			Type struct {
+				PossibleTypes []struct{ Name string }
+			} `json:"__type"`
+		}
+	}
+	if err := json.Unmarshal([]byte(first), &resp); err != nil {
+		t.Fatal(err)
+	}
+	names := func(list []struct{ Name string }) []string {
+		var s []string
+		for _, item := range list {
+			s = append(s, item.Name)
+		}
+		return s
+	}
+	for what, list := range map[string][]string{
+		"types":      names(resp.Data.Schema.Types),
+		"directives": names(resp.Data.Schema.Directives),
+	} {
+		if len(list) == 0 || !sort.StringsAreSorted(list) {
+			t.Errorf("%s are not in name order: %v", what, list)
+		}
+	}
+	if got := strings.Join(names(resp.Data.Type.PossibleTypes), ","); got != "Apple,Mango,Zebra" {
+		t.Errorf("possibleTypes = %s", got)
+	}
+
+	var enum struct {
+		Data struct {
+			Type struct {
+				EnumValues []struct{ Name string }
+			} `json:"__type"`
+		}
+	}
+	if err := json.Unmarshal([]byte(execute(t, es, `{ __type(name: "Color") { enumValues { name } } }`, nil)), &enum); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(names(enum.Data.Type.EnumValues), ","); got != "RED,GREEN,BLUE" {
+		t.Errorf("enum values = %s, want schema order", got)
+	}
+}
+
+func TestBuiltinScalarOutput(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { user: User }
+type User { id: ID! friendIds: [ID] score: Float active: Boolean age: Int }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	age := interface{}(int64(30))
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		// As scanned from SQL: integer ids, an integer score and a 0/1 flag
+		return map[string]interface{}{
+			"id":        int64(42),
+			"friendIds": []int{1, 2},
+			"score":     int64(2),
+			"active":    int64(1),
+			"age":       age,
+		}, nil
+	})
+
+	const query = `{ user { id friendIds score active age } }`
+	want := `{"data":{"user":{"active":true,"age":30,"friendIds":["1","2"],"id":"42","score":2}}}`
+	if got := execute(t, es, query, nil); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	age = 30.5
+	if got := execute(t, es, query, nil); !strings.Contains(got, `"age":null`) || !strings.Contains(got, "Int cannot represent non-integer value: 30.5") {
+		t.Errorf("fractional Int: %s", got)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { items: [Item] } type Item { name: String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "items", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return make([]map[string]interface{}, 50), nil
+	})
+	es.RegisterResolver("Item", "name", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("name unavailable")
+	})
+	run := func(params ExecuteParams) *Response {
+		params.Query = `{ items { name } }`
+		return es.Execute(context.Background(), params)
+	}
+
+	if resp := run(ExecuteParams{}); len(resp.Errors) != 50 {
+		t.Errorf("without a limit: %d errors, want 50", len(resp.Errors))
+	}
+
+	es.Executor.SetMaxErrors(5)
+	resp := run(ExecuteParams{})
+	if len(resp.Errors) != 6 {
+		t.Fatalf("%d errors, want 5 and a marker", len(resp.Errors))
+	}
+	for _, e := range resp.Errors[:5] {
+		if e.Message != "name unavailable" {
+			t.Errorf("kept error = %q", e.Message)
+		}
+	}
+	if last := resp.Errors[5]; last.Message != "too many errors" || last.Extensions["code"] != "TOO_MANY_ERRORS" {
+		t.Errorf("marker = %+v", last)
+	}
+	if items, _ := resp.Data.(map[string]interface{})["items"].([]interface{}); len(items) != 50 {
+		t.Errorf("data has %d items, want all 50 despite dropped errors", len(items))
+	}
+
+	// The per-operation limit takes precedence
+	if resp := run(ExecuteParams{MaxErrors: 2}); len(resp.Errors) != 3 {
+		t.Errorf("MaxErrors 2: %d errors, want 3", len(resp.Errors))
+	}
+}
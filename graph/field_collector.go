@@ -9,6 +9,10 @@ type FieldCollector struct {
 	schema    *Schema
 	fragments map[string]*ast.FragmentDefinition
 	variables map[string]interface{}
+
+	// Running totals across all collected selection sets
+	fieldCount int
+	aliasCount int
 }
 
 // NewFieldCollector creates a new field collector
@@ -20,6 +24,17 @@ func NewFieldCollector(schema *Schema, fragments map[string]*ast.FragmentDefinit
 	}
 }
 
+// FieldCount returns the number of field selections collected so far,
+// counting every occurrence (including fragment and alias repeats)
+func (fc *FieldCollector) FieldCount() int {
+	return fc.fieldCount
+}
+
+// AliasCount returns the number of aliased field selections collected so far
+func (fc *FieldCollector) AliasCount() int {
+	return fc.aliasCount
+}
+
 // CollectFields collects fields from a selection set, handling fragments and spreads
 func (fc *FieldCollector) CollectFields(selectionSet ast.SelectionSet, parentType string) *SelectionSet {
 	if selectionSet == nil {
@@ -30,20 +45,36 @@ func (fc *FieldCollector) CollectFields(selectionSet ast.SelectionSet, parentTyp
 		Fields: make([]*SelectedField, 0),
 	}
 
-	// Track field names to merge duplicate selections
+	// Track field names to merge duplicate selections. Sub-selections of a
+	// response key are merged first and collected once, so only fields that
+	// survive @skip/@include end up in the nested selection set.
 	fieldMap := make(map[string]*SelectedField)
+	subSelections := make(map[string]ast.SelectionSet)
 
-	fc.collectFieldsImpl(selectionSet, parentType, fieldMap, result)
+	fc.collectFieldsImpl(selectionSet, parentType, "", fieldMap, subSelections, result)
 
-	// Convert map to slice maintaining order
-	for _, field := range fieldMap {
-		result.Fields = append(result.Fields, field)
+	for _, field := range result.Fields {
+		if sub, ok := subSelections[field.GetName()]; ok {
+			nestedType := fc.schema.FieldBaseTypeName(parentType, field.Name)
+			if nestedType == "" && len(field.TypeConditions) > 0 {
+				// Field only exists on the concrete type of the fragment
+				nestedType = fc.schema.FieldBaseTypeName(field.TypeConditions[0], field.Name)
+			}
+			field.Selections = fc.CollectFields(sub, nestedType)
+		}
 	}
 
 	return result
 }
 
-func (fc *FieldCollector) collectFieldsImpl(selectionSet ast.SelectionSet, parentType string, fieldMap map[string]*SelectedField, result *SelectionSet) {
+func (fc *FieldCollector) collectFieldsImpl(
+	selectionSet ast.SelectionSet,
+	parentType string,
+	typeCondition string,
+	fieldMap map[string]*SelectedField,
+	subSelections map[string]ast.SelectionSet,
+	result *SelectionSet,
+) {
 	for _, selection := range selectionSet {
 		switch sel := selection.(type) {
 		case *ast.Field:
@@ -57,36 +88,42 @@ func (fc *FieldCollector) collectFieldsImpl(selectionSet ast.SelectionSet, paren
 				continue
 			}
 
+			fc.fieldCount++
+			if sel.Alias != "" && sel.Alias != sel.Name {
+				fc.aliasCount++
+			}
+
 			responseKey := sel.Alias
 			if responseKey == "" {
 				responseKey = sel.Name
 			}
 
-			// Check if field already exists (for merging)
-			if existing, ok := fieldMap[responseKey]; ok {
-				// Merge selections
-				if sel.SelectionSet != nil {
-					if existing.Selections == nil {
-						existing.Selections = &SelectionSet{Fields: make([]*SelectedField, 0)}
-					}
-					nestedFields := fc.CollectFields(sel.SelectionSet, fc.getFieldTypeName(parentType, sel.Name))
-					existing.Selections.Fields = append(existing.Selections.Fields, nestedFields.Fields...)
-				}
-			} else {
+			// Fields sharing a response key are merged in selection order
+			if existing, ok := fieldMap[responseKey]; !ok {
 				field := &SelectedField{
 					Name:       sel.Name,
 					Alias:      sel.Alias,
 					Arguments:  fc.collectArguments(sel.Arguments),
 					Directives: fc.collectDirectives(sel.Directives),
 				}
-
-				// Recursively collect nested fields
-				if sel.SelectionSet != nil {
-					nestedType := fc.getFieldTypeName(parentType, sel.Name)
-					field.Selections = fc.CollectFields(sel.SelectionSet, nestedType)
+				if typeCondition != "" {
+					field.TypeConditions = []string{typeCondition}
 				}
-
 				fieldMap[responseKey] = field
+				result.Fields = append(result.Fields, field)
+			} else if len(existing.TypeConditions) > 0 {
+				if typeCondition == "" {
+					existing.TypeConditions = nil
+				} else if !existing.AppliesTo(typeCondition) {
+					existing.TypeConditions = append(existing.TypeConditions, typeCondition)
+				}
+			}
+
+			// Copy before appending; the parsed document is cached and shared
+			if sel.SelectionSet != nil {
+				merged := make(ast.SelectionSet, 0, len(subSelections[responseKey])+len(sel.SelectionSet))
+				merged = append(merged, subSelections[responseKey]...)
+				subSelections[responseKey] = append(merged, sel.SelectionSet...)
 			}
 
 		case *ast.FragmentSpread:
@@ -100,11 +137,12 @@ func (fc *FieldCollector) collectFieldsImpl(selectionSet ast.SelectionSet, paren
 			}
 
 			// Check if fragment applies to this type
-			if !fc.typeApplies(fragment.TypeCondition, parentType) {
+			condition, ok := fc.fragmentCondition(fragment.TypeCondition, parentType, typeCondition)
+			if !ok {
 				continue
 			}
 
-			fc.collectFieldsImpl(fragment.SelectionSet, parentType, fieldMap, result)
+			fc.collectFieldsImpl(fragment.SelectionSet, parentType, condition, fieldMap, subSelections, result)
 
 		case *ast.InlineFragment:
 			if !fc.shouldInclude(sel.Directives) {
@@ -112,11 +150,15 @@ func (fc *FieldCollector) collectFieldsImpl(selectionSet ast.SelectionSet, paren
 			}
 
 			// Check type condition if present
-			if sel.TypeCondition != "" && !fc.typeApplies(sel.TypeCondition, parentType) {
-				continue
+			condition := typeCondition
+			if sel.TypeCondition != "" {
+				var ok bool
+				if condition, ok = fc.fragmentCondition(sel.TypeCondition, parentType, typeCondition); !ok {
+					continue
+				}
 			}
 
-			fc.collectFieldsImpl(sel.SelectionSet, parentType, fieldMap, result)
+			fc.collectFieldsImpl(sel.SelectionSet, parentType, condition, fieldMap, subSelections, result)
 		}
 	}
 }
@@ -185,8 +227,12 @@ func (fc *FieldCollector) evaluateValue(value *ast.Value) interface{} {
 
 	switch value.Kind {
 	case ast.Variable:
-		if fc.variables != nil {
-			return fc.variables[value.Raw]
+		if v, ok := fc.variables[value.Raw]; ok {
+			return v
+		}
+		// Fall back to the default declared in the operation
+		if def := value.VariableDefinition; def != nil && def.DefaultValue != nil {
+			return fc.evaluateValue(def.DefaultValue)
 		}
 		return nil
 
@@ -285,32 +331,17 @@ func parseValue(s string, dst interface{}) (interface{}, error) {
 	return s, nil
 }
 
-// getFieldTypeName gets the return type name for a field
-func (fc *FieldCollector) getFieldTypeName(parentType, fieldName string) string {
-	objType, ok := fc.schema.GetType(parentType)
-	if !ok {
-		return ""
-	}
-
-	field, ok := objType.Fields[fieldName]
-	if !ok {
-		return ""
-	}
-
-	return fc.unwrapTypeName(field.Type)
-}
-
-// unwrapTypeName unwraps a TypeRef to get the underlying type name
-func (fc *FieldCollector) unwrapTypeName(t *TypeRef) string {
-	if t == nil {
-		return ""
+// fragmentCondition decides whether a fragment is collected for parentType and
+// returns the type condition its fields carry. Fragments on a concrete type of
+// an abstract parent are kept and filtered by runtime type during execution.
+func (fc *FieldCollector) fragmentCondition(fragmentType, parentType, current string) (string, bool) {
+	if fc.typeApplies(fragmentType, parentType) {
+		return current, true
 	}
-
-	if t.IsList {
-		return fc.unwrapTypeName(t.ListElem)
+	if fc.schema.IsAbstractType(parentType) && fc.schema.isPossibleType(parentType, fragmentType) {
+		return fragmentType, true
 	}
-
-	return t.Name
+	return "", false
 }
 
 // typeApplies checks if a fragment type condition applies to a runtime type
@@ -359,7 +390,7 @@ func (fc *FieldCollector) FlattenFields(selections *SelectionSet, parentType str
 
 		// Recursively flatten nested selections
 		if field.HasSelection() {
-			nestedType := fc.getFieldTypeName(parentType, field.Name)
+			nestedType := fc.schema.FieldBaseTypeName(parentType, field.Name)
 			nested := fc.FlattenFields(field.Selections, nestedType, path)
 			result = append(result, nested...)
 		}
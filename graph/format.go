@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SQLFormat selects how generated SQL is laid out
+type SQLFormat int
+
+const (
+	// SQLFormatRaw leaves generated queries as they were built
+	SQLFormatRaw SQLFormat = iota
+	// SQLFormatPretty starts each clause on its own line and indents subqueries
+	SQLFormatPretty
+	// SQLFormatCompact collapses the query onto a single line
+	SQLFormatCompact
+)
+
+// SetSQLFormat sets the layout of generated queries (default: SQLFormatRaw)
+func (c *SQLConverter) SetSQLFormat(format SQLFormat) {
+	c.format = format
+}
+
+// Format lays out a query using the converter's SQL format
+func (c *SQLConverter) Format(query string) string {
+	return FormatSQL(query, c.format)
+}
+
+// FormatSQL lays out a query. Compact mode collapses whitespace runs outside
+// quoted strings and identifiers to a single space and drops the space just
+// inside parentheses, so equivalent queries compare equal. Pretty mode starts
+// from the compact form, so both modes agree on everything but layout. Both
+// drop -- line comments, which would otherwise swallow the rest of the query.
+func FormatSQL(query string, format SQLFormat) string {
+	switch format {
+	case SQLFormatPretty:
+		return prettySQL(compactSQL(query))
+	case SQLFormatCompact:
+		return compactSQL(query)
+	}
+	return query
+}
+
+// compactSQL collapses a query onto a single line, dropping line comments
+func compactSQL(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	var quote rune
+	pendingSpace := false
+	var last rune
+
+	for i := 0; i < len(query); i++ {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		i += size - 1
+
+		if quote != 0 {
+			sb.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+			last = r
+			continue
+		}
+
+		if unicode.IsSpace(r) {
+			pendingSpace = true
+			continue
+		}
+
+		// A line comment ends at the newline, which compacts to a space
+		if r == '-' && strings.HasPrefix(query[i:], "--") {
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end - 1
+			pendingSpace = true
+			continue
+		}
+
+		if pendingSpace && last != 0 && last != '(' && r != ')' {
+			sb.WriteByte(' ')
+		}
+		pendingSpace = false
+
+		if r == '\'' || r == '"' {
+			quote = r
+		}
+		sb.WriteRune(r)
+		last = r
+	}
+
+	return sb.String()
+}
+
+// clauseKeywords start a new line in pretty mode, longest first so that
+// e.g. LEFT JOIN is not split before JOIN
+var clauseKeywords = []string{
+	"LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "FULL JOIN", "CROSS JOIN", "JOIN",
+	"DO UPDATE SET", "DO NOTHING", "ON CONFLICT",
+	"INSERT INTO", "DELETE FROM", "UPDATE", "SET", "VALUES", "RETURNING",
+	"SELECT", "FROM", "WHERE", "GROUP BY", "HAVING", "ORDER BY", "LIMIT", "OFFSET",
+	"UNION ALL", "UNION", "WITH",
+}
+
+// statementKeywords open a subquery when they follow a parenthesis
+var statementKeywords = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "WITH"}
+
+// sqlIndent is one level of subquery indentation
+const sqlIndent = "    "
+
+// prettySQL lays out a compact query with one clause per line, indenting
+// parenthesized statements (subqueries, lateral joins and CTEs) one level.
+// Keywords inside other parentheses, such as function arguments, stay inline.
+func prettySQL(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query) + len(query)/4)
+
+	var parens []bool // Per open parenthesis: whether it holds a statement
+	depth := 0
+	space := false // A space is due before the next token on the line
+
+	newline := func() {
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+			sb.WriteString(strings.Repeat(sqlIndent, depth))
+		}
+		space = false
+	}
+	write := func(token string) {
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteString(token)
+	}
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+
+		switch ch {
+		case '\'', '"':
+			end := strings.IndexByte(query[i+1:], ch)
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			write(query[i : i+end+2])
+			i += end + 1
+			continue
+
+		case ' ':
+			space = true
+			continue
+
+		case '(':
+			write("(")
+			statement := matchKeyword(query, i+1, statementKeywords) != ""
+			parens = append(parens, statement)
+			if statement {
+				depth++
+				newline()
+			}
+			continue
+
+		case ')':
+			if n := len(parens); n > 0 {
+				if parens[n-1] {
+					depth--
+					newline()
+				}
+				parens = parens[:n-1]
+			}
+			write(")")
+			continue
+		}
+
+		inline := len(parens) > 0 && !parens[len(parens)-1]
+		if !inline && (i == 0 || !isIdentByte(query[i-1])) {
+			if kw := matchKeyword(query, i, clauseKeywords); kw != "" {
+				if !strings.HasSuffix(sb.String(), "\n"+strings.Repeat(sqlIndent, depth)) {
+					newline()
+				}
+				write(kw)
+				i += len(kw) - 1
+				continue
+			}
+		}
+
+		write(query[i : i+1])
+	}
+
+	return sb.String()
+}
+
+// matchKeyword returns the keyword of keywords that starts query at i as a
+// whole word, or ""
+func matchKeyword(query string, i int, keywords []string) string {
+	for _, kw := range keywords {
+		end := i + len(kw)
+		if strings.HasPrefix(query[i:], kw) && (end == len(query) || !isIdentByte(query[end])) {
+			return kw
+		}
+	}
+	return ""
+}
+
+// isIdentByte reports whether ch can be part of an unquoted identifier
+func isIdentByte(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '$' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
@@ -0,0 +1,98 @@
+package graph
+
+import "testing"
+
+func TestFormatSQL(t *testing.T) {
+	query := `SELECT u."id", COUNT(*) FILTER (WHERE p."draft") AS "drafts"
+FROM   "users" u
+LEFT JOIN LATERAL (SELECT draft FROM "posts" WHERE "author_id" = u."id" LIMIT $1) p ON true
+WHERE u."name" = 'a  FROM  b' AND EXISTS (SELECT 1 FROM "bans" b WHERE b."user_id" = u."id")
+GROUP BY u."id"
+ORDER BY u."id" DESC`
+
+	compact := `SELECT u."id", COUNT(*) FILTER (WHERE p."draft") AS "drafts" FROM "users" u ` +
+		`LEFT JOIN LATERAL (SELECT draft FROM "posts" WHERE "author_id" = u."id" LIMIT $1) p ON true ` +
+		`WHERE u."name" = 'a  FROM  b' AND EXISTS (SELECT 1 FROM "bans" b WHERE b."user_id" = u."id") ` +
+		`GROUP BY u."id" ORDER BY u."id" DESC`
+	if got := FormatSQL(query, SQLFormatCompact); got != compact {
+		t.Errorf("compact:\ngot  %s\nwant %s", got, compact)
+	}
+
+	pretty := `SELECT u."id", COUNT(*) FILTER (WHERE p."draft") AS "drafts"
+FROM "users" u
+LEFT JOIN LATERAL (
+    SELECT draft
+    FROM "posts"
+    WHERE "author_id" = u."id"
+    LIMIT $1
+) p ON true
+WHERE u."name" = 'a  FROM  b' AND EXISTS (
+    SELECT 1
+    FROM "bans" b
+    WHERE b."user_id" = u."id"
+)
+GROUP BY u."id"
+ORDER BY u."id" DESC`
+	if got := FormatSQL(query, SQLFormatPretty); got != pretty {
+		t.Errorf("pretty:\ngot\n%s\nwant\n%s", got, pretty)
+	}
+
+	// Both layouts of a query agree once compacted
+	if got := FormatSQL(pretty, SQLFormatCompact); got != compact {
+		t.Errorf("compacted pretty output:\ngot  %s\nwant %s", got, compact)
+	}
+	if got := FormatSQL(compact, SQLFormatPretty); got != pretty {
+		t.Errorf("pretty from compact:\ngot\n%s\nwant\n%s", got, pretty)
+	}
+}
+
+func TestFormatSQLRawAndComments(t *testing.T) {
+	query := "SELECT *\nFROM \"posts\" p JOIN \"users\" u ON u.\"id\" = p.\"author_id\" -- custom join\nWHERE u.\"name\" = '--x'"
+
+	// The zero value leaves the query alone
+	var format SQLFormat
+	if got := FormatSQL(query, format); got != query {
+		t.Errorf("raw:\ngot  %s\nwant %s", got, query)
+	}
+
+	want := `SELECT * FROM "posts" p JOIN "users" u ON u."id" = p."author_id" WHERE u."name" = '--x'`
+	if got := FormatSQL(query, SQLFormatCompact); got != want {
+		t.Errorf("compact:\ngot  %s\nwant %s", got, want)
+	}
+	if got := FormatSQL("SELECT 1 -- trailing", SQLFormatCompact); got != "SELECT 1" {
+		t.Errorf("trailing comment: got %q", got)
+	}
+}
+
+func TestCombineMutationsUsesConverterFormat(t *testing.T) {
+	mutations := []*SQLMutationResult{
+		{Query: "DELETE FROM \"posts\"\nWHERE \"id\" = $1", Params: []interface{}{1}},
+		{Query: "UPDATE \"users\"\nSET \"name\" = $1\nRETURNING \"id\"", Params: []interface{}{"Ann"}, Returning: true},
+	}
+
+	c := newTestConverter(t, testSchema)
+	result, err := c.CombineMutations(mutations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `WITH mutation_1 AS (DELETE FROM "posts" WHERE "id" = $1) UPDATE "users" SET "name" = $2 RETURNING "id"`
+	if result.Query != want {
+		t.Errorf("compact:\ngot  %s\nwant %s", result.Query, want)
+	}
+
+	c.SetSQLFormat(SQLFormatPretty)
+	result, err = c.CombineMutations(mutations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `WITH mutation_1 AS (
+    DELETE FROM "posts"
+    WHERE "id" = $1
+)
+UPDATE "users"
+SET "name" = $2
+RETURNING "id"`
+	if result.Query != want {
+		t.Errorf("pretty:\ngot\n%s\nwant\n%s", result.Query, want)
+	}
+}
@@ -0,0 +1,123 @@
+// Package graphtest provides a client for exercising an executable schema in
+// tests without going through HTTP.
+package graphtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eddieafk/goinmonster/graph"
+)
+
+// Client runs operations directly against an executable schema
+type Client struct {
+	es   *graph.ExecutableSchema
+	opts []Option
+}
+
+// Request is the operation assembled from a query and its options
+type Request struct {
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+	Context       context.Context
+}
+
+// Option configures a request
+type Option func(*Request)
+
+// NewClient creates a client; opts apply to every request it sends
+func NewClient(es *graph.ExecutableSchema, opts ...Option) *Client {
+	return &Client{
+		es:   es,
+		opts: opts,
+	}
+}
+
+// WithVar sets a variable for the operation
+func WithVar(name string, value interface{}) Option {
+	return func(r *Request) {
+		r.Variables[name] = value
+	}
+}
+
+// WithOperationName selects the operation to run in a multi-operation document
+func WithOperationName(name string) Option {
+	return func(r *Request) {
+		r.OperationName = name
+	}
+}
+
+// WithContext sets the context the operation runs with
+func WithContext(ctx context.Context) Option {
+	return func(r *Request) {
+		r.Context = ctx
+	}
+}
+
+// Errors is returned when the response contains GraphQL errors
+type Errors []*graph.Error
+
+// Error joins the error messages with their paths
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		if len(err.Path) > 0 {
+			msgs = append(msgs, fmt.Sprintf("%v: %s", err.Path, err.Message))
+		} else {
+			msgs = append(msgs, err.Message)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// RawPost executes the query and returns the raw response
+func (c *Client) RawPost(query string, opts ...Option) *graph.Response {
+	req := &Request{
+		Query:     query,
+		Variables: make(map[string]interface{}),
+		Context:   context.Background(),
+	}
+	for _, opt := range c.opts {
+		opt(req)
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	return c.es.Execute(req.Context, graph.ExecuteParams{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+	})
+}
+
+// Post executes the query and decodes data into response. Partial data is
+// still decoded when the response has errors, which are returned as Errors.
+func (c *Client) Post(query string, response interface{}, opts ...Option) error {
+	resp := c.RawPost(query, opts...)
+
+	if resp.Data != nil && response != nil {
+		data, err := json.Marshal(resp.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode data: %w", err)
+		}
+		if err := json.Unmarshal(data, response); err != nil {
+			return fmt.Errorf("failed to decode data: %w", err)
+		}
+	}
+
+	if len(resp.Errors) > 0 {
+		return Errors(resp.Errors)
+	}
+	return nil
+}
+
+// MustPost is like Post but panics on any error
+func (c *Client) MustPost(query string, response interface{}, opts ...Option) {
+	if err := c.Post(query, response, opts...); err != nil {
+		panic(err)
+	}
+}
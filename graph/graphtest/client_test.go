@@ -0,0 +1,94 @@
+package graphtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/eddieafk/goinmonster/graph"
+)
+
+type ctxKey struct{}
+
+func newSchema(t *testing.T) *graph.ExecutableSchema {
+	t.Helper()
+	es, err := graph.NewExecutableSchema(`
+type Query { user(id: ID!): User viewer: String }
+type User { id: ID! name: String friends: [User] }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"id": args["id"], "name": "Ann", "friends": nil}, nil
+	})
+	es.RegisterResolver("Query", "viewer", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		v, _ := ctx.Value(ctxKey{}).(string)
+		return v, nil
+	})
+	es.RegisterResolver("User", "friends", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("friends unavailable")
+	})
+	return es
+}
+
+func TestClientMustPost(t *testing.T) {
+	c := NewClient(newSchema(t))
+
+	var resp struct {
+		User struct {
+			ID   string
+			Name string
+		}
+	}
+	c.MustPost(`query($id: ID!) { user(id: $id) { id name } }`, &resp, WithVar("id", "7"))
+	if resp.User.ID != "7" || resp.User.Name != "Ann" {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustPost did not panic on an invalid query")
+		}
+	}()
+	c.MustPost(`{ nope }`, &resp)
+}
+
+func TestClientPostReturnsErrorsWithPartialData(t *testing.T) {
+	c := NewClient(newSchema(t))
+
+	var resp struct {
+		User struct {
+			Name    string
+			Friends []struct{ Name string }
+		}
+	}
+	err := c.Post(`{ user(id: 1) { name friends { name } } }`, &resp)
+	var gqlErrs Errors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 1 {
+		t.Fatalf("err = %v", err)
+	}
+	if got, want := err.Error(), "[user friends]: friends unavailable"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if resp.User.Name != "Ann" {
+		t.Errorf("partial data was not decoded: %+v", resp)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "ann")
+	c := NewClient(newSchema(t), WithContext(ctx))
+
+	var resp struct{ Viewer string }
+	c.MustPost(`query A { user(id: 1) { id } } query B { viewer }`, &resp, WithOperationName("B"))
+	if resp.Viewer != "ann" {
+		t.Errorf("viewer = %q; client options or operation name were not applied", resp.Viewer)
+	}
+
+	raw := c.RawPost(`{ viewer }`)
+	if len(raw.Errors) != 0 || raw.Data == nil {
+		t.Errorf("RawPost = %+v", raw)
+	}
+}
@@ -6,8 +6,14 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/eddieafk/goinmonster/sql/ast"
+	"github.com/eddieafk/goinmonster/sql/stringifiers/dialects"
 )
 
+// pg formats the operators of the conditions the builders emit
+var pg = dialects.PostgreSQL{}
+
 // PostgreSQLMarshaler handles PostgreSQL-specific type conversions
 type PostgreSQLMarshaler struct {
 	// Placeholder counter for parameterized queries
@@ -285,11 +291,57 @@ func NewWhereClauseBuilder(marshaler *PostgreSQLMarshaler) *WhereClauseBuilder {
 
 // AddCondition adds a condition to the WHERE clause
 func (b *WhereClauseBuilder) AddCondition(column, op string, value interface{}) error {
+	return b.AddCastCondition(column, op, value, "")
+}
+
+// whereOperators are the operators AddCastCondition accepts
+var whereOperators = map[string]bool{
+	"eq": true, "=": true, "neq": true, "!=": true, "<>": true,
+	"gt": true, ">": true, "gte": true, ">=": true, "lt": true, "<": true, "lte": true, "<=": true,
+	"like": true, "ilike": true, "ieq": true, "in": true, "nin": true, "not_in": true, "is_null": true,
+	"contains": true, "contained_by": true,
+	"array_contains": true, "array_contained_by": true, "array_overlap": true, "array_length": true,
+}
+
+// AddCastCondition adds a condition whose parameter is cast to castType
+// (e.g., $1::uuid); list operators cast to the array type. An empty
+// castType adds a plain condition. Unknown operators are an error.
+func (b *WhereClauseBuilder) AddCastCondition(column, op string, value interface{}, castType string) error {
+	if !whereOperators[op] {
+		return fmt.Errorf("unsupported operator %q", op)
+	}
+
+	// is_null takes no parameter; marshaling its flag would leave a gap in
+	// the placeholder sequence
+	if op == "is_null" {
+		if val, ok := value.(bool); ok && val {
+			b.clauses = append(b.clauses, column+" IS NULL")
+		} else {
+			b.clauses = append(b.clauses, column+" IS NOT NULL")
+		}
+		return nil
+	}
+
 	placeholder, err := b.marshaler.MarshalValue(value)
 	if err != nil {
 		return err
 	}
 
+	// The length is an integer whatever the element type
+	if op == "array_length" {
+		b.clauses = append(b.clauses, "array_length("+column+", 1) = "+placeholder)
+		return nil
+	}
+
+	if castType != "" {
+		switch op {
+		case "in", "nin", "not_in", "array_contains", "array_contained_by", "array_overlap":
+			placeholder += "::" + castType + "[]"
+		default:
+			placeholder += "::" + castType
+		}
+	}
+
 	var condition string
 	switch op {
 	case "eq", "=":
@@ -308,30 +360,36 @@ func (b *WhereClauseBuilder) AddCondition(column, op string, value interface{})
 		condition = column + " LIKE " + placeholder
 	case "ilike":
 		condition = column + " ILIKE " + placeholder
+	case "ieq":
+		// Case-insensitive equality; matches a functional index on LOWER(column)
+		condition = "LOWER(" + column + ") = LOWER(" + placeholder + ")"
 	case "in":
 		condition = column + " = ANY(" + placeholder + ")"
 	case "nin", "not_in":
 		condition = column + " <> ALL(" + placeholder + ")"
-	case "is_null":
-		if val, ok := value.(bool); ok && val {
-			condition = column + " IS NULL"
-		} else {
-			condition = column + " IS NOT NULL"
-		}
 	case "contains":
 		condition = column + " @> " + placeholder
 	case "contained_by":
 		condition = column + " <@ " + placeholder
+	case "array_contains":
+		condition = column + " " + pg.FormatBinaryOp(ast.OpArrayContains) + " " + placeholder
+	case "array_contained_by":
+		condition = column + " " + pg.FormatBinaryOp(ast.OpArrayContainedBy) + " " + placeholder
+	case "array_overlap":
+		condition = column + " " + pg.FormatBinaryOp(ast.OpArrayOverlap) + " " + placeholder
 	default:
-		condition = column + " " + op + " " + placeholder
+		return fmt.Errorf("unsupported operator %q", op)
 	}
 
 	b.clauses = append(b.clauses, condition)
 	return nil
 }
 
-// AddRaw adds a raw condition
+// AddRaw adds a raw condition; empty conditions (e.g., an empty OR group) are ignored
 func (b *WhereClauseBuilder) AddRaw(condition string) {
+	if condition == "" {
+		return
+	}
 	b.clauses = append(b.clauses, condition)
 }
 
@@ -372,6 +430,60 @@ func (b *OrderByBuilder) Add(column string, direction string) {
 	b.columns = append(b.columns, column+" "+dir)
 }
 
+// AddExpression adds a SQL expression (e.g., LOWER(a."name")) to ORDER BY.
+// The expression is inserted as-is, so it must come from configuration, never
+// from client input; it is only checked for statement terminators, comments
+// and unbalanced parentheses or quotes.
+func (b *OrderByBuilder) AddExpression(expr, direction string) error {
+	if err := ValidateSortExpression(expr); err != nil {
+		return err
+	}
+	b.Add(expr, direction)
+	return nil
+}
+
+// ValidateSortExpression rejects sort expressions that could end the ORDER BY
+// clause: semicolons, comments and unbalanced parentheses or quotes
+func ValidateSortExpression(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("sort expression is empty")
+	}
+	if strings.Contains(expr, "--") || strings.Contains(expr, "/*") {
+		return fmt.Errorf("sort expression %q must not contain comments", expr)
+	}
+
+	depth := 0
+	var quote rune
+	for _, r := range expr {
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"':
+			quote = r
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("sort expression %q has unbalanced parentheses", expr)
+			}
+		case ';':
+			return fmt.Errorf("sort expression %q must not contain ';'", expr)
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("sort expression %q has an unterminated quote", expr)
+	}
+	if depth != 0 {
+		return fmt.Errorf("sort expression %q has unbalanced parentheses", expr)
+	}
+	return nil
+}
+
 // AddWithNulls adds a column with NULLS handling
 func (b *OrderByBuilder) AddWithNulls(column, direction string, nullsFirst bool) {
 	dir := "ASC"
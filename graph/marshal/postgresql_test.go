@@ -0,0 +1,73 @@
+package marshal
+
+import "testing"
+
+func TestAddCastConditionArrayOperators(t *testing.T) {
+	tests := []struct {
+		op   string
+		want string
+	}{
+		{"in", `t."id" = ANY($1::uuid[])`},
+		{"nin", `t."id" <> ALL($1::uuid[])`},
+		{"array_contains", `t."id" @> $1::uuid[]`},
+		{"array_contained_by", `t."id" <@ $1::uuid[]`},
+		{"array_overlap", `t."id" && $1::uuid[]`},
+		{"eq", `t."id" = $1::uuid`},
+	}
+	for _, tt := range tests {
+		b := NewWhereClauseBuilder(NewPostgreSQLMarshaler())
+		if err := b.AddCastCondition(`t."id"`, tt.op, []interface{}{"a"}, "uuid"); err != nil {
+			t.Fatalf("%s: %v", tt.op, err)
+		}
+		if got := b.Build(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestAddCastConditionRejectsUnknownOperators(t *testing.T) {
+	m := NewPostgreSQLMarshaler()
+	b := NewWhereClauseBuilder(m)
+	for _, op := range []string{"IS NOT NULL OR 1=1 OR name =", ") OR (1=1", "between"} {
+		if err := b.AddCastCondition(`u."name"`, op, "x", ""); err == nil {
+			t.Errorf("operator %q was accepted", op)
+		}
+	}
+	if got := b.Build(); got != "" {
+		t.Errorf("rejected operators added %q", got)
+	}
+	if len(m.Params()) != 0 {
+		t.Errorf("rejected operators bound %d parameters", len(m.Params()))
+	}
+}
+
+func TestOrderByBuilderAddExpression(t *testing.T) {
+	b := NewOrderByBuilder()
+	if err := b.AddExpression(`LOWER(u."name")`, "desc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddExpression(`COALESCE(u."nickname", u."name", 'x;y')`, "ASC"); err != nil {
+		t.Fatal(err)
+	}
+	want := `LOWER(u."name") DESC, COALESCE(u."nickname", u."name", 'x;y') ASC`
+	if got := b.BuildString(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	for _, expr := range []string{
+		"",
+		`LOWER(name); DROP TABLE users`,
+		`name -- comment`,
+		`name /* comment */`,
+		`LOWER(name`,
+		`LOWER(name))`,
+		`'unterminated`,
+	} {
+		if err := b.AddExpression(expr, "ASC"); err == nil {
+			t.Errorf("AddExpression(%q) was accepted", expr)
+		}
+	}
+	if got := b.BuildString(); got != want {
+		t.Errorf("rejected expressions were added: %s", got)
+	}
+}
@@ -173,6 +173,8 @@ func UnmarshalID(v interface{}) (string, error) {
 		return strconv.FormatInt(v, 10), nil
 	case float64:
 		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
 	default:
 		return "", fmt.Errorf("cannot unmarshal %T as ID", v)
 	}
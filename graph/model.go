@@ -0,0 +1,218 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// fieldAccessor reads a GraphQL field from a model value
+type fieldAccessor func(v reflect.Value) (interface{}, error)
+
+// modelAccessors holds the field accessors of a registered model
+type modelAccessors struct {
+	typ    reflect.Type // Struct type of the model
+	fields map[string]fieldAccessor
+}
+
+// errorType is the reflect.Type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterModel precomputes how the fields of typeName are read from values of
+// sample's struct type (sample may be a struct or a pointer to one), so default
+// resolution doesn't search fields and methods per resolved field. A field
+// matches its graphql tag, its json tag, its Go name and its Go name with the
+// leading initialism lowercased (Name -> name, ID -> id, URLPath -> urlPath).
+// Methods without arguments match the same names and may return an error as
+// their last result; fields take precedence over methods.
+func (e *Executor) RegisterModel(typeName string, sample interface{}) error {
+	typ := reflect.TypeOf(sample)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("model for %s must be a struct or a pointer to a struct, got %T", typeName, sample)
+	}
+
+	model := newModelAccessors(typ)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.models == nil {
+		e.models = make(map[string]*modelAccessors)
+	}
+	e.models[typeName] = model
+	return nil
+}
+
+// newModelAccessors builds the field and method accessors of a struct type
+func newModelAccessors(typ reflect.Type) *modelAccessors {
+	model := &modelAccessors{
+		typ:    typ,
+		fields: make(map[string]fieldAccessor),
+	}
+
+	// Methods first, so fields with the same name replace them
+	ptrType := reflect.PointerTo(typ)
+	for i := 0; i < ptrType.NumMethod(); i++ {
+		method := ptrType.Method(i)
+		if method.Type.NumIn() != 1 || method.Type.NumOut() == 0 {
+			continue
+		}
+		accessor := methodAccessor(method.Index, method.Type.Out(method.Type.NumOut()-1) == errorType)
+		for _, name := range modelFieldNames(method.Name, "") {
+			model.fields[name] = accessor
+		}
+	}
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		index := field.Index
+		accessor := func(v reflect.Value) (interface{}, error) {
+			if v.Kind() == reflect.Ptr {
+				v = v.Elem()
+			}
+			f, err := v.FieldByIndexErr(index)
+			if err != nil {
+				// A nil embedded pointer on the path
+				return nil, nil
+			}
+			return f.Interface(), nil
+		}
+		for _, name := range modelFieldNames(field.Name, field.Tag.Get("json")) {
+			model.fields[name] = accessor
+		}
+		if tag := field.Tag.Get("graphql"); tag != "" && tag != "-" {
+			model.fields[tag] = accessor
+		}
+	}
+	return model
+}
+
+// SetStructFieldCache enables caching the field accessors of every struct type
+// default resolution meets, keyed by reflect.Type, so lists of structs are
+// resolved without searching fields per row. Fields match the same names as
+// with RegisterModel; with the cache, a name matching no field or method
+// resolves to null instead of falling back to a per-value search.
+func (e *Executor) SetStructFieldCache(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.structCacheEnabled = enabled
+}
+
+// resolveCachedField reads fieldName from a struct (or pointer to one) through
+// the cached accessors of its type; ok is false when the cache is disabled or
+// value isn't a struct
+func (e *Executor) resolveCachedField(value interface{}, fieldName string) (result interface{}, ok bool, err error) {
+	e.mu.RLock()
+	enabled := e.structCacheEnabled
+	e.mu.RUnlock()
+	if !enabled {
+		return nil, false, nil
+	}
+
+	val := reflect.ValueOf(value)
+	typ := val.Type()
+	if typ.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, true, nil
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false, nil
+	}
+
+	cached, found := e.structFields.Load(typ)
+	if !found {
+		cached, _ = e.structFields.LoadOrStore(typ, newModelAccessors(typ))
+	}
+
+	accessor, found := cached.(*modelAccessors).fields[fieldName]
+	if !found {
+		return nil, true, nil
+	}
+	result, err = accessor(val)
+	return result, true, err
+}
+
+// methodAccessor calls the method at index in the pointer type's method set.
+// Values that aren't pointers are copied to reach it.
+func methodAccessor(index int, returnsError bool) fieldAccessor {
+	return func(v reflect.Value) (interface{}, error) {
+		if v.Kind() != reflect.Ptr {
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			v = ptr
+		}
+		results := v.Method(index).Call(nil)
+		if returnsError {
+			if err, _ := results[len(results)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+			if len(results) == 1 {
+				return nil, nil
+			}
+		}
+		return results[0].Interface(), nil
+	}
+}
+
+// modelFieldNames returns the GraphQL names a Go field or method matches
+func modelFieldNames(goName, jsonTag string) []string {
+	names := []string{goName, lowerInitialism(goName)}
+	if tag, _, _ := strings.Cut(jsonTag, ","); tag != "" && tag != "-" {
+		names = append(names, tag)
+	}
+	return names
+}
+
+// lowerInitialism lowercases the leading uppercase run of a Go name, keeping
+// the start of the next word (ID -> id, URLPath -> urlPath, Name -> name)
+func lowerInitialism(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// resolveModelField reads fieldName from value through the accessors of the
+// model registered for typeName; ok is false when they don't apply
+func (e *Executor) resolveModelField(typeName string, value interface{}, fieldName string) (result interface{}, ok bool, err error) {
+	e.mu.RLock()
+	model := e.models[typeName]
+	e.mu.RUnlock()
+	if model == nil {
+		return nil, false, nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Kind() == reflect.Ptr {
+		if val.Type().Elem() != model.typ {
+			return nil, false, nil
+		}
+		if val.IsNil() {
+			return nil, true, nil
+		}
+	} else if val.Type() != model.typ {
+		return nil, false, nil
+	}
+
+	accessor, found := model.fields[fieldName]
+	if !found {
+		return nil, false, nil
+	}
+	result, err = accessor(val)
+	return result, true, err
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type modelBase struct {
+	ID string
+}
+
+type modelUser struct {
+	modelBase
+	Name     string
+	Email    string `json:"email_address,omitempty"`
+	Nickname string `graphql:"handle"`
+	URLPath  string
+	secret   string
+}
+
+func (u *modelUser) DisplayName() string { return u.Name + " (" + u.Email + ")" }
+
+func (u modelUser) Score() (int, error) {
+	if u.Name == "" {
+		return 0, errors.New("no name")
+	}
+	return len(u.Name), nil
+}
+
+func modelSchema(t testing.TB, user interface{}) *ExecutableSchema {
+	t.Helper()
+	es, err := NewExecutableSchema(`
+type Query { user: User }
+type User { id: ID name: String email_address: String handle: String urlPath: String displayName: String score: Int }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Executor.RegisterModel("User", modelUser{}); err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "user", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return user, nil
+	})
+	return es
+}
+
+func TestRegisterModel(t *testing.T) {
+	user := modelUser{modelBase{"7"}, "Ann", "ann@example.com", "annie", "/ann", "x"}
+	const query = `{ user { id name email_address handle urlPath displayName score } }`
+	const want = `{"data":{"user":{"displayName":"Ann (ann@example.com)","email_address":"ann@example.com","handle":"annie","id":"7","name":"Ann","score":3,"urlPath":"/ann"}}}`
+
+	// Values and pointers resolve the same, including pointer-receiver methods
+	for _, value := range []interface{}{user, &user} {
+		if got := execute(t, modelSchema(t, value), query, nil); got != want {
+			t.Errorf("%T:\ngot  %s\nwant %s", value, got, want)
+		}
+	}
+
+	got := execute(t, modelSchema(t, &modelUser{}), `{ user { id score } }`, nil)
+	if want := `"message":"no name"`; !strings.Contains(got, want) {
+		t.Errorf("method error was not reported: %s", got)
+	}
+
+	if got := execute(t, modelSchema(t, (*modelUser)(nil)), `{ user { id } }`, nil); got != `{"data":{"user":null}}` {
+		t.Errorf("nil model: %s", got)
+	}
+
+	es := modelSchema(t, nil)
+	if err := es.Executor.RegisterModel("User", "not a struct"); err == nil {
+		t.Error("a non-struct model was accepted")
+	}
+}
+
+func BenchmarkDefaultResolve(b *testing.B) {
+	user := &modelUser{modelBase{"7"}, "Ann", "ann@example.com", "annie", "/ann", "x"}
+	e := NewExecutor(nil)
+	b.Run("reflection", func(b *testing.B) {
+		for b.Loop() {
+			e.defaultResolve("User", user, "email_address")
+		}
+	})
+	if err := e.RegisterModel("User", modelUser{}); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("model", func(b *testing.B) {
+		for b.Loop() {
+			e.defaultResolve("User", user, "email_address")
+		}
+	})
+}
+
+// cachedUser is resolved through the struct field cache
+type cachedUser struct {
+	ID       int    `json:"id"`
+	FullName string `json:"full_name"`
+	Nickname string `graphql:"handle"`
+	Email    string `json:"-"`
+}
+
+func (u *cachedUser) Initials() string { return u.FullName[:1] }
+
+func cachedUserSchema(t testing.TB, users []*cachedUser) *ExecutableSchema {
+	t.Helper()
+	es, err := NewExecutableSchema(`
+type Query { users: [User] }
+type User { id: ID full_name: String handle: String email: String initials: String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Query", "users", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return users, nil
+	})
+	return es
+}
+
+func TestStructFieldCache(t *testing.T) {
+	users := []*cachedUser{
+		{ID: 1, FullName: "Ann Lee", Nickname: "ann", Email: "ann@example.com"},
+		{ID: 2, FullName: "Bo Kim", Nickname: "bo"},
+		nil,
+	}
+	es := cachedUserSchema(t, users)
+	const query = `{ users { id full_name handle email initials } }`
+	uncached := execute(t, es, query, nil)
+	es.Executor.SetStructFieldCache(true)
+
+	got := execute(t, es, query, nil)
+	if got != uncached {
+		t.Errorf("cached resolution differs:\n%s\nuncached:\n%s", got, uncached)
+	}
+	want := `{"data":{"users":[` +
+		`{"email":"ann@example.com","full_name":"Ann Lee","handle":"ann","id":"1","initials":"A"},` +
+		`{"email":"","full_name":"Bo Kim","handle":"bo","id":"2","initials":"B"},` +
+		`null]}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// Repeated resolution reuses the accessors cached for the type
+	n := 0
+	es.Executor.structFields.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Errorf("%d struct types cached, want 1", n)
+	}
+}
+
+func BenchmarkStructList(b *testing.B) {
+	users := make([]*cachedUser, 1000)
+	for i := range users {
+		users[i] = &cachedUser{ID: i, FullName: "Ann Lee", Nickname: "ann"}
+	}
+	for _, cached := range []bool{false, true} {
+		es := cachedUserSchema(b, users)
+		es.Executor.SetStructFieldCache(cached)
+		name := "reflection"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if resp := es.Execute(context.Background(), ExecuteParams{Query: `{ users { id full_name handle } }`}); len(resp.Errors) > 0 {
+					b.Fatal(resp.Errors)
+				}
+			}
+		})
+	}
+}
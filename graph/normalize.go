@@ -0,0 +1,231 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// NormalizeQuery returns a canonical form of a validated query document for
+// use as a cache or allow-list key. Formatting, comments, the order of fields,
+// arguments and variable definitions, duplicate selections and fragment
+// names don't affect the result: fragment spreads are inlined, and fragments
+// on the enclosing type are merged into it.
+func NormalizeQuery(doc *ast.QueryDocument) string {
+	fragments := make(map[string]*ast.FragmentDefinition, len(doc.Fragments))
+	for _, def := range doc.Fragments {
+		fragments[def.Name] = def
+	}
+
+	operations := make([]string, 0, len(doc.Operations))
+	for _, op := range doc.Operations {
+		var b strings.Builder
+		b.WriteString(string(op.Operation))
+		if op.Name != "" {
+			b.WriteString(" " + op.Name)
+		}
+
+		if len(op.VariableDefinitions) > 0 {
+			vars := make([]string, 0, len(op.VariableDefinitions))
+			for _, v := range op.VariableDefinitions {
+				def := "$" + v.Variable + ":" + v.Type.String()
+				if v.DefaultValue != nil {
+					def += "=" + normalizeValue(v.DefaultValue)
+				}
+				vars = append(vars, def+normalizeDirectives(v.Directives))
+			}
+			sort.Strings(vars)
+			b.WriteString("(" + strings.Join(vars, ",") + ")")
+		}
+
+		b.WriteString(normalizeDirectives(op.Directives))
+		b.WriteString(normalizeSelectionSet(op.SelectionSet, rootTypeName(op.SelectionSet), fragments, nil))
+		operations = append(operations, b.String())
+	}
+	sort.Strings(operations)
+
+	return strings.Join(operations, " ")
+}
+
+// operationDocument returns a document with only op and the fragments it
+// uses, directly or through other fragments, in name order
+func operationDocument(op *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition) *ast.QueryDocument {
+	used := make(map[string]bool)
+	var walk func(set ast.SelectionSet)
+	walk = func(set ast.SelectionSet) {
+		for _, sel := range set {
+			switch s := sel.(type) {
+			case *ast.Field:
+				walk(s.SelectionSet)
+			case *ast.InlineFragment:
+				walk(s.SelectionSet)
+			case *ast.FragmentSpread:
+				if def, ok := fragments[s.Name]; ok && !used[s.Name] {
+					used[s.Name] = true
+					walk(def.SelectionSet)
+				}
+			}
+		}
+	}
+	walk(op.SelectionSet)
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	doc := &ast.QueryDocument{Operations: ast.OperationList{op}}
+	for _, name := range names {
+		doc.Fragments = append(doc.Fragments, fragments[name])
+	}
+	return doc
+}
+
+// normalizeSelectionSet prints a selection set with sorted, deduplicated
+// selections. Fragments on the parent type itself are merged into it.
+func normalizeSelectionSet(set ast.SelectionSet, parentType string, fragments map[string]*ast.FragmentDefinition, visiting map[string]bool) string {
+	if len(set) == 0 {
+		return ""
+	}
+
+	seen := make(map[string]bool, len(set))
+	selections := make([]string, 0, len(set))
+	collectNormalized(set, parentType, fragments, visiting, func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			selections = append(selections, s)
+		}
+	})
+	sort.Strings(selections)
+
+	return "{" + strings.Join(selections, " ") + "}"
+}
+
+// collectNormalized passes the printed selections of set to add; visiting
+// holds the fragments being inlined, guarding against cycles
+func collectNormalized(set ast.SelectionSet, parentType string, fragments map[string]*ast.FragmentDefinition, visiting map[string]bool, add func(string)) {
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			field := s.Name
+			if s.Alias != "" && s.Alias != s.Name {
+				field = s.Alias + ":" + s.Name
+			}
+			fieldType := ""
+			if s.Definition != nil {
+				fieldType = s.Definition.Type.Name()
+			}
+			field += normalizeArguments(s.Arguments)
+			field += normalizeDirectives(s.Directives)
+			field += normalizeSelectionSet(s.SelectionSet, fieldType, fragments, visiting)
+			add(field)
+
+		case *ast.InlineFragment:
+			collectFragment(s.TypeCondition, s.Directives, s.SelectionSet, parentType, fragments, visiting, add)
+
+		case *ast.FragmentSpread:
+			def, ok := fragments[s.Name]
+			if !ok || visiting[s.Name] {
+				continue
+			}
+			inner := make(map[string]bool, len(visiting)+1)
+			for name := range visiting {
+				inner[name] = true
+			}
+			inner[s.Name] = true
+			directives := append(ast.DirectiveList{}, s.Directives...)
+			directives = append(directives, def.Directives...)
+			collectFragment(def.TypeCondition, directives, def.SelectionSet, parentType, fragments, inner, add)
+		}
+	}
+}
+
+// collectFragment merges a fragment without directives on the parent type
+// into the enclosing selections and prints others as "...on Type{...}"
+func collectFragment(typeCondition string, directives ast.DirectiveList, set ast.SelectionSet, parentType string, fragments map[string]*ast.FragmentDefinition, visiting map[string]bool, add func(string)) {
+	if len(directives) == 0 && (typeCondition == "" || typeCondition == parentType) {
+		collectNormalized(set, parentType, fragments, visiting, add)
+		return
+	}
+
+	s := "..."
+	innerType := parentType
+	if typeCondition != "" {
+		s += "on " + typeCondition
+		innerType = typeCondition
+	}
+	add(s + normalizeDirectives(directives) + normalizeSelectionSet(set, innerType, fragments, visiting))
+}
+
+// rootTypeName returns the type of the fields of an operation's selection set
+func rootTypeName(set ast.SelectionSet) string {
+	for _, sel := range set {
+		var def *ast.Definition
+		switch s := sel.(type) {
+		case *ast.Field:
+			def = s.ObjectDefinition
+		case *ast.FragmentSpread:
+			def = s.ObjectDefinition
+		case *ast.InlineFragment:
+			def = s.ObjectDefinition
+		}
+		if def != nil {
+			return def.Name
+		}
+	}
+	return ""
+}
+
+// normalizeArguments prints arguments sorted by name
+func normalizeArguments(args ast.ArgumentList) string {
+	if len(args) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		parts = append(parts, arg.Name+":"+normalizeValue(arg.Value))
+	}
+	sort.Strings(parts)
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// normalizeDirectives prints directives in order, with sorted arguments
+func normalizeDirectives(directives ast.DirectiveList) string {
+	var b strings.Builder
+	for _, d := range directives {
+		b.WriteString("@" + d.Name + normalizeArguments(d.Arguments))
+	}
+	return b.String()
+}
+
+// normalizeValue prints a value with object fields sorted by name
+func normalizeValue(v *ast.Value) string {
+	if v == nil {
+		return "null"
+	}
+
+	switch v.Kind {
+	case ast.Variable:
+		return "$" + v.Raw
+	case ast.StringValue, ast.BlockValue:
+		return strconv.Quote(v.Raw)
+	case ast.ListValue:
+		items := make([]string, 0, len(v.Children))
+		for _, child := range v.Children {
+			items = append(items, normalizeValue(child.Value))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case ast.ObjectValue:
+		fields := make([]string, 0, len(v.Children))
+		for _, child := range v.Children {
+			fields = append(fields, child.Name+":"+normalizeValue(child.Value))
+		}
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ",") + "}"
+	default:
+		return v.Raw
+	}
+}
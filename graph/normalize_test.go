@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2"
+)
+
+func TestNormalizedQueryIsPerOperation(t *testing.T) {
+	es, err := NewExecutableSchema(`type Query { a: Int b: Int }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	es.Executor.SetQueryNormalizer(NormalizeQuery)
+	es.RegisterResolver("Query", "a", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		keys = append(keys, GetRequestContext(ctx).NormalizedQuery)
+		return 1, nil
+	})
+	es.RegisterResolver("Query", "b", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		keys = append(keys, GetRequestContext(ctx).NormalizedQuery)
+		return 2, nil
+	})
+
+	query := `query A { ...F } query B { b } fragment F on Query { a }`
+	for _, name := range []string{"A", "B"} {
+		if resp := es.Execute(context.Background(), ExecuteParams{Query: query, OperationName: name}); len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+	}
+	want := []string{"query A{a}", "query B{b}"}
+	if len(keys) != 2 || keys[0] != want[0] || keys[1] != want[1] {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+}
+
+func TestNormalizeQueryEquivalentQueries(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { user(id: ID!, active: Boolean): User }
+type User { id: ID! name: String posts(first: Int): [Post] }
+type Post { title: String }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalize := func(query string) string {
+		t.Helper()
+		doc, errs := gqlparser.LoadQuery(es.Schema.GetSchema(), query)
+		if errs != nil {
+			t.Fatalf("%s: %v", query, errs)
+		}
+		return NormalizeQuery(doc)
+	}
+
+	base := `query Q($id: ID!) { user(id: $id, active: true) { id name posts(first: 2) { title } } }`
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"formatting", `
+# Look up one user
+query   Q( $id : ID! ) {
+	user( id : $id , active : true ) {
+		id
+		name
+		posts ( first : 2 ) { title }
+	}
+}`},
+		{"field order", `query Q($id: ID!) { user(active: true, id: $id) { posts(first: 2) { title } name id } }`},
+		{"fragment", `query Q($id: ID!) { user(id: $id, active: true) { ...U } } fragment U on User { id name posts(first: 2) { title } }`},
+		{"inline fragment", `query Q($id: ID!) { user(id: $id, active: true) { id ... on User { name posts(first: 2) { title } } } }`},
+		{"duplicate fields", `query Q($id: ID!) { user(id: $id, active: true) { id name id posts(first: 2) { title } name } }`},
+	}
+	want := normalize(base)
+	for _, tt := range tests {
+		if got := normalize(tt.query); got != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, want)
+		}
+	}
+
+	// Different arguments are different queries
+	if got := normalize(`query Q($id: ID!) { user(id: $id, active: false) { id name posts(first: 2) { title } } }`); got == want {
+		t.Errorf("argument change normalized to the same key %s", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // ResolverFunc is the signature for field resolver functions
@@ -115,11 +116,28 @@ type ResolveInfo struct {
 	Arguments    map[string]interface{}
 	Variables    map[string]interface{}
 	Selection    *SelectionSet
+	Directives   []*DirectiveInstance // Directives on the field in the query, e.g. @lang(code: "en")
 	Path         []string
-	RootValue    interface{}
+	ParentValue  interface{} // The object whose field is being resolved
+	RootValue    interface{} // The root value passed to Execute
 	OperationCtx *OperationContext
 }
 
+// PathString returns the response path joined with dots (e.g., "user.posts.0.title")
+func (i *ResolveInfo) PathString() string {
+	return strings.Join(i.Path, ".")
+}
+
+// Directive returns the query directive named name on the field, or nil
+func (i *ResolveInfo) Directive(name string) *DirectiveInstance {
+	for _, d := range i.Directives {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
 // SelectionSet represents selected fields in a query
 type SelectionSet struct {
 	Fields   []*SelectedField
@@ -133,6 +151,10 @@ type SelectedField struct {
 	Arguments  map[string]interface{}
 	Selections *SelectionSet
 	Directives []*DirectiveInstance
+
+	// TypeConditions limits the field to these runtime types when it was
+	// selected through a fragment on a concrete type of an abstract parent
+	TypeConditions []string
 }
 
 // DirectiveInstance represents a directive applied to a field in a query
@@ -149,6 +171,19 @@ func (sf *SelectedField) GetName() string {
 	return sf.Name
 }
 
+// AppliesTo reports whether the field is selected for the given runtime type
+func (sf *SelectedField) AppliesTo(typeName string) bool {
+	if len(sf.TypeConditions) == 0 {
+		return true
+	}
+	for _, t := range sf.TypeConditions {
+		if t == typeName {
+			return true
+		}
+	}
+	return false
+}
+
 // HasSelection checks if a field has nested selections
 func (sf *SelectedField) HasSelection() bool {
 	return sf.Selections != nil && len(sf.Selections.Fields) > 0
@@ -161,6 +196,7 @@ type OperationContext struct {
 	Variables     map[string]interface{}
 	Schema        *Schema
 	RootResolver  RootResolver
+	RootValue     interface{}
 }
 
 // DataLoader provides batching and caching for resolver data fetching
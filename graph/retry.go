@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how ExecuteWithRetry retries transient errors
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts including the first; 1 or less runs once
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each next one
+	MaxDelay    time.Duration // Upper bound of a delay; 0 for none
+
+	// Retryable classifies errors; IsTransientError when nil
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy makes up to three attempts, waiting 50ms and then 100ms
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// transientSQLStates are SQLSTATE codes and classes worth retrying:
+// serialization failures, deadlocks, connection exceptions (class 08) and
+// server shutdowns
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+	"08":    true, // connection exception
+}
+
+// IsTransientError reports whether err is likely to go away when the
+// statement is run again: a serialization failure or deadlock, a dropped
+// connection, or a driver error with one of those SQLSTATE codes. Errors of
+// drivers exposing SQLState() (lib/pq, pgx) are classified by their code.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var coded interface{ SQLState() string }
+	if errors.As(err, &coded) {
+		code := coded.SQLState()
+		return transientSQLStates[code] || (len(code) == 5 && transientSQLStates[code[:2]])
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// ExecuteWithRetry runs a converted statement, a *SQLSelectResult or a
+// *SQLMutationResult, retrying transient errors with exponential backoff.
+// SELECTs are always retried; mutations only when marked Idempotent, since
+// a write may have been applied before its error. Selected and RETURNING
+// rows are scanned for typeName into Rows. db must not be a transaction:
+// after a serialization failure only a new transaction can succeed.
+func (c *SQLConverter) ExecuteWithRetry(ctx context.Context, db DB, typeName string, result interface{}, policy RetryPolicy) (*MutationExecResult, error) {
+	var run func() (*MutationExecResult, error)
+	retry := true
+
+	switch r := result.(type) {
+	case *SQLSelectResult:
+		run = func() (*MutationExecResult, error) {
+			scanned, err := c.queryRows(ctx, db, typeName, r)
+			if err != nil {
+				return nil, err
+			}
+			return &MutationExecResult{AffectedRows: int64(len(scanned)), Rows: scanned}, nil
+		}
+	case *SQLMutationResult:
+		run = func() (*MutationExecResult, error) {
+			return c.ExecuteMutation(ctx, db, typeName, r)
+		}
+		retry = r.Idempotent
+	default:
+		return nil, fmt.Errorf("cannot execute %T", result)
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	delay := policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		res, err := run()
+		if err == nil || !retry || attempt >= policy.MaxAttempts || !retryable(err) {
+			return res, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sqlStateError is a driver error carrying a SQLSTATE code, like lib/pq's
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// flakyDB fails its first calls with err before passing through to DB
+type flakyDB struct {
+	DB
+	failures int
+	err      error
+	calls    int
+}
+
+func (d *flakyDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if d.calls++; d.calls <= d.failures {
+		return nil, d.err
+	}
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d *flakyDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if d.calls++; d.calls <= d.failures {
+		return nil, d.err
+	}
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{sqlStateError("40001"), true},
+		{sqlStateError("40P01"), true},
+		{sqlStateError("08006"), true},
+		{fmt.Errorf("select: %w", sqlStateError("57P01")), true},
+		{sqlStateError("23505"), false}, // unique_violation
+		{driver.ErrBadConn, true},
+		{context.Canceled, false},
+		{errors.New("syntax error"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.want {
+			t.Errorf("IsTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteWithRetry(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	fake := &fakeDB{columns: []string{"full_name"}, rows: [][]driver.Value{{"Ann"}}}
+
+	sel, err := c.ConvertToSelect(ctx, listInfo("users", "User", nil, &SelectedField{Name: "fullName"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &flakyDB{DB: fake.open(t), failures: 1, err: sqlStateError("40001")}
+	res, err := c.ExecuteWithRetry(ctx, db, "User", sel, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.calls != 2 || !reflect.DeepEqual(res.Rows, []map[string]interface{}{{"fullName": "Ann"}}) {
+		t.Errorf("select: %d calls, rows %v; want success on the second attempt", db.calls, res.Rows)
+	}
+
+	// Writes are only retried when marked idempotent
+	update, err := c.ConvertToUpdate(ctx, "User", map[string]interface{}{"id": map[string]interface{}{"_eq": "1"}},
+		map[string]interface{}{"fullName": "Bo"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = &flakyDB{DB: fake.open(t), failures: 1, err: sqlStateError("40001")}
+	if _, err := c.ExecuteWithRetry(ctx, db, "User", update, policy); err == nil || db.calls != 1 {
+		t.Errorf("non-idempotent update: err = %v after %d calls, want the first error", err, db.calls)
+	}
+	update.Idempotent = true
+	db = &flakyDB{DB: fake.open(t), failures: 1, err: sqlStateError("40001")}
+	if _, err := c.ExecuteWithRetry(ctx, db, "User", update, policy); err != nil || db.calls != 2 {
+		t.Errorf("idempotent update: err = %v after %d calls", err, db.calls)
+	}
+
+	// Permanent errors and exhausted attempts return the error
+	db = &flakyDB{DB: fake.open(t), failures: 1, err: sqlStateError("23505")}
+	if _, err := c.ExecuteWithRetry(ctx, db, "User", sel, policy); err == nil || db.calls != 1 {
+		t.Errorf("permanent error: err = %v after %d calls", err, db.calls)
+	}
+	db = &flakyDB{DB: fake.open(t), failures: 5, err: driver.ErrBadConn}
+	if _, err := c.ExecuteWithRetry(ctx, db, "User", sel, policy); !errors.Is(err, driver.ErrBadConn) || db.calls != 3 {
+		t.Errorf("exhausted attempts: err = %v after %d calls, want 3", err, db.calls)
+	}
+}
@@ -0,0 +1,279 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DB is the subset of *sql.DB, *sql.Tx and *sql.Conn used to run converted queries
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// DBPool pairs a primary database with an optional read replica
+type DBPool struct {
+	Primary DB
+	Replica DB // Queries use Primary when nil
+}
+
+// For returns the database for an operation type: the replica for "query"
+// and the primary otherwise
+func (p *DBPool) For(operationType string) DB {
+	if operationType == "query" && p.Replica != nil {
+		return p.Replica
+	}
+	return p.Primary
+}
+
+// MutationExecResult is the outcome of ExecuteMutation
+type MutationExecResult struct {
+	AffectedRows int64                    `json:"affectedRows"` // Rows inserted, updated or deleted
+	Rows         []map[string]interface{} `json:"returning"`    // RETURNING rows keyed by field name; nil without RETURNING
+}
+
+// ExecuteMutation runs a converted mutation. Without RETURNING the affected
+// row count comes from the driver; with RETURNING the rows are scanned for
+// typeName and counted.
+func (c *SQLConverter) ExecuteMutation(ctx context.Context, db DB, typeName string, mutation *SQLMutationResult) (*MutationExecResult, error) {
+	if !mutation.Returning {
+		res, err := db.ExecContext(ctx, mutation.Query, mutation.Params...)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("rows affected not supported: %w", err)
+		}
+		return &MutationExecResult{AffectedRows: affected}, nil
+	}
+
+	rows, err := db.QueryContext(ctx, mutation.Query, mutation.Params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scanned, err := c.ScanRows(rows, typeName)
+	if err != nil {
+		return nil, err
+	}
+	return &MutationExecResult{
+		AffectedRows: int64(len(scanned)),
+		Rows:         scanned,
+	}, nil
+}
+
+// ScanRows scans every row into a map keyed by GraphQL field name. Columns are
+// matched to the fields of typeName through the column mapping; unmatched
+// columns keep their column name. NULL columns become nil, so an empty string
+// and NULL stay distinct, and a NULL in a non-null field is an error.
+func (c *SQLConverter) ScanRows(rows *sql.Rows, typeName string) ([]map[string]interface{}, error) {
+	return c.scanRows(rows, typeName, nil)
+}
+
+// ScanSelect scans the rows of a converted SELECT into the response shape
+// given by its ColumnMapping: joined columns are nested under their relation
+// field, and a relation whose columns are all NULL (no joined row) becomes
+// nil. Rows of a hasMany join are grouped by the parent's primary key, each
+// joined row becoming an item of the relation's list (empty without joined
+// rows). Columns without a mapping are scanned like ScanRows does.
+func (c *SQLConverter) ScanSelect(rows *sql.Rows, typeName string, result *SQLSelectResult) ([]map[string]interface{}, error) {
+	paths := make(map[string][]ColumnPath, len(result.ColumnMapping))
+	for _, m := range result.ColumnMapping {
+		paths[m.Column] = append(paths[m.Column], m)
+	}
+	return c.scanRows(rows, typeName, paths)
+}
+
+// scanRows scans rows, placing mapped columns at their response paths
+func (c *SQLConverter) scanRows(rows *sql.Rows, typeName string, paths map[string][]ColumnPath) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve column -> field once for the whole result set. Interfaces also
+	// map the columns of their implementations (single-table inheritance),
+	// whose nullability only holds for rows of that implementation.
+	fieldByColumn := make(map[string]*FieldDefinition)
+	nonNullIn := make(map[string]map[string]bool) // column -> types where it is non-null
+	for _, t := range c.scanTypes(typeName) {
+		if objType, ok := c.schema.GetType(t); ok {
+			for name, field := range objType.Fields {
+				col := c.getColumnName(t, name)
+				if _, exists := fieldByColumn[col]; !exists {
+					fieldByColumn[col] = field
+				}
+				if field.Type != nil && field.Type.NonNull {
+					if nonNullIn[col] == nil {
+						nonNullIn[col] = make(map[string]bool)
+					}
+					nonNullIn[col][t] = true
+				}
+			}
+		}
+	}
+	typenameIndex := -1
+	for i, col := range columns {
+		if col == "__typename" {
+			typenameIndex = i
+		}
+	}
+
+	// Relation fields filled by joined columns; hasMany relations are lists
+	// filled across the rows of one parent
+	var nested, lists []string
+	keyed := false
+	for _, colPaths := range paths {
+		for _, m := range colPaths {
+			switch {
+			case m.Key:
+				keyed = true
+			case m.List:
+				if !containsString(lists, m.Path[0]) {
+					lists = append(lists, m.Path[0])
+				}
+			case len(m.Path) > 1:
+				if !containsString(nested, m.Path[0]) {
+					nested = append(nested, m.Path[0])
+				}
+			}
+		}
+	}
+
+	result := make([]map[string]interface{}, 0)
+	parents := make(map[string]map[string]interface{})
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		for i := range values {
+			values[i] = nil
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		// The implementation of this row when typeName is abstract
+		rowType := typeName
+		if typenameIndex >= 0 {
+			switch v := values[typenameIndex].(type) {
+			case string:
+				rowType = v
+			case []byte:
+				rowType = string(v)
+			}
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		items := make(map[string]map[string]interface{}, len(lists))
+		var key []interface{}
+		for i, col := range columns {
+			name := col
+			field := fieldByColumn[col]
+			if field != nil {
+				name = field.Name
+			}
+
+			var value interface{}
+			switch v := values[i].(type) {
+			case nil:
+				for _, t := range []string{typeName, rowType} {
+					if nonNullIn[col][t] {
+						return nil, fmt.Errorf("column %s is NULL but %s.%s is non-null", col, t, field.Name)
+					}
+				}
+			case []byte:
+				// Drivers may reuse the buffer on the next Scan
+				value = string(v)
+			default:
+				value = v
+			}
+
+			if colPaths, ok := paths[col]; ok {
+				for _, m := range colPaths {
+					switch {
+					case m.Key:
+						key = append(key, value)
+					case m.List:
+						item, ok := items[m.Path[0]]
+						if !ok {
+							item = make(map[string]interface{})
+							items[m.Path[0]] = item
+						}
+						setPath(item, m.Path[1:], value)
+					default:
+						setPath(row, m.Path, value)
+					}
+				}
+				continue
+			}
+			row[name] = value
+		}
+		for _, m := range nested {
+			if obj, ok := row[m].(map[string]interface{}); ok && allNil(obj) {
+				row[m] = nil
+			}
+		}
+
+		// Later rows of a parent only add hasMany items
+		parent, seen := row, false
+		if keyed {
+			k := fmt.Sprintf("%#v", key)
+			parent, seen = parents[k]
+			if !seen {
+				parent = row
+				parents[k] = row
+			}
+		}
+		if !seen {
+			for _, name := range lists {
+				row[name] = make([]interface{}, 0)
+			}
+			result = append(result, row)
+		}
+		for _, name := range lists {
+			if item := items[name]; item != nil && !allNil(item) {
+				parent[name] = append(parent[name].([]interface{}), item)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// setPath sets value at path in row, creating the nested objects on the way
+func setPath(row map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := row[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			row[key] = next
+		}
+		row = next
+	}
+	row[path[len(path)-1]] = value
+}
+
+// allNil reports whether every value of obj is nil
+func allNil(obj map[string]interface{}) bool {
+	for _, v := range obj {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// scanTypes returns typeName followed by its possible types when it is abstract
+func (c *SQLConverter) scanTypes(typeName string) []string {
+	return append([]string{typeName}, c.schema.PossibleTypes(typeName)...)
+}
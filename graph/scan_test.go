@@ -0,0 +1,390 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eddieafk/goinmonster/sql/dialect"
+	"github.com/eddieafk/goinmonster/sql/stringifiers/dialecttypes"
+)
+
+// fakeDB answers every query with the same columns and rows
+type fakeDB struct {
+	columns []string
+	rows    [][]driver.Value
+	queries []string
+}
+
+// open returns a *sql.DB backed by d
+func (d *fakeDB) open(t *testing.T) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (d *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d *fakeDB) Driver() driver.Driver                        { return d }
+func (d *fakeDB) Open(string) (driver.Conn, error)             { return fakeConn{d}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.queries = append(c.db.queries, query)
+	return &fakeRows{columns: c.db.columns, rows: c.db.rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.queries = append(c.db.queries, query)
+	return driver.RowsAffected(len(c.db.rows)), nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// query runs result's query against d
+func (d *fakeDB) query(t *testing.T, result *SQLSelectResult) *sql.Rows {
+	t.Helper()
+	rows, err := d.open(t).Query(result.Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func TestScanSelectGroupsHasManyRows(t *testing.T) {
+	c := tenantConverter(t)
+	info := listInfo("users", "User", nil, &SelectedField{Name: "fullName"},
+		&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}})
+	result, err := c.ConvertToSelect(WithTenant(context.Background(), "t1"), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Query, `u."id" AS "__key_id"`) {
+		t.Fatalf("parent key is not projected:\n%s", result.Query)
+	}
+	if !strings.Contains(result.Query, ") p_pos ON true") {
+		t.Errorf("lateral join is not joined on its correlated subquery:\n%s", result.Query)
+	}
+
+	db := &fakeDB{
+		columns: []string{"full_name", "p_pos_title", "__key_id"},
+		rows: [][]driver.Value{
+			{"Ann", "first", int64(1)},
+			{"Ann", "second", int64(1)},
+			{"Ann", nil, int64(2)},
+			{"Bob", "third", int64(3)},
+		},
+	}
+	got, err := c.ScanSelect(db.query(t, result), "User", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"fullName": "Ann", "posts": []interface{}{
+			map[string]interface{}{"title": "first"},
+			map[string]interface{}{"title": "second"},
+		}},
+		{"fullName": "Ann", "posts": []interface{}{}},
+		{"fullName": "Bob", "posts": []interface{}{map[string]interface{}{"title": "third"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestScanSelectNestsBelongsTo(t *testing.T) {
+	c := tenantConverter(t)
+	info := listInfo("posts", "Post", nil, &SelectedField{Name: "title"},
+		&SelectedField{Name: "author", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "fullName"}}}})
+	result, err := c.ConvertToSelect(WithTenant(context.Background(), "t1"), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result.Query, "__key_") {
+		t.Errorf("belongsTo join projects a parent key:\n%s", result.Query)
+	}
+
+	db := &fakeDB{
+		columns: []string{"title", "a_aut_fullName"},
+		rows:    [][]driver.Value{{"first", "Ann"}, {"orphan", nil}},
+	}
+	got, err := c.ScanSelect(db.query(t, result), "Post", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"title": "first", "author": map[string]interface{}{"fullName": "Ann"}},
+		{"title": "orphan", "author": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestScanRowsNulls(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapFieldToColumn("User", "fullName", "display_name")
+	db := &fakeDB{
+		columns: []string{"id", "display_name", "tenant_id"},
+		rows: [][]driver.Value{
+			{int64(1), nil, ""},
+			{int64(2), []byte("Ann"), nil},
+		},
+	}
+	rows := db.query(t, &SQLSelectResult{Query: "SELECT"})
+	got, err := c.ScanRows(rows, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"id": int64(1), "fullName": nil, "tenantId": ""},
+		{"id": int64(2), "fullName": "Ann", "tenantId": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// id is ID!, so a NULL there is an error
+	db.rows = [][]driver.Value{{nil, "Ann", nil}}
+	if _, err := c.ScanRows(db.query(t, &SQLSelectResult{Query: "SELECT"}), "User"); err == nil {
+		t.Error("NULL in a non-null field was accepted")
+	}
+}
+
+func TestSingleTableInheritance(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { animals: [Animal] dogs: [Dog] }
+interface Animal { id: ID! name: String }
+type Dog implements Animal { id: ID! name: String barks: Boolean }
+type Cat implements Animal { id: ID! name: String indoor: Boolean }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSQLConverter(es.Schema, dialect.PostgreSQL)
+	c.SetSQLFormat(SQLFormatCompact)
+	c.ConfigureInheritance("Animal", &InheritanceConfig{
+		Table:               "animals",
+		DiscriminatorColumn: "kind",
+		TypeValues:          map[string]string{"Dog": "dog", "Cat": "cat"},
+	})
+
+	db := &fakeDB{
+		columns: []string{"name", "barks", "indoor", "__typename"},
+		rows: [][]driver.Value{
+			{"Rex", true, nil, "Dog"},
+			{"Tom", nil, false, "Cat"},
+		},
+	}
+	var queries []string
+	resolve := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		info := GetResolveInfo(ctx)
+		result, err := c.ConvertToSelect(ctx, info)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, result.Query)
+		return c.ScanSelect(db.query(t, result), unwrapTypeName(info.ReturnType), result)
+	}
+	es.RegisterResolver("Query", "animals", resolve)
+	es.RegisterResolver("Query", "dogs", resolve)
+
+	got := execute(t, es, `{ animals { __typename name ... on Dog { barks } ... on Cat { indoor } } }`, nil)
+	want := `{"data":{"animals":[{"__typename":"Dog","barks":true,"name":"Rex"},{"__typename":"Cat","indoor":false,"name":"Tom"}]}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	wantSQL := `SELECT a."name", a."barks", a."indoor", CASE a."kind" WHEN 'cat' THEN 'Cat' WHEN 'dog' THEN 'Dog' END AS "__typename" FROM "animals" a`
+	if len(queries) != 1 || queries[0] != wantSQL {
+		t.Errorf("interface query:\ngot  %v\nwant %s", queries, wantSQL)
+	}
+
+	// An implementation only reads its own rows
+	queries = nil
+	db.columns, db.rows = []string{"name"}, [][]driver.Value{{"Rex"}}
+	execute(t, es, `{ dogs { name } }`, nil)
+	if len(queries) != 1 || !strings.HasSuffix(queries[0], `FROM "animals" d WHERE d."kind" = $1`) {
+		t.Errorf("implementation query: %v", queries)
+	}
+}
+
+func TestScanRowsInheritanceNulls(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { animals: [Animal] }
+interface Animal { name: String }
+type Dog implements Animal { name: String barks: Boolean! }
+type Cat implements Animal { name: String indoor: Boolean! }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewSQLConverter(es.Schema, dialect.PostgreSQL)
+	db := &fakeDB{
+		columns: []string{"name", "barks", "indoor", "__typename"},
+		rows: [][]driver.Value{
+			{"Rex", true, nil, "Dog"},
+			{"Tom", nil, false, "Cat"},
+		},
+	}
+	got, err := c.ScanRows(db.query(t, &SQLSelectResult{Query: "SELECT"}), "Animal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"name": "Rex", "barks": true, "indoor": nil, "__typename": "Dog"},
+		{"name": "Tom", "barks": nil, "indoor": false, "__typename": "Cat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Dog.barks is still non-null for Dog rows
+	db.rows = [][]driver.Value{{"Rex", nil, nil, "Dog"}}
+	if _, err := c.ScanRows(db.query(t, &SQLSelectResult{Query: "SELECT"}), "Animal"); err == nil {
+		t.Error("NULL in Dog.barks was accepted for a Dog row")
+	}
+}
+
+func TestExecuteMutationAffectedRows(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	db := &fakeDB{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}},
+	}
+	where := map[string]interface{}{"fullName": map[string]interface{}{"_eq": "Ann"}}
+	set := map[string]interface{}{"fullName": "Bo"}
+
+	update, err := c.ConvertToUpdate(context.Background(), "User", where, set, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.ExecuteMutation(context.Background(), db.open(t), "User", update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.AffectedRows != 3 || res.Rows != nil {
+		t.Errorf("without RETURNING: %+v", res)
+	}
+
+	update, err = c.ConvertToUpdate(context.Background(), "User", where, set, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = c.ExecuteMutation(context.Background(), db.open(t), "User", update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}, {"id": int64(3)}}
+	if res.AffectedRows != 3 || !reflect.DeepEqual(res.Rows, want) {
+		t.Errorf("with RETURNING: %+v", res)
+	}
+	if got := db.queries[len(db.queries)-1]; !strings.HasSuffix(got, `RETURNING "id"`) {
+		t.Errorf("query = %s", got)
+	}
+}
+
+func TestDBPoolRouting(t *testing.T) {
+	es, err := NewExecutableSchema(`
+type Query { users: Boolean fresh: Boolean }
+type Mutation { addUser: Boolean }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(query string) func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			db := DBFromContext(ctx)
+			if db == nil {
+				return nil, errors.New("no database in context")
+			}
+			rows, err := db.QueryContext(ctx, query)
+			if err != nil {
+				return nil, err
+			}
+			return true, rows.Close()
+		}
+	}
+	es.RegisterResolver("Query", "users", run("SELECT users"))
+	es.RegisterResolver("Mutation", "addUser", run("INSERT user"))
+	es.RegisterResolver("Query", "fresh", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return run("SELECT fresh")(WithPrimary(ctx), args)
+	})
+
+	primary, replica := &fakeDB{}, &fakeDB{}
+	es.Executor.SetDBPool(&DBPool{Primary: primary.open(t), Replica: replica.open(t)})
+
+	for _, query := range []string{`{ users }`, `mutation { addUser }`, `{ fresh }`} {
+		if got := execute(t, es, query, nil); strings.Contains(got, "errors") {
+			t.Fatalf("%s: %s", query, got)
+		}
+	}
+	if want := []string{"SELECT users"}; !reflect.DeepEqual(replica.queries, want) {
+		t.Errorf("replica ran %q, want %q", replica.queries, want)
+	}
+	if want := []string{"INSERT user", "SELECT fresh"}; !reflect.DeepEqual(primary.queries, want) {
+		t.Errorf("primary ran %q, want %q", primary.queries, want)
+	}
+
+	if db := (&DBPool{Primary: primary.open(t)}).For("query"); db == nil {
+		t.Error("a pool without a replica has no database for queries")
+	}
+}
+
+func TestExplainPlan(t *testing.T) {
+	c := newTestConverter(t, testSchema)
+	c.MapTypeToTable("User", "users")
+	ctx := context.Background()
+	result, err := c.ConvertToSelect(ctx, listInfo("users", "User", nil, &SelectedField{Name: "fullName"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := `[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.5, "Total Cost": 12.25, "Plan Rows": 100,
+		"Actual Total Time": 0.3, "Actual Rows": 3}, "Planning Time": 0.1, "Execution Time": 0.4}]`
+	db := &fakeDB{columns: []string{"QUERY PLAN"}, rows: [][]driver.Value{{[]byte(plan)}}}
+	got, err := c.ExplainPlan(ctx, db.open(t), result, dialecttypes.ExplainOptions{Analyze: true, Format: "TEXT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "EXPLAIN (ANALYZE, FORMAT JSON) " + result.Query; db.queries[0] != want {
+		t.Errorf("query = %s\nwant %s", db.queries[0], want)
+	}
+	if got.NodeType != "Seq Scan" || got.StartupCost != 0.5 || got.TotalCost != 12.25 || got.PlanRows != 100 ||
+		got.ActualTime != 0.3 || got.ActualRows != 3 || got.PlanningTime != 0.1 || got.ExecutionTime != 0.4 {
+		t.Errorf("plan = %+v", got)
+	}
+
+	for _, bad := range []string{`not json`, `[]`} {
+		db := &fakeDB{columns: []string{"QUERY PLAN"}, rows: [][]driver.Value{{[]byte(bad)}}}
+		if _, err := c.ExplainPlan(ctx, db.open(t), result, dialecttypes.ExplainOptions{}); err == nil {
+			t.Errorf("plan %s was accepted", bad)
+		}
+	}
+}
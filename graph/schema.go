@@ -2,6 +2,9 @@ package graph
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/vektah/gqlparser/v2"
@@ -19,6 +22,9 @@ type Schema struct {
 	enumMap      map[string]*EnumType
 	scalarMap    map[string]*ScalarType
 
+	// Object types of each interface and union
+	possibleTypes map[string][]string
+
 	mu sync.RWMutex
 }
 
@@ -29,6 +35,17 @@ type ObjectType struct {
 	Fields      map[string]*FieldDefinition
 	Implements  []string
 	Directives  []*Directive
+	SQLReadonly bool // True when marked @sql(readonly: true)
+
+	// Table mapping from a type-level @sql(table:, schema:, primaryKey:)
+	SQLTable      string
+	SQLSchema     string
+	SQLPrimaryKey []string
+}
+
+// Directive returns the first directive with the given name applied to the type
+func (t *ObjectType) Directive(name string) (*Directive, bool) {
+	return findDirective(t.Directives, name)
 }
 
 // FieldDefinition represents a field in an object type
@@ -40,9 +57,16 @@ type FieldDefinition struct {
 	Directives  []*Directive
 
 	// SQL mapping (optional)
-	SQLColumn   string // Maps to SQL column name
-	SQLTable    string // Maps to SQL table name
-	SQLRelation string // Relation type: "hasOne", "hasMany", "belongsTo"
+	SQLColumn   string   // Maps to SQL column name
+	SQLTable    string   // Maps to SQL table name
+	SQLRelation string   // Relation type: "hasOne", "hasMany", "belongsTo"
+	Filterable  bool     // False when marked @sql(filterable: false)
+	Sortable    bool     // False when marked @sql(sortable: false)
+	SQLDefault  string   // SQL function used on insert when no value is given (e.g., "now()")
+	SQLCast     string   // Type the column and bound parameters are cast to (e.g., "uuid")
+	SQLIndex    string   // Name of the index covering the column, from @sql(index: "...")
+	SQLJoinOn   string   // Custom join condition of a relation (see JoinConfig.On)
+	SQLJSONPath []string // Keys of the JSON sub-path read from the column (e.g., profile, bio)
 }
 
 // ArgumentDefinition represents an argument for a field
@@ -66,6 +90,7 @@ type InputType struct {
 	Name        string
 	Description string
 	Fields      map[string]*InputFieldDefinition
+	OneOf       bool // Marked @oneOf: exactly one field must be given, and not null
 }
 
 // InputFieldDefinition represents a field in an input type
@@ -92,9 +117,10 @@ type EnumValue struct {
 
 // ScalarType represents a custom scalar type
 type ScalarType struct {
-	Name        string
-	Description string
-	Marshaler   Marshaler
+	Name           string
+	Description    string
+	SpecifiedByURL string // From @specifiedBy(url: ...)
+	Marshaler      Marshaler
 }
 
 // Directive represents a directive attached to a schema element
@@ -103,7 +129,9 @@ type Directive struct {
 	Arguments map[string]interface{}
 }
 
-// Marshaler interface for custom scalar types
+// Marshaler interface for custom scalar types. MarshalGraphQL serializes
+// resolver values for the response; UnmarshalGraphQL coerces argument values
+// (literals and variables) before they reach resolvers.
 type Marshaler interface {
 	MarshalGraphQL(v interface{}) (interface{}, error)
 	UnmarshalGraphQL(v interface{}) (interface{}, error)
@@ -128,6 +156,8 @@ func NewSchema(schemaString string) (*Schema, error) {
 		inputTypeMap: make(map[string]*InputType),
 		enumMap:      make(map[string]*EnumType),
 		scalarMap:    make(map[string]*ScalarType),
+
+		possibleTypes: make(map[string][]string),
 	}
 
 	if err := s.buildTypeMap(); err != nil {
@@ -150,6 +180,8 @@ func NewSchemaFromSources(sources ...*ast.Source) (*Schema, error) {
 		inputTypeMap: make(map[string]*InputType),
 		enumMap:      make(map[string]*EnumType),
 		scalarMap:    make(map[string]*ScalarType),
+
+		possibleTypes: make(map[string][]string),
 	}
 
 	if err := s.buildTypeMap(); err != nil {
@@ -159,11 +191,14 @@ func NewSchemaFromSources(sources ...*ast.Source) (*Schema, error) {
 	return s, nil
 }
 
+// jsonPathKeyPattern matches one key of an @sql(jsonExtract: ...) path
+var jsonPathKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // buildTypeMap builds internal type maps from the parsed schema
 func (s *Schema) buildTypeMap() error {
 	for name, def := range s.schema.Types {
 		switch def.Kind {
-		case ast.Object:
+		case ast.Object, ast.Interface:
 			objType := &ObjectType{
 				Name:        name,
 				Description: def.Description,
@@ -171,6 +206,28 @@ func (s *Schema) buildTypeMap() error {
 				Implements:  def.Interfaces,
 				Directives:  convertDirectives(def.Directives),
 			}
+			if dir := def.Directives.ForName("sql"); dir != nil {
+				for _, arg := range dir.Arguments {
+					if arg.Value == nil {
+						continue
+					}
+					switch arg.Name {
+					case "readonly":
+						objType.SQLReadonly = arg.Value.Raw == "true"
+					case "table":
+						objType.SQLTable = arg.Value.Raw
+					case "schema":
+						objType.SQLSchema = arg.Value.Raw
+					case "primaryKey":
+						// Composite keys are comma-separated
+						for _, col := range strings.Split(arg.Value.Raw, ",") {
+							if col = strings.TrimSpace(col); col != "" {
+								objType.SQLPrimaryKey = append(objType.SQLPrimaryKey, col)
+							}
+						}
+					}
+				}
+			}
 
 			for _, field := range def.Fields {
 				objType.Fields[field.Name] = &FieldDefinition{
@@ -179,6 +236,8 @@ func (s *Schema) buildTypeMap() error {
 					Type:        convertTypeRef(field.Type),
 					Arguments:   convertArguments(field.Arguments),
 					Directives:  convertDirectives(field.Directives),
+					Filterable:  true,
+					Sortable:    true,
 				}
 
 				// Extract SQL mapping from directives
@@ -192,6 +251,26 @@ func (s *Schema) buildTypeMap() error {
 								objType.Fields[field.Name].SQLTable = arg.Value.Raw
 							case "relation":
 								objType.Fields[field.Name].SQLRelation = arg.Value.Raw
+							case "filterable":
+								objType.Fields[field.Name].Filterable = arg.Value.Raw != "false"
+							case "sortable":
+								objType.Fields[field.Name].Sortable = arg.Value.Raw != "false"
+							case "default":
+								objType.Fields[field.Name].SQLDefault = arg.Value.Raw
+							case "cast":
+								objType.Fields[field.Name].SQLCast = arg.Value.Raw
+							case "index":
+								objType.Fields[field.Name].SQLIndex = arg.Value.Raw
+							case "joinOn":
+								objType.Fields[field.Name].SQLJoinOn = arg.Value.Raw
+							case "jsonExtract":
+								path := strings.Split(arg.Value.Raw, ".")
+								for _, key := range path {
+									if !jsonPathKeyPattern.MatchString(key) {
+										return fmt.Errorf("field %s.%s: invalid jsonExtract path %q", name, field.Name, arg.Value.Raw)
+									}
+								}
+								objType.Fields[field.Name].SQLJSONPath = path
 							}
 						}
 					}
@@ -205,6 +284,7 @@ func (s *Schema) buildTypeMap() error {
 				Name:        name,
 				Description: def.Description,
 				Fields:      make(map[string]*InputFieldDefinition),
+				OneOf:       def.Directives.ForName("oneOf") != nil,
 			}
 
 			for _, field := range def.Fields {
@@ -241,10 +321,23 @@ func (s *Schema) buildTypeMap() error {
 			s.enumMap[name] = enumType
 
 		case ast.Scalar:
-			s.scalarMap[name] = &ScalarType{
+			scalarType := &ScalarType{
 				Name:        name,
 				Description: def.Description,
 			}
+			if url, ok := getSpecifiedByURL(def.Directives).(string); ok {
+				scalarType.SpecifiedByURL = url
+			}
+			s.scalarMap[name] = scalarType
+		}
+
+		if def.Kind == ast.Interface || def.Kind == ast.Union {
+			possible := s.schema.GetPossibleTypes(def)
+			names := make([]string, 0, len(possible))
+			for _, t := range possible {
+				names = append(names, t.Name)
+			}
+			s.possibleTypes[name] = names
 		}
 	}
 
@@ -253,9 +346,58 @@ func (s *Schema) buildTypeMap() error {
 
 // GetSchema returns the underlying gqlparser schema
 func (s *Schema) GetSchema() *ast.Schema {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.schema
 }
 
+// Reload replaces the schema with a newly parsed SDL. Registered scalar
+// marshalers and enum value mappings carry over by name. If the SDL is
+// invalid the current schema is kept and the parse error is returned.
+func (s *Schema) Reload(schemaString string) error {
+	next, err := NewSchema(schemaString)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, scalar := range s.scalarMap {
+		if scalar.Marshaler == nil {
+			continue
+		}
+		if n, ok := next.scalarMap[name]; ok {
+			n.Marshaler = scalar.Marshaler
+		} else {
+			next.scalarMap[name] = scalar
+		}
+	}
+
+	for name, enumType := range s.enumMap {
+		n, ok := next.enumMap[name]
+		if !ok {
+			continue
+		}
+		for i := range n.Values {
+			for _, old := range enumType.Values {
+				if old.Name == n.Values[i].Name {
+					n.Values[i].Value = old.Value
+				}
+			}
+		}
+	}
+
+	s.schema = next.schema
+	s.source = next.source
+	s.typeMap = next.typeMap
+	s.inputTypeMap = next.inputTypeMap
+	s.enumMap = next.enumMap
+	s.scalarMap = next.scalarMap
+	s.possibleTypes = next.possibleTypes
+	return nil
+}
+
 // GetType returns an object type by name
 func (s *Schema) GetType(name string) (*ObjectType, bool) {
 	s.mu.RLock()
@@ -272,6 +414,59 @@ func (s *Schema) GetInputType(name string) (*InputType, bool) {
 	return t, ok
 }
 
+// ValidateOneOf checks that every @oneOf input object within value (including
+// nested inputs and list items) has exactly one non-null field
+func (s *Schema) ValidateOneOf(typeRef *TypeRef, value interface{}) error {
+	if typeRef == nil || value == nil {
+		return nil
+	}
+
+	if typeRef.IsList {
+		items, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one
+			return s.ValidateOneOf(typeRef.ListElem, value)
+		}
+		for _, item := range items {
+			if err := s.ValidateOneOf(typeRef.ListElem, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	inputType, ok := s.GetInputType(typeRef.Name)
+	if !ok {
+		return nil
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if inputType.OneOf {
+		given := 0
+		for name, v := range fields {
+			if v == nil {
+				return fmt.Errorf("oneOf input %s: field %q must not be null", inputType.Name, name)
+			}
+			given++
+		}
+		if given != 1 {
+			return fmt.Errorf("oneOf input %s must have exactly one field, got %d", inputType.Name, given)
+		}
+	}
+
+	for name, v := range fields {
+		if field, ok := inputType.Fields[name]; ok {
+			if err := s.ValidateOneOf(field.Type, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // GetEnum returns an enum type by name
 func (s *Schema) GetEnum(name string) (*EnumType, bool) {
 	s.mu.RLock()
@@ -280,6 +475,62 @@ func (s *Schema) GetEnum(name string) (*EnumType, bool) {
 	return t, ok
 }
 
+// SetEnumValue sets the internal value an enum value is stored as (defaults to its name)
+func (s *Schema) SetEnumValue(enumName, name string, value interface{}) error {
+	if value == nil || !reflect.TypeOf(value).Comparable() {
+		return fmt.Errorf("enum %s.%s: internal value must be a non-nil comparable value", enumName, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enumType, ok := s.enumMap[enumName]
+	if !ok {
+		return fmt.Errorf("unknown enum %s", enumName)
+	}
+	for i := range enumType.Values {
+		if enumType.Values[i].Name == name {
+			enumType.Values[i].Value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("enum %s has no value %s", enumName, name)
+}
+
+// EnumInternalValue returns the internal value of an enum value by name
+func (s *Schema) EnumInternalValue(enumName, name string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if enumType, ok := s.enumMap[enumName]; ok {
+		for _, v := range enumType.Values {
+			if v.Name == name {
+				return v.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// EnumValueName returns the name of the enum value with the given internal value
+func (s *Schema) EnumValueName(enumName string, value interface{}) (string, bool) {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if enumType, ok := s.enumMap[enumName]; ok {
+		for _, v := range enumType.Values {
+			if v.Value == value {
+				return v.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
 // GetScalar returns a scalar type by name
 func (s *Schema) GetScalar(name string) (*ScalarType, bool) {
 	s.mu.RLock()
@@ -288,6 +539,160 @@ func (s *Schema) GetScalar(name string) (*ScalarType, bool) {
 	return t, ok
 }
 
+// UnmarshalInput coerces an input value through the UnmarshalGraphQL of the
+// custom scalars it contains, including list items and input object fields.
+// Maps and slices are copied rather than modified.
+func (s *Schema) UnmarshalInput(typeRef *TypeRef, value interface{}) (interface{}, error) {
+	if typeRef == nil || value == nil {
+		return value, nil
+	}
+
+	if typeRef.IsList {
+		items, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced to a list of one
+			v, err := s.UnmarshalInput(typeRef.ListElem, value)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{v}, nil
+		}
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := s.UnmarshalInput(typeRef.ListElem, item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	}
+
+	if scalar, ok := s.GetScalar(typeRef.Name); ok {
+		if scalar.Marshaler == nil {
+			return value, nil
+		}
+		v, err := scalar.Marshaler.UnmarshalGraphQL(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", typeRef.Name, err)
+		}
+		return v, nil
+	}
+
+	inputType, ok := s.GetInputType(typeRef.Name)
+	if !ok {
+		return value, nil
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+
+	result := make(map[string]interface{}, len(fields))
+	for name, v := range fields {
+		if field, ok := inputType.Fields[name]; ok {
+			coerced, err := s.UnmarshalInput(field.Type, v)
+			if err != nil {
+				return nil, err
+			}
+			v = coerced
+		}
+		result[name] = v
+	}
+	return result, nil
+}
+
+// FieldType returns the declared type of a field
+func (s *Schema) FieldType(typeName, fieldName string) (*TypeRef, bool) {
+	objType, ok := s.GetType(typeName)
+	if !ok {
+		return nil, false
+	}
+
+	field, ok := objType.Fields[fieldName]
+	if !ok || field.Type == nil {
+		return nil, false
+	}
+
+	return field.Type, true
+}
+
+// FieldArgument returns the definition of an argument declared on a field
+func (s *Schema) FieldArgument(typeName, fieldName, argName string) (*ArgumentDefinition, bool) {
+	objType, ok := s.GetType(typeName)
+	if !ok {
+		return nil, false
+	}
+
+	field, ok := objType.Fields[fieldName]
+	if !ok {
+		return nil, false
+	}
+
+	arg, ok := field.Arguments[argName]
+	return arg, ok
+}
+
+// FieldBaseTypeName returns the named type of a field with list and non-null
+// wrappers removed (e.g., User for [User!]!)
+func (s *Schema) FieldBaseTypeName(typeName, fieldName string) string {
+	fieldType, _ := s.FieldType(typeName, fieldName)
+	return unwrapTypeName(fieldType)
+}
+
+// unwrapTypeName unwraps a TypeRef to get the underlying type name
+func unwrapTypeName(t *TypeRef) string {
+	if t == nil {
+		return ""
+	}
+
+	if t.IsList {
+		return unwrapTypeName(t.ListElem)
+	}
+
+	return t.Name
+}
+
+// IsLeafType reports whether the named type is a scalar or enum
+func (s *Schema) IsLeafType(name string) bool {
+	def, ok := s.GetSchema().Types[name]
+	if !ok {
+		return false
+	}
+	return def.Kind == ast.Scalar || def.Kind == ast.Enum
+}
+
+// IsAbstractType reports whether the named type is an interface or union
+func (s *Schema) IsAbstractType(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.possibleTypes[name]
+	return ok
+}
+
+// PossibleTypes returns the object types implementing an interface or
+// belonging to a union; it is empty for other types
+func (s *Schema) PossibleTypes(abstractName string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.possibleTypes[abstractName]...)
+}
+
+// isPossibleType reports whether typeName is abstractName itself or one of its possible object types
+func (s *Schema) isPossibleType(abstractName, typeName string) bool {
+	if abstractName == typeName {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, possible := range s.possibleTypes[abstractName] {
+		if possible == typeName {
+			return true
+		}
+	}
+	return false
+}
+
 // RegisterScalar registers a custom scalar type with a marshaler
 func (s *Schema) RegisterScalar(name string, marshaler Marshaler) {
 	s.mu.Lock()
@@ -304,26 +709,29 @@ func (s *Schema) RegisterScalar(name string, marshaler Marshaler) {
 
 // QueryType returns the Query type if defined
 func (s *Schema) QueryType() (*ObjectType, bool) {
-	if s.schema.Query == nil {
+	schema := s.GetSchema()
+	if schema.Query == nil {
 		return nil, false
 	}
-	return s.GetType(s.schema.Query.Name)
+	return s.GetType(schema.Query.Name)
 }
 
 // MutationType returns the Mutation type if defined
 func (s *Schema) MutationType() (*ObjectType, bool) {
-	if s.schema.Mutation == nil {
+	schema := s.GetSchema()
+	if schema.Mutation == nil {
 		return nil, false
 	}
-	return s.GetType(s.schema.Mutation.Name)
+	return s.GetType(schema.Mutation.Name)
 }
 
 // SubscriptionType returns the Subscription type if defined
 func (s *Schema) SubscriptionType() (*ObjectType, bool) {
-	if s.schema.Subscription == nil {
+	schema := s.GetSchema()
+	if schema.Subscription == nil {
 		return nil, false
 	}
-	return s.GetType(s.schema.Subscription.Name)
+	return s.GetType(schema.Subscription.Name)
 }
 
 // convertTypeRef converts gqlparser type to our TypeRef
@@ -367,6 +775,21 @@ func convertArguments(args ast.ArgumentDefinitionList) map[string]*ArgumentDefin
 	return result
 }
 
+// Directive returns the first directive with the given name applied to the field
+func (f *FieldDefinition) Directive(name string) (*Directive, bool) {
+	return findDirective(f.Directives, name)
+}
+
+// findDirective looks up a directive by name
+func findDirective(dirs []*Directive, name string) (*Directive, bool) {
+	for _, d := range dirs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
 // convertDirectives converts gqlparser directives to our Directive slice
 func convertDirectives(dirs ast.DirectiveList) []*Directive {
 	result := make([]*Directive, 0, len(dirs))
@@ -378,7 +801,16 @@ func convertDirectives(dirs ast.DirectiveList) []*Directive {
 		}
 
 		for _, arg := range dir.Arguments {
-			d.Arguments[arg.Name] = arg.Value.Raw
+			// Scalars keep their raw text; lists and objects have no raw
+			// form and are converted to []interface{} / map[string]interface{}
+			switch arg.Value.Kind {
+			case ast.ListValue, ast.ObjectValue:
+				if v, err := arg.Value.Value(nil); err == nil {
+					d.Arguments[arg.Name] = v
+				}
+			default:
+				d.Arguments[arg.Name] = arg.Value.Raw
+			}
 		}
 
 		result = append(result, d)
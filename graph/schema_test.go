@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// upperMarshaler upper-cases string input values
+type upperMarshaler struct{}
+
+func (upperMarshaler) MarshalGraphQL(v interface{}) (interface{}, error) { return v, nil }
+
+func (upperMarshaler) UnmarshalGraphQL(v interface{}) (interface{}, error) {
+	return strings.ToUpper(v.(string)), nil
+}
+
+func TestUnmarshalInputCoercesSingleValueToList(t *testing.T) {
+	schema, err := NewSchema(`scalar Code type Query { find(codes: [Code]): Boolean }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema.RegisterScalar("Code", upperMarshaler{})
+	listType := &TypeRef{IsList: true, ListElem: &TypeRef{Name: "Code"}}
+
+	got, err := schema.UnmarshalInput(listType, "ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"AB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("single value: got %#v, want %#v", got, want)
+	}
+
+	got, err = schema.UnmarshalInput(listType, []interface{}{"ab", "cd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"AB", "CD"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list: got %#v, want %#v", got, want)
+	}
+}
+
+func TestFieldType(t *testing.T) {
+	schema, err := NewSchema(`type Query { users: [User!]! user: User nested: [[User]] } type User { id: ID! }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, ok := schema.FieldType("Query", "users")
+	if !ok {
+		t.Fatal("Query.users not found")
+	}
+	if !ref.IsList || !ref.NonNull || ref.ListElem == nil || ref.ListElem.Name != "User" || !ref.ListElem.NonNull {
+		t.Errorf("Query.users: got %+v", ref)
+	}
+
+	for _, field := range []string{"users", "user", "nested"} {
+		if got := schema.FieldBaseTypeName("Query", field); got != "User" {
+			t.Errorf("FieldBaseTypeName(Query.%s) = %q, want User", field, got)
+		}
+	}
+
+	if _, ok := schema.FieldType("Query", "missing"); ok {
+		t.Error("unknown field was found")
+	}
+	if _, ok := schema.FieldType("Missing", "users"); ok {
+		t.Error("field of an unknown type was found")
+	}
+	if got := schema.FieldBaseTypeName("Query", "missing"); got != "" {
+		t.Errorf("FieldBaseTypeName of an unknown field = %q", got)
+	}
+}
+
+func TestDirectiveLookup(t *testing.T) {
+	schema, err := NewSchema(`
+directive @auth(requires: String, scopes: [String], limits: RateLimit) on OBJECT | FIELD_DEFINITION
+directive @cache(maxAge: Int) on FIELD_DEFINITION
+input RateLimit { max: Int window: String }
+type Query { secrets: [Secret] @auth(requires: "ADMIN", scopes: ["read", "audit"], limits: {max: 10, window: "1m"}) }
+type Secret @auth(requires: "USER") { value: String @cache(maxAge: 60) }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, _ := schema.GetType("Query")
+	auth, ok := query.Fields["secrets"].Directive("auth")
+	if !ok {
+		t.Fatal("@auth not found on Query.secrets")
+	}
+	if auth.Arguments["requires"] != "ADMIN" {
+		t.Errorf("requires = %#v", auth.Arguments["requires"])
+	}
+	if scopes, ok := auth.Arguments["scopes"].([]interface{}); !ok || len(scopes) != 2 || scopes[0] != "read" || scopes[1] != "audit" {
+		t.Errorf("scopes = %#v", auth.Arguments["scopes"])
+	}
+	if limits, ok := auth.Arguments["limits"].(map[string]interface{}); !ok || limits["window"] != "1m" {
+		t.Errorf("limits = %#v", auth.Arguments["limits"])
+	}
+	if _, ok := query.Fields["secrets"].Directive("cache"); ok {
+		t.Error("found a directive the field doesn't have")
+	}
+
+	secret, _ := schema.GetType("Secret")
+	if d, ok := secret.Directive("auth"); !ok || d.Arguments["requires"] != "USER" {
+		t.Errorf("type-level @auth = %+v, %v", d, ok)
+	}
+	if d, ok := secret.Fields["value"].Directive("cache"); !ok || d.Arguments["maxAge"] != "60" {
+		t.Errorf("@cache = %+v, %v", d, ok)
+	}
+}
+
+func TestPossibleTypes(t *testing.T) {
+	schema, err := NewSchema(`
+type Query { node: Node search: SearchResult }
+interface Node { id: ID! }
+type User implements Node { id: ID! }
+type Post implements Node { id: ID! }
+type Comment { id: ID! }
+union SearchResult = Post | Comment
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		abstract bool
+		possible []string
+	}{
+		{"Node", true, []string{"User", "Post"}},
+		{"SearchResult", true, []string{"Post", "Comment"}},
+		{"User", false, nil},
+		{"Missing", false, nil},
+	}
+	for _, tt := range tests {
+		if got := schema.IsAbstractType(tt.name); got != tt.abstract {
+			t.Errorf("IsAbstractType(%s) = %v", tt.name, got)
+		}
+		if got := schema.PossibleTypes(tt.name); !reflect.DeepEqual(got, tt.possible) {
+			t.Errorf("PossibleTypes(%s) = %v, want %v", tt.name, got, tt.possible)
+		}
+	}
+}
+
+func TestFieldArgument(t *testing.T) {
+	schema, err := NewSchema(`
+enum Sort { NEWEST OLDEST }
+type Query { posts(limit: Int = 10, sort: Sort = NEWEST, author: ID!): [String] }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit, ok := schema.FieldArgument("Query", "posts", "limit")
+	if !ok {
+		t.Fatal("limit argument not found")
+	}
+	if limit.DefaultValue != "10" || limit.Type.Name != "Int" || limit.Type.NonNull {
+		t.Errorf("limit = %+v, type %+v", limit, limit.Type)
+	}
+	if sort, _ := schema.FieldArgument("Query", "posts", "sort"); sort == nil || sort.DefaultValue != "NEWEST" {
+		t.Errorf("sort = %+v", sort)
+	}
+	if author, _ := schema.FieldArgument("Query", "posts", "author"); author == nil || author.DefaultValue != nil || !author.Type.NonNull {
+		t.Errorf("author = %+v", author)
+	}
+
+	for _, path := range [][3]string{{"Query", "posts", "offset"}, {"Query", "users", "limit"}, {"Missing", "posts", "limit"}} {
+		if _, ok := schema.FieldArgument(path[0], path[1], path[2]); ok {
+			t.Errorf("FieldArgument%v found an undeclared argument", path)
+		}
+	}
+}
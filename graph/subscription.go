@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Notification is a NOTIFY received on a LISTENed channel
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Notifier is the LISTEN side of a PostgreSQL connection, e.g., a small
+// wrapper over pq.Listener or a dedicated pgx connection. It must be safe
+// for Listen and Unlisten to be called while WaitForNotification blocks.
+type Notifier interface {
+	Listen(ctx context.Context, channel string) error
+	Unlisten(ctx context.Context, channel string) error
+	WaitForNotification(ctx context.Context) (*Notification, error)
+}
+
+// PGListener fans the notifications of one Notifier out to subscribers,
+// LISTENing on a channel while it has at least one subscriber
+type PGListener struct {
+	notifier Notifier
+
+	mu   sync.Mutex
+	subs map[string]map[chan string]struct{} // channel -> subscribers
+}
+
+// NewPGListener creates a listener over notifier; call Run to deliver
+// notifications
+func NewPGListener(notifier Notifier) *PGListener {
+	return &PGListener{
+		notifier: notifier,
+		subs:     make(map[string]map[chan string]struct{}),
+	}
+}
+
+// Run delivers notifications to subscribers until ctx is cancelled or the
+// notifier fails
+func (l *PGListener) Run(ctx context.Context) error {
+	for {
+		n, err := l.notifier.WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if n == nil {
+			continue
+		}
+
+		l.mu.Lock()
+		for sub := range l.subs[n.Channel] {
+			// A subscriber that is behind already has a notification
+			// pending; every notification triggers the same re-query, so
+			// the extra one is dropped rather than blocking the loop
+			select {
+			case sub <- n.Payload:
+			default:
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Subscribe returns the payloads of notifications on channel until ctx is
+// cancelled, when the returned channel is closed
+func (l *PGListener) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	sub := make(chan string, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.subs[channel]) == 0 {
+		if err := l.notifier.Listen(ctx, channel); err != nil {
+			return nil, fmt.Errorf("listen %s: %w", channel, err)
+		}
+		l.subs[channel] = make(map[chan string]struct{})
+	}
+	l.subs[channel][sub] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subs[channel], sub)
+		close(sub)
+		if len(l.subs[channel]) == 0 {
+			delete(l.subs, channel)
+			l.notifier.Unlisten(context.Background(), channel)
+		}
+	}()
+	return sub, nil
+}
+
+// SubscriptionSelectFunc builds the query a subscription runs for a
+// notification; payload is the NOTIFY payload
+type SubscriptionSelectFunc func(ctx context.Context, info *ResolveInfo, payload string) (*SQLSelectResult, error)
+
+// subscriptionConfig is the LISTEN channel and query of a subscription field
+type subscriptionConfig struct {
+	channel string
+	query   SubscriptionSelectFunc
+}
+
+// SubscriptionEvent is one emission of a SQL-backed subscription
+type SubscriptionEvent struct {
+	Payload string                   // NOTIFY payload that triggered the event
+	Rows    []map[string]interface{} // Selected rows keyed by field name
+	Err     error                    // Set when building or running the query failed
+}
+
+// ConfigureSubscription backs a subscription field with LISTEN/NOTIFY: each
+// notification on channel runs the SELECT built by query and emits its rows.
+// A nil query converts the subscription's selection like a query field.
+func (c *SQLConverter) ConfigureSubscription(typeName, fieldName, channel string, query SubscriptionSelectFunc) {
+	c.subscriptions[typeName+"."+fieldName] = &subscriptionConfig{channel: channel, query: query}
+}
+
+// Subscribe starts the subscription field being resolved by info, returning
+// an event for each notification on its channel until ctx is cancelled.
+// Queries run on db; listener must be running (see PGListener.Run).
+func (c *SQLConverter) Subscribe(ctx context.Context, db DB, listener *PGListener, info *ResolveInfo) (<-chan *SubscriptionEvent, error) {
+	cfg, ok := c.subscriptions[info.ParentType+"."+info.FieldName]
+	if !ok {
+		return nil, fmt.Errorf("subscription %s.%s is not configured", info.ParentType, info.FieldName)
+	}
+	query := cfg.query
+	if query == nil {
+		query = func(ctx context.Context, info *ResolveInfo, payload string) (*SQLSelectResult, error) {
+			return c.ConvertToSelect(ctx, info)
+		}
+	}
+
+	payloads, err := listener.Subscribe(ctx, cfg.channel)
+	if err != nil {
+		return nil, err
+	}
+
+	typeName := unwrapTypeName(info.ReturnType)
+	events := make(chan *SubscriptionEvent)
+	go func() {
+		defer close(events)
+		for payload := range payloads {
+			event := &SubscriptionEvent{Payload: payload}
+			if result, err := query(ctx, info, payload); err != nil {
+				event.Err = err
+			} else {
+				event.Rows, event.Err = c.queryRows(ctx, db, typeName, result)
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// queryRows runs a converted SELECT and scans its rows for typeName into
+// the response shape of its column mapping
+func (c *SQLConverter) queryRows(ctx context.Context, db DB, typeName string, result *SQLSelectResult) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, result.Query, result.Params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return c.ScanSelect(rows, typeName, result)
+}
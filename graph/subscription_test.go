@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubNotifier delivers the notifications sent on its channel
+type stubNotifier struct {
+	notifications chan *Notification
+
+	mu        sync.Mutex
+	listening map[string]bool
+}
+
+func newStubNotifier() *stubNotifier {
+	return &stubNotifier{notifications: make(chan *Notification), listening: make(map[string]bool)}
+}
+
+func (n *stubNotifier) Listen(ctx context.Context, channel string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listening[channel] = true
+	return nil
+}
+
+func (n *stubNotifier) Unlisten(ctx context.Context, channel string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.listening, channel)
+	return nil
+}
+
+func (n *stubNotifier) isListening(channel string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.listening[channel]
+}
+
+func (n *stubNotifier) WaitForNotification(ctx context.Context) (*Notification, error) {
+	select {
+	case note := <-n.notifications:
+		return note, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestSQLSubscription(t *testing.T) {
+	c := newTestConverter(t, testSchema+`type Subscription { userChanged: [User] }`)
+	c.MapTypeToTable("User", "users")
+	db := &fakeDB{columns: []string{"full_name"}, rows: [][]driver.Value{{"Ann"}}}
+
+	var payloads []string
+	c.ConfigureSubscription("Subscription", "userChanged", "users_changed", func(ctx context.Context, info *ResolveInfo, payload string) (*SQLSelectResult, error) {
+		payloads = append(payloads, payload)
+		return c.ConvertToSelect(ctx, info)
+	})
+
+	notifier := newStubNotifier()
+	listener := NewPGListener(notifier)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.Run(ctx)
+
+	info := listInfo("userChanged", "User", nil, &SelectedField{Name: "fullName"})
+	info.ParentType = "Subscription"
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	events, err := c.Subscribe(subCtx, db.open(t), listener, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notifier.isListening("users_changed") {
+		t.Fatal("subscribing did not LISTEN on the channel")
+	}
+
+	for _, payload := range []string{"1", "2"} {
+		notifier.notifications <- &Notification{Channel: "users_changed", Payload: payload}
+		select {
+		case event := <-events:
+			if event.Err != nil {
+				t.Fatal(event.Err)
+			}
+			if event.Payload != payload || !reflect.DeepEqual(event.Rows, []map[string]interface{}{{"fullName": "Ann"}}) {
+				t.Errorf("event = %+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no event for notification %s", payload)
+		}
+	}
+	if !reflect.DeepEqual(payloads, []string{"1", "2"}) || len(db.queries) != 2 {
+		t.Errorf("payloads %v, %d queries", payloads, len(db.queries))
+	}
+
+	unsubscribe()
+	deadline := time.Now().Add(time.Second)
+	for notifier.isListening("users_changed") {
+		if time.Now().After(deadline) {
+			t.Fatal("the last unsubscribe did not UNLISTEN")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, open := <-events; open {
+		t.Error("events were not closed after unsubscribing")
+	}
+
+	info.FieldName = "postAdded"
+	if _, err := c.Subscribe(ctx, db.open(t), listener, info); err == nil {
+		t.Error("an unconfigured subscription was started")
+	}
+}
+
+func TestSQLSubscriptionJoins(t *testing.T) {
+	c := tenantConverter(t)
+	c.ConfigureSubscription("Subscription", "userChanged", "users_changed", nil)
+	db := &fakeDB{
+		columns: []string{"full_name", "p_pos_title", "__key_id"},
+		rows: [][]driver.Value{
+			{"Ann", "first", int64(1)},
+			{"Ann", "second", int64(1)},
+			{"Bob", nil, int64(2)},
+		},
+	}
+
+	notifier := newStubNotifier()
+	listener := NewPGListener(notifier)
+	ctx, cancel := context.WithCancel(WithTenant(context.Background(), "t1"))
+	defer cancel()
+	go listener.Run(ctx)
+
+	info := listInfo("userChanged", "User", nil, &SelectedField{Name: "fullName"},
+		&SelectedField{Name: "posts", Selections: &SelectionSet{Fields: []*SelectedField{{Name: "title"}}}})
+	info.ParentType = "Subscription"
+	events, err := c.Subscribe(ctx, db.open(t), listener, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier.notifications <- &Notification{Channel: "users_changed", Payload: "1"}
+	select {
+	case event := <-events:
+		if event.Err != nil {
+			t.Fatal(event.Err)
+		}
+		want := []map[string]interface{}{
+			{"fullName": "Ann", "posts": []interface{}{
+				map[string]interface{}{"title": "first"},
+				map[string]interface{}{"title": "second"},
+			}},
+			{"fullName": "Bob", "posts": []interface{}{}},
+		}
+		if !reflect.DeepEqual(event.Rows, want) {
+			t.Errorf("rows = %#v\nwant %#v", event.Rows, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event for the notification")
+	}
+}
@@ -2,6 +2,13 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/eddieafk/goinmonster/graph"
@@ -113,6 +120,30 @@ func (t *Tracing) ExtensionData(ctx context.Context) map[string]interface{} {
 	}
 }
 
+// RequestID extension adds the request ID to the response extensions
+type RequestID struct{}
+
+// NewRequestID creates a new request ID extension
+func NewRequestID() *RequestID {
+	return &RequestID{}
+}
+
+// ExtensionName returns the extension name
+func (r *RequestID) ExtensionName() string {
+	return "requestId"
+}
+
+// ExtensionData returns the request ID
+func (r *RequestID) ExtensionData(ctx context.Context) map[string]interface{} {
+	id := graph.GetRequestID(ctx)
+	if id == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"requestId": id,
+	}
+}
+
 // ComplexityLimit extension for query complexity limiting
 type ComplexityLimit struct {
 	limit          int
@@ -367,6 +398,154 @@ func (r *RequestLogger) InterceptResponse(ctx context.Context, response *graph.R
 	return response
 }
 
+// DefaultRedactKeys are the variable keys masked by StructuredLogger when
+// StructuredLoggerOptions.RedactKeys is nil
+var DefaultRedactKeys = []string{"password", "secret", "token"}
+
+// StructuredLoggerOptions configures a StructuredLogger
+type StructuredLoggerOptions struct {
+	Writer             io.Writer // Destination of log lines; defaults to os.Stderr
+	RedactKeys         []string  // Variable keys masked at any depth, case-insensitively
+	VariableSampleRate float64   // Fraction of requests (0-1) logged with their variables
+	MaxVariables       int       // Top-level variables logged per request; 0 means all
+}
+
+// StructuredLogger extension writes one JSON line per request
+type StructuredLogger struct {
+	mu                 sync.Mutex
+	writer             io.Writer
+	redactKeys         map[string]bool
+	variableSampleRate float64
+	maxVariables       int
+}
+
+// structuredLogEntry is the shape of a StructuredLogger line
+type structuredLogEntry struct {
+	Time          string                 `json:"time"`
+	RequestID     string                 `json:"requestId,omitempty"`
+	OperationName string                 `json:"operationName"`
+	DurationMs    float64                `json:"durationMs"`
+	ErrorCount    int                    `json:"errorCount"`
+	Complexity    int                    `json:"complexity"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// redactedValue replaces the value of redacted variables
+const redactedValue = "[REDACTED]"
+
+// NewStructuredLogger creates a structured request logger
+func NewStructuredLogger(opts StructuredLoggerOptions) *StructuredLogger {
+	writer := opts.Writer
+	if writer == nil {
+		writer = os.Stderr
+	}
+	keys := opts.RedactKeys
+	if keys == nil {
+		keys = DefaultRedactKeys
+	}
+	redactKeys := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		redactKeys[strings.ToLower(key)] = true
+	}
+
+	return &StructuredLogger{
+		writer:             writer,
+		redactKeys:         redactKeys,
+		variableSampleRate: opts.VariableSampleRate,
+		maxVariables:       opts.MaxVariables,
+	}
+}
+
+// ExtensionName returns the extension name
+func (l *StructuredLogger) ExtensionName() string {
+	return "structuredLogger"
+}
+
+// InterceptOperation records the request start time
+func (l *StructuredLogger) InterceptOperation(ctx context.Context, next func(ctx context.Context) *graph.Response) context.Context {
+	if rc := graph.GetRequestContext(ctx); rc != nil {
+		rc.Set("structuredLogger:start", time.Now())
+	}
+	return ctx
+}
+
+// InterceptResponse writes the log line for the completed request
+func (l *StructuredLogger) InterceptResponse(ctx context.Context, response *graph.Response) *graph.Response {
+	rc := graph.GetRequestContext(ctx)
+	if rc == nil {
+		return response
+	}
+
+	entry := structuredLogEntry{
+		Time:          time.Now().UTC().Format(time.RFC3339Nano),
+		RequestID:     rc.RequestID,
+		OperationName: rc.OperationName,
+		Complexity:    rc.GetInt("complexity"),
+	}
+	if startTime, ok := rc.Get("structuredLogger:start"); ok {
+		entry.DurationMs = float64(time.Since(startTime.(time.Time)).Microseconds()) / 1000
+	}
+	if response != nil {
+		entry.ErrorCount = len(response.Errors)
+	}
+	if len(rc.Variables) > 0 && l.variableSampleRate > 0 && rand.Float64() < l.variableSampleRate {
+		entry.Variables = l.sampleVariables(rc.Variables)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return response
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writer.Write(append(line, '\n'))
+
+	return response
+}
+
+// sampleVariables returns the redacted variables to log, keeping the first
+// maxVariables names in sorted order
+func (l *StructuredLogger) sampleVariables(variables map[string]interface{}) map[string]interface{} {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if l.maxVariables > 0 && len(names) > l.maxVariables {
+		names = names[:l.maxVariables]
+	}
+
+	result := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		result[name] = l.redact(name, variables[name])
+	}
+	return result
+}
+
+// redact masks the value of a redacted key, walking nested maps and lists
+func (l *StructuredLogger) redact(key string, value interface{}) interface{} {
+	if l.redactKeys[strings.ToLower(key)] {
+		return redactedValue
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = l.redact(k, item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = l.redact("", item)
+		}
+		return result
+	}
+	return value
+}
+
 // FixedComplexity extension that sets fixed complexity values
 type FixedComplexity struct {
 	costs map[string]int
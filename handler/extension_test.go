@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStructuredLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := newOKServer(t)
+	s.Use(NewStructuredLogger(StructuredLoggerOptions{Writer: &buf, VariableSampleRate: 1}))
+
+	post(t, s, "/graphql", `{"query":"query Check { ok }","operationName":"Check",`+
+		`"variables":{"token":"abc","input":{"name":"ann","Password":"hunter2","items":[{"secret":"x","n":1}]}}}`)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", buf.String(), err)
+	}
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	want := []string{"complexity", "durationMs", "errorCount", "operationName", "requestId", "time", "variables"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("log keys = %v, want %v", keys, want)
+	}
+	if entry["operationName"] != "Check" || entry["errorCount"] != float64(0) {
+		t.Errorf("operationName = %v, errorCount = %v", entry["operationName"], entry["errorCount"])
+	}
+
+	wantVars := map[string]interface{}{
+		"token": "[REDACTED]",
+		"input": map[string]interface{}{
+			"name":     "ann",
+			"Password": "[REDACTED]",
+			"items":    []interface{}{map[string]interface{}{"secret": "[REDACTED]", "n": float64(1)}},
+		},
+	}
+	if !reflect.DeepEqual(entry["variables"], wantVars) {
+		t.Errorf("variables = %v, want %v", entry["variables"], wantVars)
+	}
+}
+
+func TestStructuredLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	s := newOKServer(t)
+	s.Use(NewStructuredLogger(StructuredLoggerOptions{Writer: &buf}))
+
+	post(t, s, "/graphql", `{"query":"{ ok }","variables":{"token":"abc"}}`)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["variables"]; ok {
+		t.Errorf("variables were logged with a zero sample rate: %s", buf.String())
+	}
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Handler returns the server wrapped in the given middlewares. The first
+// middleware is the outermost one and sees the request first.
+func (s *Server) Handler(middlewares ...func(http.Handler) http.Handler) http.Handler {
+	var h http.Handler = s
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// CORSConfig configures the CORS middleware
+type CORSConfig struct {
+	AllowedOrigins []string // "*" allows any origin
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// AllowCredentials is only sent to origins listed by name; origins
+	// allowed through "*" never get credentialed access
+	AllowCredentials bool
+	MaxAge           int // Preflight cache duration in seconds (0 omits the header)
+}
+
+// DefaultCORSConfig returns a permissive CORS configuration
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", RequestIDHeader},
+	}
+}
+
+// CORSMiddleware adds CORS headers and answers preflight requests. Other
+// OPTIONS requests are passed on to the next handler.
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		allowed[origin] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			switch {
+			case allowAll && !cfg.AllowCredentials:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case origin != "" && allowed[origin]:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+				if cfg.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			case allowAll:
+				// Echoing any origin with credentials would let every site
+				// make credentialed requests
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if methods != "" {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+			}
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if cfg.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+				}
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GzipMiddleware compresses responses for clients that accept gzip.
+// Event streams and protocol upgrades (WebSocket) are passed through, and
+// responses without a body (1xx, 204, 304) are left uncompressed.
+func GzipMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") ||
+				strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+				r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			gw := &gzipResponseWriter{ResponseWriter: w}
+			defer gw.close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter writes the response body through a gzip writer
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer      *gzip.Writer // nil until a response with a body starts
+	wroteHeader bool
+}
+
+// WriteHeader starts compression unless the status has no body, dropping the
+// Content-Length set for the uncompressed body
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	if bodyAllowed(status) {
+		g.Header().Del("Content-Length")
+		g.Header().Set("Content-Encoding", "gzip")
+		g.writer = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+// Write compresses the body
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.writer == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.writer.Write(b)
+}
+
+// Flush flushes compressed data to the client
+func (g *gzipResponseWriter) Flush() {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.writer != nil {
+		g.writer.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g., for a WebSocket upgrade
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := g.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// close writes the gzip footer of a compressed body
+func (g *gzipResponseWriter) close() error {
+	if g.writer == nil {
+		return nil
+	}
+	return g.writer.Close()
+}
+
+// bodyAllowed reports whether a response with status may have a body
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// RequestIDMiddleware ensures every request carries an X-Request-ID header,
+// generating one when the client didn't send a usable ID, and echoes it in the
+// response. The server picks the same ID up for its request context.
+func RequestIDMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := requestIDFromHeader(r)
+			r.Header.Set(RequestIDHeader, id)
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
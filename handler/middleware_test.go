@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name+" in")
+				next.ServeHTTP(w, r)
+				order = append(order, name+" out")
+			})
+		}
+	}
+
+	h := newOKServer(t).Handler(trace("first"), trace("second"))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(rec, req)
+
+	if got, want := strings.Join(order, ", "), "first in, second in, second out, first out"; got != want {
+		t.Errorf("order = %s, want %s", got, want)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `{"data":{"ok":true}}`) {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestBuiltinMiddlewares(t *testing.T) {
+	cors := DefaultCORSConfig()
+	cors.AllowedOrigins = []string{"https://app.example"}
+	h := newOKServer(t).Handler(CORSMiddleware(cors), RequestIDMiddleware(), GzipMiddleware())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/graphql", nil)
+	req.Header.Set("Origin", "https://app.example")
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("preflight Allow-Origin = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://evil.example")
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Allow-Origin %q", got)
+	}
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Error("response has no request ID")
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `{"data":{"ok":true}}`) {
+		t.Errorf("decompressed body = %q", body)
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	cfg := DefaultCORSConfig()
+	cfg.AllowedOrigins = []string{"*", "https://app.example"}
+	cfg.AllowCredentials = true
+	var reachedNext bool
+	h := CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reachedNext = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://app.example", "https://app.example", "true"},
+		{"https://evil.example", "*", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/graphql", nil)
+		req.Header.Set("Origin", tt.origin)
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("%s: Allow-Origin = %q, want %q", tt.origin, got, tt.allowOrigin)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+			t.Errorf("%s: Allow-Credentials = %q, want %q", tt.origin, got, tt.credentials)
+		}
+	}
+
+	// Only preflights are answered by the middleware
+	reachedNext = false
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/graphql", nil)
+	req.Header.Set("Origin", "https://app.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	h.ServeHTTP(rec, req)
+	if reachedNext || rec.Code != http.StatusOK {
+		t.Errorf("preflight: reached next = %v, status %d", reachedNext, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("OPTIONS", "/graphql", nil))
+	if !reachedNext || rec.Code != http.StatusNoContent {
+		t.Errorf("plain OPTIONS: reached next = %v, status %d", reachedNext, rec.Code)
+	}
+}
+
+// hijackRecorder is a ResponseRecorder whose connection can be hijacked
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestGzipBodilessAndHijack(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified, http.StatusSwitchingProtocols} {
+		h := GzipMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(rec, req)
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+			t.Errorf("%d: Content-Encoding %q, %d body bytes", status, rec.Header().Get("Content-Encoding"), rec.Body.Len())
+		}
+	}
+
+	// Handlers behind the middleware can still hijack the connection
+	h := GzipMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("wrapped writer is not a Hijacker")
+		}
+		hj.Hijack()
+	}))
+	for _, upgrade := range []string{"", "websocket"} {
+		rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest("GET", "/graphql", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if upgrade != "" {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", upgrade)
+		}
+		h.ServeHTTP(rec, req)
+		if !rec.hijacked {
+			t.Errorf("upgrade %q: connection was not hijacked", upgrade)
+		}
+		if upgrade != "" && rec.Header().Get("Vary") != "" {
+			t.Error("upgrade request was handled by the gzip writer")
+		}
+	}
+}
@@ -2,9 +2,16 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"log"
+	"mime"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +27,10 @@ type Server struct {
 	extensions       []Extension
 	errorPresenter   ErrorPresenterFunc
 	recoverFunc      RecoverFunc
+	panicHandler     PanicHandlerFunc
+
+	// Builds the DataLoaderRegistry of each request
+	dataLoaderFactory func(ctx context.Context) *graph.DataLoaderRegistry
 
 	// Configuration
 	queryCache           QueryCache
@@ -28,10 +39,21 @@ type Server struct {
 	enableIntrospection  bool
 	enablePlayground     bool
 	playgroundPath       string
+	playgroundHTML       string
+	playgroundHandler    http.Handler
+	graphQLPath          string
+	explainPath          string
 	disableSuggestions   bool
 	websocketUpgrader    WebsocketUpgrader
 	websocketInitTimeout time.Duration
 	websocketKeepAlive   time.Duration
+	sseIdleTimeout       time.Duration
+	allowedOperations    []string
+	maxFields            int
+	maxAliases           int
+	requireOperationName bool
+	errorMasking         bool
+	maxErrors            int
 }
 
 // Config holds server configuration
@@ -39,11 +61,19 @@ type Config struct {
 	EnableIntrospection  bool
 	EnablePlayground     bool
 	PlaygroundPath       string
+	PlaygroundHTML       string // Replaces the Apollo Sandbox page (e.g., GraphiQLHTML); {{endpoint}} is the GraphQL path
+	GraphQLPath          string // Path GraphQL requests are served on; empty serves any path
+	ExplainPath          string // Dev only: queries on this path return their SQL instead of running it (e.g., "/graphql/explain")
 	RequestTimeout       time.Duration
 	ComplexityLimit      int
 	DisableSuggestions   bool
 	WebsocketInitTimeout time.Duration
 	WebsocketKeepAlive   time.Duration
+	SSEIdleTimeout       time.Duration
+	AllowedOperations    []string // Operation types served (e.g., "query"); empty allows all
+	MaxFields            int      // Total field selections per operation; 0 means no limit
+	MaxAliases           int      // Total aliased selections per operation; 0 means no limit
+	RequireOperationName bool     // Reject anonymous operations
 }
 
 // DefaultConfig returns a default configuration
@@ -57,6 +87,7 @@ func DefaultConfig() Config {
 		DisableSuggestions:   false,
 		WebsocketInitTimeout: 15 * time.Second,
 		WebsocketKeepAlive:   30 * time.Second,
+		SSEIdleTimeout:       5 * time.Minute,
 	}
 }
 
@@ -74,11 +105,19 @@ func NewWithConfig(es *graph.ExecutableSchema, cfg Config) *Server {
 		enableIntrospection:  cfg.EnableIntrospection,
 		enablePlayground:     cfg.EnablePlayground,
 		playgroundPath:       cfg.PlaygroundPath,
+		playgroundHTML:       cfg.PlaygroundHTML,
+		graphQLPath:          cfg.GraphQLPath,
+		explainPath:          cfg.ExplainPath,
 		requestTimeout:       cfg.RequestTimeout,
 		complexityLimit:      cfg.ComplexityLimit,
 		disableSuggestions:   cfg.DisableSuggestions,
 		websocketInitTimeout: cfg.WebsocketInitTimeout,
 		websocketKeepAlive:   cfg.WebsocketKeepAlive,
+		sseIdleTimeout:       cfg.SSEIdleTimeout,
+		allowedOperations:    cfg.AllowedOperations,
+		maxFields:            cfg.MaxFields,
+		maxAliases:           cfg.MaxAliases,
+		requireOperationName: cfg.RequireOperationName,
 	}
 
 	// Set default error presenter
@@ -87,6 +126,9 @@ func NewWithConfig(es *graph.ExecutableSchema, cfg Config) *Server {
 	// Set default recover function
 	s.recoverFunc = DefaultRecoverFunc
 
+	// Set default panic handler
+	s.panicHandler = DefaultPanicHandler
+
 	return s
 }
 
@@ -101,6 +143,17 @@ func (s *Server) Use(extension Extension) {
 func (s *Server) AddTransport(transport Transport) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	// SSE streams inherit keep-alive and idle settings from the server config
+	if sse, ok := transport.(*SSE); ok {
+		if sse.KeepAlive == 0 {
+			sse.KeepAlive = s.websocketKeepAlive
+		}
+		if sse.IdleTimeout == 0 {
+			sse.IdleTimeout = s.sseIdleTimeout
+		}
+	}
+
 	s.transports = append(s.transports, transport)
 }
 
@@ -111,6 +164,23 @@ func (s *Server) SetErrorPresenter(f ErrorPresenterFunc) {
 	s.errorPresenter = f
 }
 
+// SetErrorMasking replaces unclassified resolver errors (e.g., raw database
+// errors) with a generic message in responses; the original is logged. Errors
+// returned as *graph.Error pass through unchanged.
+func (s *Server) SetErrorMasking(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorMasking = enabled
+}
+
+// SetMaxErrors caps the errors collected per operation; past it, the rest are
+// dropped with a single "too many errors" error. 0 means no limit.
+func (s *Server) SetMaxErrors(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxErrors = n
+}
+
 // SetRecoverFunc sets a custom recovery function
 func (s *Server) SetRecoverFunc(f RecoverFunc) {
 	s.mu.Lock()
@@ -118,6 +188,35 @@ func (s *Server) SetRecoverFunc(f RecoverFunc) {
 	s.recoverFunc = f
 }
 
+// SetPanicHandler sets the handler that receives recovered panics and their stack traces
+func (s *Server) SetPanicHandler(f PanicHandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.panicHandler = f
+}
+
+// SetDataLoaderFactory sets the function building a fresh DataLoaderRegistry
+// for every request, attached with graph.WithDataLoaders. Loaders created per
+// request can't serve one user's cached data to another.
+func (s *Server) SetDataLoaderFactory(factory func(ctx context.Context) *graph.DataLoaderRegistry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataLoaderFactory = factory
+}
+
+// ReloadSchema atomically replaces the served schema. An invalid SDL is
+// rejected and the current schema keeps serving requests.
+func (s *Server) ReloadSchema(sdl string) error {
+	s.mu.RLock()
+	es := s.executableSchema
+	s.mu.RUnlock()
+
+	if es == nil {
+		return fmt.Errorf("server has no executable schema")
+	}
+	return es.Reload(sdl)
+}
+
 // SetQueryCache sets a query cache
 func (s *Server) SetQueryCache(cache QueryCache) {
 	s.mu.Lock()
@@ -133,12 +232,18 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	explain := s.explainPath != "" && r.URL.Path == s.explainPath
+
+	// With a GraphQL path, other paths (e.g., a shared mux prefix) are not ours
+	if !explain && s.graphQLPath != "" && r.URL.Path != s.graphQLPath {
+		http.NotFound(w, r)
+		return
+	}
+
 	// Create request context
 	ctx := r.Context()
-	if s.requestTimeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
-		defer cancel()
+	if explain {
+		ctx = graph.WithExplain(ctx)
 	}
 
 	// Find a transport that supports this request
@@ -148,6 +253,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	for _, transport := range transports {
 		if transport.Supports(r) {
+			// SSE streams are long-lived; their IdleTimeout bounds them instead
+			if _, streaming := transport.(*SSE); !streaming && s.requestTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+				defer cancel()
+			}
 			s.handleRequest(ctx, w, r, transport)
 			return
 		}
@@ -161,25 +272,37 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, transport Transport) {
 	defer func() {
 		if rec := recover(); rec != nil {
+			stack := debug.Stack()
+
 			s.mu.RLock()
 			recoverFunc := s.recoverFunc
+			panicHandler := s.panicHandler
 			s.mu.RUnlock()
 
+			if panicHandler != nil {
+				panicHandler(ctx, rec, stack)
+			}
+
 			err := recoverFunc(ctx, rec)
 			s.writeError(w, err)
 		}
 	}()
 
+	// The transports write the negotiated media type
+	w.Header().Set("Content-Type", NegotiateResponseType(r.Header.Get("Accept")))
+
 	// Parse request
 	params, err := transport.ParseRequest(r)
 	if err != nil {
 		s.writeError(w, err)
 		return
 	}
+	defer params.cleanup()
 
 	// Execute operation hooks
 	s.mu.RLock()
 	extensions := s.extensions
+	dataLoaderFactory := s.dataLoaderFactory
 	s.mu.RUnlock()
 
 	// Create operation context
@@ -187,7 +310,22 @@ func (s *Server) handleRequest(ctx context.Context, w http.ResponseWriter, r *ht
 	rc.Query = params.Query
 	rc.OperationName = params.OperationName
 	rc.Variables = params.Variables
+	rc.RequestID = requestIDFromHeader(r)
 	ctx = graph.WithRequestContext(ctx, rc)
+	w.Header().Set(RequestIDHeader, rc.RequestID)
+
+	// Fresh data loaders for every request
+	if dataLoaderFactory != nil {
+		if registry := dataLoaderFactory(ctx); registry != nil {
+			ctx = graph.WithDataLoaders(ctx, registry)
+		}
+	}
+
+	// Subscriptions over SSE send one frame per event
+	if sse, ok := transport.(*SSE); ok {
+		s.streamOperation(ctx, w, sse, params)
+		return
+	}
 
 	// Call extension hooks: OperationStart
 	for _, ext := range extensions {
@@ -220,20 +358,105 @@ func (s *Server) handleRequest(ctx context.Context, w http.ResponseWriter, r *ht
 		}
 	}
 
+	// Explain requests return the SQL their resolvers produced
+	if queries, ok := graph.ExplainedQueries(ctx); ok {
+		response.Extensions["explain"] = queries
+	}
+
 	// Write response
 	transport.WriteResponse(w, response)
 }
 
 // executeOperation executes a GraphQL operation
 func (s *Server) executeOperation(ctx context.Context, params *RequestParams) *graph.Response {
+	response := s.executableSchema.Execute(ctx, s.executeParams(ctx, params))
+	s.maskErrors(ctx, response)
+	return response
+}
+
+// streamOperation executes an operation and streams its responses over SSE
+// until the subscription ends or the client disconnects
+func (s *Server) streamOperation(ctx context.Context, w http.ResponseWriter, sse *SSE, params *RequestParams) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := s.executableSchema.Subscribe(ctx, s.executeParams(ctx, params))
+	events := make(chan *graph.Response)
+	go func() {
+		defer close(events)
+		for response := range responses {
+			s.maskErrors(ctx, response)
+			select {
+			case events <- response:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if err := sse.Stream(ctx, w, events); err != nil && ctx.Err() == nil {
+		log.Printf("[GraphQL] sse stream (request %s): %v", graph.GetRequestID(ctx), err)
+	}
+}
+
+// executeParams builds the executor parameters for a request
+func (s *Server) executeParams(ctx context.Context, params *RequestParams) graph.ExecuteParams {
 	execParams := graph.ExecuteParams{
 		Query:         params.Query,
 		OperationName: params.OperationName,
 		Variables:     params.Variables,
 		Context:       ctx,
+
+		AllowedOperations:    s.allowedOperations,
+		MaxFields:            s.maxFields,
+		MaxAliases:           s.maxAliases,
+		RequireOperationName: s.requireOperationName,
+	}
+
+	s.mu.RLock()
+	execParams.MaxErrors = s.maxErrors
+	s.mu.RUnlock()
+
+	// Explain requests must not reach the database; mutation and subscription
+	// resolvers write or listen whatever the context says
+	if graph.ExplainRequested(ctx) {
+		execParams.AllowedOperations = []string{"query"}
+	}
+
+	return execParams
+}
+
+// maskErrors masks the unclassified errors of response when masking is enabled
+func (s *Server) maskErrors(ctx context.Context, response *graph.Response) {
+	s.mu.RLock()
+	masking := s.errorMasking
+	s.mu.RUnlock()
+
+	if masking {
+		for i, gqlErr := range response.Errors {
+			response.Errors[i] = maskError(ctx, gqlErr)
+		}
 	}
+}
 
-	return s.executableSchema.Execute(ctx, execParams)
+// maskError hides the message of an error that wraps an unclassified error
+func maskError(ctx context.Context, gqlErr *graph.Error) *graph.Error {
+	var coded *graph.Error
+	if gqlErr.Err == nil || errors.As(gqlErr.Err, &coded) {
+		return gqlErr
+	}
+
+	log.Printf("[GraphQL] error (request %s) at %v: %v", graph.GetRequestID(ctx), gqlErr.Path, gqlErr.Err)
+
+	return &graph.Error{
+		Message:   "internal server error",
+		Locations: gqlErr.Locations,
+		Path:      gqlErr.Path,
+		Extensions: map[string]interface{}{
+			"code": "INTERNAL_SERVER_ERROR",
+		},
+		Err: gqlErr.Err,
+	}
 }
 
 // writeError writes an error response
@@ -242,24 +465,106 @@ func (s *Server) writeError(w http.ResponseWriter, err error) {
 	presenter := s.errorPresenter
 	s.mu.RUnlock()
 
+	// Transport errors keep their HTTP status
+	status := http.StatusOK
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		status = transportErr.StatusCode
+		err = &graph.Error{
+			Message:    transportErr.Message,
+			Extensions: map[string]interface{}{"code": transportErr.Code},
+		}
+	}
+
 	gqlErr := presenter(context.Background(), err)
 	response := &graph.Response{
 		Errors: []*graph.Error{gqlErr},
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK) // GraphQL always returns 200
+	// Under application/graphql-response+json request errors aren't 200
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType != MediaTypeGraphQLResponse {
+		mediaType = MediaTypeJSON
+	} else if status == http.StatusOK {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.WriteHeader(status) // GraphQL errors are 200; transport errors have their own status
 	// Debug: log the raw response for diagnosis
 	debugBytes, _ := json.MarshalIndent(response, "", "  ")
 	fmt.Println("--- GraphQL Response ---\n" + string(debugBytes) + "\n------------------------")
 	json.NewEncoder(w).Encode(response)
 }
 
+// RequestIDHeader is the header used to read and echo the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// requestIDFromHeader reuses a client-supplied request ID or generates a new one
+func requestIDFromHeader(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// newRequestID generates a random (version 4) UUID
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// isPrintableASCII checks that a header value contains only printable ASCII
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// SetPlaygroundHandler serves the playground path with h instead of the
+// built-in page, e.g., a self-hosted GraphiQL for air-gapped deployments
+func (s *Server) SetPlaygroundHandler(h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playgroundHandler = h
+}
+
 // servePlayground serves the GraphQL Playground
 func (s *Server) servePlayground(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	custom := s.playgroundHandler
+	s.mu.RUnlock()
+	if custom != nil {
+		custom.ServeHTTP(w, r)
+		return
+	}
+
+	page := playgroundHTML
+	if s.playgroundHTML != "" {
+		page = s.playgroundHTML
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(playgroundHTML))
+	w.Write([]byte(strings.ReplaceAll(page, "{{endpoint}}", template.JSEscapeString(s.playgroundEndpoint()))))
+}
+
+// playgroundEndpoint returns the path the playground sends queries to
+func (s *Server) playgroundEndpoint() string {
+	if s.graphQLPath != "" {
+		return s.graphQLPath
+	}
+	return "/graphql"
 }
 
 // RequestParams contains parsed request parameters
@@ -268,6 +573,17 @@ type RequestParams struct {
 	OperationName string                 `json:"operationName"`
 	Variables     map[string]interface{} `json:"variables"`
 	Extensions    map[string]interface{} `json:"extensions"`
+
+	// Uploads to release once the request is done
+	uploads []*Upload
+}
+
+// cleanup closes the request's uploads and removes their temp files
+func (p *RequestParams) cleanup() {
+	for _, upload := range p.uploads {
+		upload.remove()
+	}
+	p.uploads = nil
 }
 
 // ErrorPresenterFunc formats errors for response
@@ -286,12 +602,23 @@ func DefaultErrorPresenter(ctx context.Context, err error) *graph.Error {
 // RecoverFunc handles panics
 type RecoverFunc func(ctx context.Context, err interface{}) error
 
-// DefaultRecoverFunc is the default recover function
+// DefaultRecoverFunc is the default recover function. The panic value is
+// never exposed to the client; details go to the panic handler instead.
 func DefaultRecoverFunc(ctx context.Context, err interface{}) error {
-	if e, ok := err.(error); ok {
-		return e
+	return &graph.Error{
+		Message: "internal server error",
+		Extensions: map[string]interface{}{
+			"code": "INTERNAL_SERVER_ERROR",
+		},
 	}
-	return &graph.Error{Message: "internal server error"}
+}
+
+// PanicHandlerFunc receives a recovered panic value and the stack trace captured at recovery
+type PanicHandlerFunc func(ctx context.Context, recovered interface{}, stack []byte)
+
+// DefaultPanicHandler logs the panic value and stack trace
+func DefaultPanicHandler(ctx context.Context, recovered interface{}, stack []byte) {
+	log.Printf("[GraphQL] panic (request %s): %v\n%s", graph.GetRequestID(ctx), recovered, stack)
 }
 
 // QueryCache caches parsed queries
@@ -354,8 +681,42 @@ const playgroundHTML = `<!DOCTYPE html>
   <script>
     new window.EmbeddedSandbox({
       target: '#sandbox',
-      initialEndpoint: '/graphql',
+      initialEndpoint: '{{endpoint}}',
     });
   </script>
 </body>
 </html>`
+
+// GraphiQLHTML is a GraphiQL page for Config.PlaygroundHTML, loaded from the
+// unpkg CDN
+const GraphiQLHTML = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>GraphiQL</title>
+  <style>
+    body {
+      height: 100vh;
+      margin: 0;
+      overflow: hidden;
+    }
+    #graphiql {
+      height: 100vh;
+    }
+  </style>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body>
+  <div id="graphiql">Loading...</div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: '{{endpoint}}' });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(
+      React.createElement(GraphiQL, { fetcher: fetcher })
+    );
+  </script>
+</body>
+</html>`
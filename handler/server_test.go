@@ -0,0 +1,390 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eddieafk/goinmonster/graph"
+)
+
+// post sends a JSON GraphQL request to s and returns the response body
+func post(t *testing.T, s *Server, path, body string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	s.ServeHTTP(rec, req)
+	return rec.Body.String()
+}
+
+// newTestServer serves sdl over the POST transport with cfg, registering
+// resolvers keyed "Type.field"
+func newTestServer(t *testing.T, sdl string, cfg Config, resolvers map[string]graph.ResolverFunc) *Server {
+	t.Helper()
+	es, err := graph.NewExecutableSchema(sdl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, resolver := range resolvers {
+		typeName, fieldName, _ := strings.Cut(key, ".")
+		es.RegisterResolver(typeName, fieldName, resolver)
+	}
+	s := NewWithConfig(es, cfg)
+	s.AddTransport(NewPOST())
+	return s
+}
+
+// resolveOK resolves Query.ok of the servers below
+func resolveOK(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	return true, nil
+}
+
+// newOKServer serves `type Query { ok: Boolean }` on /graphql, with cfg
+// overriding the defaults when given
+func newOKServer(t *testing.T, cfg ...Config) *Server {
+	t.Helper()
+	c := Config{GraphQLPath: "/graphql"}
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	return newTestServer(t, `type Query { ok: Boolean }`, c, map[string]graph.ResolverFunc{"Query.ok": resolveOK})
+}
+
+func TestExplainPath(t *testing.T) {
+	dbCalls := 0
+	s := newTestServer(t, `type Query { users: [String] } type Mutation { addUser: Boolean }`,
+		Config{GraphQLPath: "/graphql", ExplainPath: "/graphql/explain"},
+		map[string]graph.ResolverFunc{
+			"Query.users": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				if graph.ExplainRequested(ctx) {
+					return nil, nil
+				}
+				dbCalls++
+				return []string{}, nil
+			},
+			"Mutation.addUser": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				dbCalls++
+				return true, nil
+			},
+		})
+
+	if body := post(t, s, "/graphql/explain", `{"query":"{users}"}`); strings.Contains(body, "errors") {
+		t.Errorf("explain query failed: %s", body)
+	}
+	if body := post(t, s, "/graphql/explain", `{"query":"mutation {addUser}"}`); !strings.Contains(body, "OPERATION_NOT_ALLOWED") {
+		t.Errorf("explain mutation was not rejected: %s", body)
+	}
+	if dbCalls != 0 {
+		t.Errorf("explain requests made %d database calls", dbCalls)
+	}
+
+	post(t, s, "/graphql", `{"query":"mutation {addUser}"}`)
+	if dbCalls != 1 {
+		t.Errorf("mutation on the GraphQL path ran %d times, want 1", dbCalls)
+	}
+}
+
+func TestSSEStreamsSubscriptions(t *testing.T) {
+	es, err := graph.NewExecutableSchema(`type Query { ok: Boolean } type Subscription { tick: Int }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.RegisterResolver("Subscription", "tick", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		ticks := make(chan int, 3)
+		ticks <- 1
+		ticks <- 2
+		ticks <- 3
+		close(ticks)
+		return ticks, nil
+	})
+
+	s := NewWithConfig(es, Config{GraphQLPath: "/graphql"})
+	s.AddTransport(NewSSE())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/graphql?query=subscription+%7Btick%7D", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	s.ServeHTTP(rec, req)
+
+	want := `data: {"data":{"tick":1}}` + "\n\n" + `data: {"data":{"tick":2}}` + "\n\n" + `data: {"data":{"tick":3}}` + "\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	s := newOKServer(t)
+	s.Use(NewRequestID())
+
+	send := func(id string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("trace-123")
+	if got := rec.Header().Get(RequestIDHeader); got != "trace-123" {
+		t.Errorf("header = %q, want the provided ID", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"requestId":"trace-123"`) {
+		t.Errorf("extensions do not echo the ID: %s", rec.Body.String())
+	}
+
+	rec = send("")
+	id := rec.Header().Get(RequestIDHeader)
+	if len(id) != 36 || strings.Count(id, "-") != 4 {
+		t.Errorf("generated ID %q is not a UUID", id)
+	}
+	if !strings.Contains(rec.Body.String(), `"requestId":"`+id+`"`) {
+		t.Errorf("extensions do not carry the generated ID: %s", rec.Body.String())
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	s := newTestServer(t, `type Query { boom: Boolean }`, Config{GraphQLPath: "/graphql"}, map[string]graph.ResolverFunc{
+		"Query.boom": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			panic("db password is hunter2")
+		},
+	})
+
+	var recovered interface{}
+	var stack []byte
+	s.SetPanicHandler(func(ctx context.Context, rec interface{}, st []byte) {
+		recovered, stack = rec, st
+	})
+
+	body := post(t, s, "/graphql", `{"query":"{boom}"}`)
+	if strings.Contains(body, "hunter2") || !strings.Contains(body, "INTERNAL_SERVER_ERROR") {
+		t.Errorf("client error is not generic: %s", body)
+	}
+	if recovered != "db password is hunter2" {
+		t.Errorf("handler received %v", recovered)
+	}
+	if !strings.Contains(string(stack), "TestPanicRecovery") {
+		t.Errorf("stack does not include the panicking resolver:\n%s", stack)
+	}
+}
+
+func TestReloadSchema(t *testing.T) {
+	s := newTestServer(t, `type Query { hello: String }`, Config{GraphQLPath: "/graphql"}, map[string]graph.ResolverFunc{
+		"Query.hello": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "hi", nil
+		},
+	})
+
+	// Cache the document against the old schema
+	if body := post(t, s, "/graphql", `{"query":"{hello}"}`); body != `{"data":{"hello":"hi"}}`+"\n" {
+		t.Fatalf("before reload: %s", body)
+	}
+
+	if err := s.ReloadSchema(`type Query { hello: String goodbye: String`); err == nil {
+		t.Error("invalid SDL was accepted")
+	}
+	if body := post(t, s, "/graphql", `{"query":"{hello}"}`); !strings.Contains(body, `"hello":"hi"`) {
+		t.Errorf("rejected reload disrupted serving: %s", body)
+	}
+
+	if err := s.ReloadSchema(`type Query { hello: String goodbye: String }`); err != nil {
+		t.Fatal(err)
+	}
+	s.executableSchema.RegisterResolver("Query", "goodbye", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return "bye", nil
+	})
+	if body := post(t, s, "/graphql", `{"query":"{hello goodbye}"}`); !strings.Contains(body, `{"data":{"goodbye":"bye","hello":"hi"}}`) {
+		t.Errorf("after reload: %s", body)
+	}
+
+	// The cached document must not outlive the field it selects
+	if err := s.ReloadSchema(`type Query { goodbye: String }`); err != nil {
+		t.Fatal(err)
+	}
+	if body := post(t, s, "/graphql", `{"query":"{hello}"}`); !strings.Contains(body, "errors") {
+		t.Errorf("removed field still resolves: %s", body)
+	}
+}
+
+func TestAllowedOperations(t *testing.T) {
+	calls := 0
+	resolve := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		calls++
+		return true, nil
+	}
+	s := newTestServer(t, `type Query { ok: Boolean } type Mutation { addUser: Boolean }`,
+		Config{GraphQLPath: "/graphql", AllowedOperations: []string{"query"}},
+		map[string]graph.ResolverFunc{"Query.ok": resolve, "Mutation.addUser": resolve})
+
+	if body := post(t, s, "/graphql", `{"query":"mutation {addUser}"}`); !strings.Contains(body, "OPERATION_NOT_ALLOWED") {
+		t.Errorf("mutation was not rejected: %s", body)
+	}
+	if calls != 0 {
+		t.Errorf("rejected mutation ran %d resolvers", calls)
+	}
+	if body := post(t, s, "/graphql", `{"query":"{ok}"}`); !strings.Contains(body, `{"data":{"ok":true}}`) {
+		t.Errorf("query failed: %s", body)
+	}
+}
+
+func TestRequireOperationName(t *testing.T) {
+	s := newOKServer(t, Config{GraphQLPath: "/graphql", RequireOperationName: true})
+
+	if body := post(t, s, "/graphql", `{"query":"{ok}"}`); !strings.Contains(body, "OPERATION_NAME_REQUIRED") || strings.Contains(body, `"ok":true`) {
+		t.Errorf("anonymous operation was not rejected: %s", body)
+	}
+	if body := post(t, s, "/graphql", `{"query":"query Check {ok}"}`); !strings.Contains(body, `{"data":{"ok":true}}`) {
+		t.Errorf("named operation failed: %s", body)
+	}
+	if body := post(t, newOKServer(t), "/graphql", `{"query":"{ok}"}`); !strings.Contains(body, `{"data":{"ok":true}}`) {
+		t.Errorf("anonymous operation was rejected by default: %s", body)
+	}
+}
+
+func TestDataLoaderFactory(t *testing.T) {
+	load := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		loader, ok := graph.GetDataLoader(ctx, "users")
+		if !ok {
+			return nil, fmt.Errorf("no users loader")
+		}
+		return loader.Load(ctx, "me")
+	}
+	s := newTestServer(t, `type Query { me: String again: String }`, Config{GraphQLPath: "/graphql"},
+		map[string]graph.ResolverFunc{"Query.me": load, "Query.again": load})
+
+	batches := 0
+	s.SetDataLoaderFactory(func(ctx context.Context) *graph.DataLoaderRegistry {
+		registry := graph.NewDataLoaderRegistry()
+		registry.Register("users", graph.NewDataLoader(func(ctx context.Context, keys []interface{}) ([]interface{}, []error) {
+			batches++
+			return []interface{}{fmt.Sprintf("batch %d", batches)}, nil
+		}, graph.WithMaxBatch(1)))
+		return registry
+	})
+
+	// Within a request the loader caches; across requests nothing is shared
+	for i, want := range []string{
+		`{"data":{"again":"batch 1","me":"batch 1"}}`,
+		`{"data":{"again":"batch 2","me":"batch 2"}}`,
+	} {
+		if body := post(t, s, "/graphql", `{"query":"{ me again }"}`); !strings.Contains(body, want) {
+			t.Errorf("request %d: %s, want %s", i+1, body, want)
+		}
+	}
+}
+
+func TestErrorMasking(t *testing.T) {
+	s := newTestServer(t, `type Query { db: String user: String }`, Config{GraphQLPath: "/graphql"}, map[string]graph.ResolverFunc{
+		"Query.db": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("pq: relation \"users_secret\" does not exist")
+		},
+		"Query.user": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("lookup: %w", &graph.Error{
+				Message:    "user not found",
+				Extensions: map[string]interface{}{"code": "NOT_FOUND"},
+			})
+		},
+	})
+
+	if body := post(t, s, "/graphql", `{"query":"{db}"}`); !strings.Contains(body, "users_secret") {
+		t.Errorf("unmasked server hid the error: %s", body)
+	}
+
+	s.SetErrorMasking(true)
+	body := post(t, s, "/graphql", `{"query":"{db}"}`)
+	if strings.Contains(body, "users_secret") || !strings.Contains(body, `"message":"internal server error"`) {
+		t.Errorf("database error was not masked: %s", body)
+	}
+	if !strings.Contains(body, `"path":["db"]`) {
+		t.Errorf("masked error lost its path: %s", body)
+	}
+
+	body = post(t, s, "/graphql", `{"query":"{user}"}`)
+	if !strings.Contains(body, `"message":"user not found"`) || !strings.Contains(body, "NOT_FOUND") {
+		t.Errorf("coded error was masked: %s", body)
+	}
+}
+
+func TestGraphQLPath(t *testing.T) {
+	s := newOKServer(t, Config{GraphQLPath: "/query", EnablePlayground: true, PlaygroundPath: "/"})
+
+	if body := post(t, s, "/query", `{"query":"{ok}"}`); !strings.Contains(body, `{"data":{"ok":true}}`) {
+		t.Errorf("/query: %s", body)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"}`))
+	req.Header.Set("Content-Type", "application/json")
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("/graphql: status %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "initialEndpoint: '/query'") {
+		t.Errorf("playground does not point to /query:\n%s", body)
+	}
+
+	// Without a GraphQL path the playground keeps its default endpoint
+	s = newOKServer(t, Config{EnablePlayground: true, PlaygroundPath: "/"})
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "initialEndpoint: '/graphql'") {
+		t.Errorf("default playground endpoint:\n%s", body)
+	}
+}
+
+func TestPlaygroundOverrides(t *testing.T) {
+	get := func(s *Server, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	s := newOKServer(t, Config{GraphQLPath: "/query", EnablePlayground: true, PlaygroundPath: "/play", PlaygroundHTML: GraphiQLHTML})
+	if body := get(s, "/play").Body.String(); !strings.Contains(body, "GraphiQL.createFetcher({ url: '/query' })") {
+		t.Errorf("GraphiQL page:\n%s", body)
+	}
+
+	var called bool
+	s.SetPlaygroundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("custom playground"))
+	}))
+	if rec := get(s, "/play"); !called || rec.Body.String() != "custom playground" {
+		t.Errorf("custom handler: called = %v, body %q", called, rec.Body.String())
+	}
+
+	// The handler only serves the playground path
+	called = false
+	if rec := get(s, "/elsewhere"); called || rec.Code != http.StatusNotFound {
+		t.Errorf("other path: called = %v, status %d", called, rec.Code)
+	}
+}
+
+func TestServerMaxErrors(t *testing.T) {
+	broken := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("broken")
+	}
+	s := newTestServer(t, `type Query { a: String b: String c: String d: String }`, Config{GraphQLPath: "/graphql"},
+		map[string]graph.ResolverFunc{"Query.a": broken, "Query.b": broken, "Query.c": broken, "Query.d": broken})
+	s.SetMaxErrors(2)
+
+	body := post(t, s, "/graphql", `{"query":"{ a b c d }"}`)
+	if n := strings.Count(body, `"message":"broken"`); n != 2 {
+		t.Errorf("%d field errors, want 2: %s", n, body)
+	}
+	if strings.Count(body, `"message":"too many errors"`) != 1 {
+		t.Errorf("no single too many errors marker: %s", body)
+	}
+}
@@ -1,11 +1,19 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/eddieafk/goinmonster/graph"
 )
@@ -22,6 +30,86 @@ type Transport interface {
 	WriteResponse(w http.ResponseWriter, response *graph.Response)
 }
 
+// Response media types of the GraphQL-over-HTTP spec
+const (
+	MediaTypeJSON            = "application/json"
+	MediaTypeGraphQLResponse = "application/graphql-response+json"
+)
+
+// NegotiateResponseType picks the response media type for an Accept header.
+// application/graphql-response+json is used when the client accepts it at
+// least as much as application/json; anything else falls back to
+// application/json for legacy clients.
+func NegotiateResponseType(accept string) string {
+	graphqlQ, jsonQ := -1.0, -1.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case MediaTypeGraphQLResponse:
+			graphqlQ = max(graphqlQ, q)
+		case MediaTypeJSON, "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+
+	if graphqlQ > 0 && graphqlQ >= jsonQ {
+		return MediaTypeGraphQLResponse
+	}
+	return MediaTypeJSON
+}
+
+// writeJSONResponse writes response as the media type the server negotiated
+// into the Content-Type header. Under application/graphql-response+json,
+// request errors (no data) are sent with 400.
+func writeJSONResponse(w http.ResponseWriter, response *graph.Response) {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	status := http.StatusOK
+	if mediaType != MediaTypeGraphQLResponse {
+		mediaType = MediaTypeJSON
+	} else if response.IsRequestError() {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// TransportError is a request the transport rejects with an HTTP status other
+// than 200, e.g., 413 for an oversized body
+type TransportError struct {
+	StatusCode int
+	Message    string
+	Code       string // Extension code of the GraphQL error
+}
+
+func (e *TransportError) Error() string {
+	return e.Message
+}
+
+// errBodyTooLarge maps the error of a body read through http.MaxBytesReader to
+// a 413 transport error
+func errBodyTooLarge(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &TransportError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Message:    fmt.Sprintf("request body too large (limit %d bytes)", maxErr.Limit),
+			Code:       "REQUEST_TOO_LARGE",
+		}
+	}
+	return err
+}
+
 // POST transport handles POST requests with JSON body
 type POST struct {
 	// MaxBodySize limits the request body size (default: 1MB)
@@ -65,7 +153,7 @@ func (t *POST) ParseRequest(r *http.Request) (*RequestParams, error) {
 		// Body is the query itself
 		queryBytes, err := io.ReadAll(body)
 		if err != nil {
-			return nil, err
+			return nil, errBodyTooLarge(err)
 		}
 		return &RequestParams{
 			Query: string(queryBytes),
@@ -73,9 +161,13 @@ func (t *POST) ParseRequest(r *http.Request) (*RequestParams, error) {
 	}
 
 	// Parse JSON body
+	// Numbers stay json.Number until coerced against the variable types, so
+	// large integers keep their precision
 	var params RequestParams
-	if err := json.NewDecoder(body).Decode(&params); err != nil {
-		return nil, err
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		return nil, errBodyTooLarge(err)
 	}
 
 	return &params, nil
@@ -83,9 +175,7 @@ func (t *POST) ParseRequest(r *http.Request) (*RequestParams, error) {
 
 // WriteResponse writes a JSON response
 func (t *POST) WriteResponse(w http.ResponseWriter, response *graph.Response) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSONResponse(w, response)
 }
 
 // GET transport handles GET requests with query parameters
@@ -106,6 +196,14 @@ func (t *GET) Supports(r *http.Request) bool {
 	return r.Method == http.MethodGet && r.URL.Query().Get("query") != ""
 }
 
+// unmarshalUseNumber decodes JSON like json.Unmarshal, but keeps numbers as
+// json.Number like the POST transport
+func unmarshalUseNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
 // ParseRequest parses a GET request
 func (t *GET) ParseRequest(r *http.Request) (*RequestParams, error) {
 	query := r.URL.Query()
@@ -117,7 +215,7 @@ func (t *GET) ParseRequest(r *http.Request) (*RequestParams, error) {
 
 	// Parse variables if present
 	if varsStr := query.Get("variables"); varsStr != "" {
-		if err := json.Unmarshal([]byte(varsStr), &params.Variables); err != nil {
+		if err := unmarshalUseNumber([]byte(varsStr), &params.Variables); err != nil {
 			return nil, err
 		}
 	}
@@ -134,9 +232,7 @@ func (t *GET) ParseRequest(r *http.Request) (*RequestParams, error) {
 
 // WriteResponse writes a JSON response
 func (t *GET) WriteResponse(w http.ResponseWriter, response *graph.Response) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSONResponse(w, response)
 }
 
 // MultipartForm transport handles multipart form uploads (for file uploads)
@@ -145,6 +241,10 @@ type MultipartForm struct {
 	MaxMemory int64
 	// MaxUploadSize limits the total upload size
 	MaxUploadSize int64
+	// StreamToDiskThreshold streams file parts larger than this many bytes to
+	// temp files instead of memory (0 keeps the ParseMultipartForm behavior).
+	// The temp files are removed once the response is written.
+	StreamToDiskThreshold int64
 }
 
 // NewMultipartForm creates a new multipart form transport
@@ -168,8 +268,16 @@ func (t *MultipartForm) Supports(r *http.Request) bool {
 
 // ParseRequest parses a multipart form request (GraphQL multipart spec)
 func (t *MultipartForm) ParseRequest(r *http.Request) (*RequestParams, error) {
+	if t.MaxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, t.MaxUploadSize)
+	}
+
+	if t.StreamToDiskThreshold > 0 {
+		return t.parseStreaming(r)
+	}
+
 	if err := r.ParseMultipartForm(t.MaxMemory); err != nil {
-		return nil, err
+		return nil, errBodyTooLarge(err)
 	}
 
 	// Get operations field
@@ -179,7 +287,7 @@ func (t *MultipartForm) ParseRequest(r *http.Request) (*RequestParams, error) {
 	}
 
 	var params RequestParams
-	if err := json.Unmarshal([]byte(operations), &params); err != nil {
+	if err := unmarshalUseNumber([]byte(operations), &params); err != nil {
 		return nil, err
 	}
 
@@ -206,7 +314,7 @@ func (t *MultipartForm) ParseRequest(r *http.Request) (*RequestParams, error) {
 			}
 
 			for _, path := range paths {
-				setNestedValue(params.Variables, path, upload)
+				setUpload(&params, path, upload)
 			}
 		}
 	}
@@ -214,11 +322,122 @@ func (t *MultipartForm) ParseRequest(r *http.Request) (*RequestParams, error) {
 	return &params, nil
 }
 
+// parseStreaming reads the parts in order, keeping files up to
+// StreamToDiskThreshold in memory and spooling larger ones to temp files
+func (t *MultipartForm) parseStreaming(r *http.Request) (*RequestParams, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	var params *RequestParams
+	var fileMap map[string][]string
+
+	// The uploads are owned by params once it exists; until then drop them here
+	var pending []*Upload
+	fail := func(err error) (*RequestParams, error) {
+		if params != nil {
+			params.cleanup()
+		}
+		for _, upload := range pending {
+			upload.remove()
+		}
+		return nil, errBodyTooLarge(err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		switch name := part.FormName(); {
+		case name == "operations":
+			data, err := io.ReadAll(io.LimitReader(part, t.MaxMemory))
+			if err != nil {
+				return fail(err)
+			}
+			params = &RequestParams{}
+			if err := unmarshalUseNumber(data, params); err != nil {
+				return fail(err)
+			}
+			params.uploads, pending = pending, nil
+
+		case name == "map":
+			data, err := io.ReadAll(io.LimitReader(part, t.MaxMemory))
+			if err != nil {
+				return fail(err)
+			}
+			if err := json.Unmarshal(data, &fileMap); err != nil {
+				return fail(err)
+			}
+
+		case part.FileName() != "":
+			upload, err := t.readUpload(part)
+			if err != nil {
+				return fail(err)
+			}
+			if params == nil {
+				pending = append(pending, upload)
+				continue
+			}
+			params.uploads = append(params.uploads, upload)
+			for _, path := range fileMap[name] {
+				setUpload(params, path, upload)
+			}
+		}
+	}
+
+	if params == nil {
+		return fail(&graph.Error{Message: "missing operations field"})
+	}
+	return params, nil
+}
+
+// readUpload buffers a file part, moving it to a temp file once it grows past
+// StreamToDiskThreshold
+func (t *MultipartForm) readUpload(part *multipart.Part) (*Upload, error) {
+	upload := &Upload{
+		Filename: part.FileName(),
+		MimeType: part.Header.Get("Content-Type"),
+	}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, t.StreamToDiskThreshold+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n <= t.StreamToDiskThreshold {
+		upload.File = io.NopCloser(bytes.NewReader(buf.Bytes()))
+		upload.Size = n
+		return upload, nil
+	}
+
+	file, err := os.CreateTemp("", "goinmonster-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	upload.File = file
+	upload.tempPath = file.Name()
+
+	size, err := io.Copy(file, io.MultiReader(&buf, part))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		upload.remove()
+		return nil, err
+	}
+	upload.Size = size
+	return upload, nil
+}
+
 // WriteResponse writes a JSON response
 func (t *MultipartForm) WriteResponse(w http.ResponseWriter, response *graph.Response) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	writeJSONResponse(w, response)
 }
 
 // Upload represents a file upload
@@ -227,6 +446,29 @@ type Upload struct {
 	Filename string
 	Size     int64
 	MimeType string
+
+	// Temp file holding the upload when it was streamed to disk
+	tempPath string
+}
+
+// remove closes the upload and deletes its temp file, if any
+func (u *Upload) remove() {
+	if u.File != nil {
+		u.File.Close()
+	}
+	if u.tempPath != "" {
+		os.Remove(u.tempPath)
+	}
+}
+
+// setUpload places an upload at a map path. Paths are relative to the
+// operations object (e.g., "variables.file"); a path without the prefix is
+// taken as relative to the variables.
+func setUpload(params *RequestParams, path string, upload *Upload) {
+	if params.Variables == nil {
+		params.Variables = make(map[string]interface{})
+	}
+	setNestedValue(params.Variables, strings.TrimPrefix(path, "variables."), upload)
 }
 
 // setNestedValue sets a value in a nested map/slice structure using a path
@@ -293,7 +535,12 @@ func (t *OPTIONS) WriteResponse(w http.ResponseWriter, response *graph.Response)
 }
 
 // SSE transport handles Server-Sent Events (for subscriptions)
-type SSE struct{}
+type SSE struct {
+	// KeepAlive is the interval between ping comment frames (0 disables pings)
+	KeepAlive time.Duration
+	// IdleTimeout closes the stream when no event is sent for this long (0 disables it)
+	IdleTimeout time.Duration
+}
 
 // NewSSE creates a new SSE transport
 func NewSSE() *SSE {
@@ -331,6 +578,66 @@ func (t *SSE) WriteResponse(w http.ResponseWriter, response *graph.Response) {
 	}
 }
 
+// Stream writes each response from events as an SSE frame until the channel is
+// closed, the context is cancelled or the stream has been idle for IdleTimeout.
+// Ping comments are sent every KeepAlive so proxies keep the connection open.
+func (t *SSE) Stream(ctx context.Context, w http.ResponseWriter, events <-chan *graph.Response) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("response writer does not support flushing")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var ping <-chan time.Time
+	if t.KeepAlive > 0 {
+		ticker := time.NewTicker(t.KeepAlive)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	var idle <-chan time.Time
+	var idleTimer *time.Timer
+	if t.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(t.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle:
+			return fmt.Errorf("sse stream idle for %s", t.IdleTimeout)
+		case <-ping:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return err
+			}
+			flusher.Flush()
+		case response, ok := <-events:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(response)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return err
+			}
+			flusher.Flush()
+			if idleTimer != nil {
+				idleTimer.Reset(t.IdleTimeout)
+			}
+		}
+	}
+}
+
 // Batch transport handles batched GraphQL requests
 type Batch struct {
 	// MaxBatchSize limits the number of operations in a batch
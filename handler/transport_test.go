@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eddieafk/goinmonster/graph"
+)
+
+// fakeFlusher records what was written at each flush
+type fakeFlusher struct {
+	mu      sync.Mutex
+	header  http.Header
+	buf     strings.Builder
+	flushed []string
+}
+
+func newFakeFlusher() *fakeFlusher {
+	return &fakeFlusher{header: make(http.Header)}
+}
+
+func (f *fakeFlusher) Header() http.Header { return f.header }
+
+func (f *fakeFlusher) WriteHeader(int) {}
+
+func (f *fakeFlusher) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buf.Write(p)
+}
+
+func (f *fakeFlusher) Flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushed = append(f.flushed, f.buf.String())
+	f.buf.Reset()
+}
+
+// frames returns the flushed frames, skipping the empty header flush
+func (f *fakeFlusher) frames() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var frames []string
+	for _, s := range f.flushed {
+		if s != "" {
+			frames = append(frames, s)
+		}
+	}
+	return frames
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	w := newFakeFlusher()
+	events := make(chan *graph.Response)
+	done := make(chan error, 1)
+	sse := &SSE{KeepAlive: 5 * time.Millisecond}
+	go func() { done <- sse.Stream(context.Background(), w, events) }()
+
+	deadline := time.Now().Add(time.Second)
+	for len(w.frames()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d frames after 1s, want 3 pings", len(w.frames()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	events <- &graph.Response{Data: map[string]interface{}{"tick": 1}}
+	close(events)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	frames := w.frames()
+	for _, frame := range frames[:3] {
+		if frame != ": ping\n\n" {
+			t.Errorf("frame %q is not a ping", frame)
+		}
+	}
+	if last := frames[len(frames)-1]; last != `data: {"data":{"tick":1}}`+"\n\n" {
+		t.Errorf("last frame = %q", last)
+	}
+}
+
+func TestSSEIdleTimeout(t *testing.T) {
+	sse := &SSE{IdleTimeout: 10 * time.Millisecond}
+	start := time.Now()
+	err := sse.Stream(context.Background(), newFakeFlusher(), make(chan *graph.Response))
+	if err == nil || !strings.Contains(err.Error(), "idle") {
+		t.Errorf("err = %v, want an idle timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("idle stream stayed open for %s", elapsed)
+	}
+}
+
+// multipartUpload builds a GraphQL multipart request uploading content as $file
+func multipartUpload(t *testing.T, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("operations", `{"query":"mutation($file: Upload!) { upload(file: $file) }","variables":{"file":null}}`)
+	mw.WriteField("map", `{"0":["variables.file"]}`)
+	part, err := mw.CreateFormFile("0", "big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/graphql", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMultipartStreamToDisk(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	es, err := graph.NewExecutableSchema(`scalar Upload type Query { ok: Boolean } type Mutation { upload(file: Upload!): String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var onDisk bool
+	es.RegisterResolver("Mutation", "upload", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		upload := args["file"].(*Upload)
+		_, onDisk = upload.File.(*os.File)
+		if entries, _ := os.ReadDir(tmp); onDisk && len(entries) != 1 {
+			return nil, fmt.Errorf("%d temp files during the request, want 1", len(entries))
+		}
+		data, err := io.ReadAll(upload.File)
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Sprintf("%s %d %d", upload.Filename, upload.Size, len(data)), nil
+	})
+
+	s := NewWithConfig(es, Config{GraphQLPath: "/graphql"})
+	s.AddTransport(&MultipartForm{MaxMemory: 1 << 20, MaxUploadSize: 1 << 20, StreamToDiskThreshold: 1024})
+
+	tests := []struct {
+		size   int
+		onDisk bool
+	}{
+		{100, false},
+		{4096, true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, multipartUpload(t, bytes.Repeat([]byte("x"), tt.size)))
+
+		want := fmt.Sprintf(`{"data":{"upload":"big.bin %d %d"}}`, tt.size, tt.size)
+		if body := rec.Body.String(); !strings.Contains(body, want) {
+			t.Errorf("%d bytes: %s, want %s", tt.size, body, want)
+		}
+		if onDisk != tt.onDisk {
+			t.Errorf("%d bytes: on disk = %v, want %v", tt.size, onDisk, tt.onDisk)
+		}
+		if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+			t.Errorf("%d bytes: %d temp files left after the request", tt.size, len(entries))
+		}
+	}
+
+	// Without a threshold, uploads are parsed by ParseMultipartForm
+	s = NewWithConfig(es, Config{GraphQLPath: "/graphql"})
+	s.AddTransport(NewMultipartForm())
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, multipartUpload(t, []byte("small")))
+	if body, want := rec.Body.String(), `{"data":{"upload":"big.bin 5 5"}}`; !strings.Contains(body, want) {
+		t.Errorf("in-memory upload: %s, want %s", body, want)
+	}
+}
+
+func TestRequestBodyTooLarge(t *testing.T) {
+	es, err := graph.NewExecutableSchema(`scalar Upload type Query { ok: Boolean } type Mutation { upload(file: Upload!): String }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWithConfig(es, Config{GraphQLPath: "/graphql"})
+	s.AddTransport(&POST{MaxBodySize: 64})
+	s.AddTransport(&MultipartForm{MaxMemory: 1 << 20, MaxUploadSize: 256})
+
+	padding := strings.Repeat(" ", 100)
+	requests := map[string]*http.Request{
+		"json":      httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"`+padding+`}`)),
+		"graphql":   httptest.NewRequest("POST", "/graphql", strings.NewReader(`{ok}`+padding)),
+		"multipart": multipartUpload(t, bytes.Repeat([]byte("x"), 512)),
+	}
+	requests["json"].Header.Set("Content-Type", "application/json")
+	requests["graphql"].Header.Set("Content-Type", "application/graphql")
+
+	for name, req := range requests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status = %d, want 413", name, rec.Code)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, "request body too large") || !strings.Contains(body, "REQUEST_TOO_LARGE") {
+			t.Errorf("%s: body = %s", name, body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ok}"}`))
+	req.Header.Set("Content-Type", "application/json")
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("body under the limit: status = %d", rec.Code)
+	}
+}
+
+func TestNegotiateResponseType(t *testing.T) {
+	tests := map[string]string{
+		"":                                  MediaTypeJSON,
+		"application/json":                  MediaTypeJSON,
+		"*/*":                               MediaTypeJSON,
+		"application/graphql-response+json": MediaTypeGraphQLResponse,
+		"application/graphql-response+json, application/json;q=0.9": MediaTypeGraphQLResponse,
+		"application/graphql-response+json;q=0.5, application/json": MediaTypeJSON,
+		"application/graphql-response+json;q=0":                     MediaTypeJSON,
+	}
+	for accept, want := range tests {
+		if got := NegotiateResponseType(accept); got != want {
+			t.Errorf("NegotiateResponseType(%q) = %s, want %s", accept, got, want)
+		}
+	}
+}
+
+func TestResponseMediaTypes(t *testing.T) {
+	s := newOKServer(t)
+	tests := []struct {
+		accept      string
+		query       string
+		status      int
+		contentType string
+	}{
+		{"application/json", "{ok}", http.StatusOK, "application/json; charset=utf-8"},
+		{"application/json", "{nope}", http.StatusOK, "application/json; charset=utf-8"},
+		{"application/graphql-response+json", "{ok}", http.StatusOK, "application/graphql-response+json; charset=utf-8"},
+		{"application/graphql-response+json", "{nope}", http.StatusBadRequest, "application/graphql-response+json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/graphql", strings.NewReader(fmt.Sprintf(`{"query":%q}`, tt.query)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", tt.accept)
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status || rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("Accept %s, %s: status %d, Content-Type %q; want %d, %q",
+				tt.accept, tt.query, rec.Code, rec.Header().Get("Content-Type"), tt.status, tt.contentType)
+		}
+	}
+}
+
+func TestLargeIntegerVariables(t *testing.T) {
+	s := newTestServer(t, `
+input Range { from: Int to: Float }
+type Query { echo(id: ID, n: Int, f: Float, r: Range): String }
+`, Config{GraphQLPath: "/graphql"}, map[string]graph.ResolverFunc{
+		"Query.echo": func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			r, _ := args["r"].(map[string]interface{})
+			return fmt.Sprintf("%v %T %T %T %T", args["id"], args["n"], args["f"], r["from"], r["to"]), nil
+		},
+	})
+	s.AddTransport(NewGET())
+
+	const query = `query($id: ID, $n: Int, $f: Float, $r: Range) { echo(id: $id, n: $n, f: $f, r: $r) }`
+	const variables = `{"id":9007199254740993,"n":7,"f":2,"r":{"from":1,"to":3}}`
+	want := `{"data":{"echo":"9007199254740993 int64 float64 int64 float64"}}`
+
+	body := post(t, s, "/graphql", fmt.Sprintf(`{"query":%q,"variables":%s}`, query, variables))
+	if !strings.Contains(body, want) {
+		t.Errorf("POST: %s, want %s", body, want)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(query)+"&variables="+url.QueryEscape(variables), nil))
+	if body := rec.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("GET: %s, want %s", body, want)
+	}
+}
@@ -26,6 +26,11 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+	case "validate":
+		if err := cmd.RunValidate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "version", "-v", "--version":
 		fmt.Printf("goinmonster version %s\n", version)
 	case "help", "-h", "--help":
@@ -46,6 +51,7 @@ Usage:
 Commands:
   init        Initialize a new goinmonster project with config file
   generate    Generate Go code from GraphQL schema (alias: gen)
+  validate    Check the config and schema without generating code
   version     Print version information
   help        Show this help message
 
@@ -53,8 +59,9 @@ Examples:
   goinmonster init
   goinmonster generate
   goinmonster gen --config goinmonster.yaml
+  goinmonster validate
 
 Configuration:
-  By default, goinmonster looks for 'goinmonster.yaml' in the current directory.
-  Use --config to specify a different configuration file.`)
+  By default, goinmonster uses the nearest 'goinmonster.yaml' in the current
+  directory or its parents. Use --config to specify a different configuration file.`)
 }
@@ -52,9 +52,10 @@ const (
 	OpJSONContainedBy // <@
 
 	// Array operators
-	OpArrayContains // @>
-	OpArrayOverlap  // &&
-	OpArrayConcat   // ||
+	OpArrayContains    // @>
+	OpArrayOverlap     // &&
+	OpArrayConcat      // ||
+	OpArrayContainedBy // <@
 )
 
 type UnaryOp int
@@ -30,6 +30,7 @@ type Dialect interface {
 	SupportsForUpdate() bool
 	SupportsMaterializedCTE() bool
 	SupportsFullOuterJoin() bool
+	SupportsFullText() bool
 
 	// Formatters
 	FormatLimitOffset(limit, offset ast.Expression) string
@@ -41,6 +42,9 @@ type Dialect interface {
 	FormatBoolLiteral(b bool) string
 	FormatCast(typeName string) string
 
+	// WrapExplain prefixes a statement with EXPLAIN and its options
+	WrapExplain(sql string, opts dialecttypes.ExplainOptions) string
+
 	// Escape functions
 	EscapeString(value string) string
 	EscapeIdentifier(identifier string) string
@@ -2,6 +2,7 @@ package dialects
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -36,6 +37,7 @@ func (d PostgreSQL) SupportsNullsFirstLast() bool  { return true }
 func (d PostgreSQL) SupportsForUpdate() bool       { return true }
 func (d PostgreSQL) SupportsMaterializedCTE() bool { return true }
 func (d PostgreSQL) SupportsFullOuterJoin() bool   { return true }
+func (d PostgreSQL) SupportsFullText() bool        { return true }
 
 /*
 * ========================================================================
@@ -194,13 +196,18 @@ func (d PostgreSQL) BuildInsert(opts dialecttypes.PostgreSQLInsertOptions) strin
 		sb.WriteString(")")
 	}
 
-	// VALUES
-	sb.WriteString("\nVALUES ")
-	valueParts := make([]string, len(opts.Values))
-	for i, row := range opts.Values {
-		valueParts[i] = "(" + strings.Join(row, ", ") + ")"
+	// VALUES, or the rows of a SELECT
+	if opts.Select != "" {
+		sb.WriteString("\n")
+		sb.WriteString(opts.Select)
+	} else {
+		sb.WriteString("\nVALUES ")
+		valueParts := make([]string, len(opts.Values))
+		for i, row := range opts.Values {
+			valueParts[i] = "(" + strings.Join(row, ", ") + ")"
+		}
+		sb.WriteString(strings.Join(valueParts, ", "))
 	}
-	sb.WriteString(strings.Join(valueParts, ", "))
 
 	// ON CONFLICT
 	if opts.OnConflict != nil {
@@ -248,9 +255,14 @@ func (d PostgreSQL) BuildUpdate(opts dialecttypes.PostgreSQLUpdateOptions) strin
 
 	// SET clause
 	sb.WriteString("\nSET ")
-	setParts := make([]string, 0, len(opts.Set))
-	for col, val := range opts.Set {
-		setParts = append(setParts, fmt.Sprintf("%s = %s", col, val))
+	setCols := make([]string, 0, len(opts.Set))
+	for col := range opts.Set {
+		setCols = append(setCols, col)
+	}
+	sort.Strings(setCols)
+	setParts := make([]string, 0, len(setCols))
+	for _, col := range setCols {
+		setParts = append(setParts, fmt.Sprintf("%s = %s", col, opts.Set[col]))
 	}
 	sb.WriteString(strings.Join(setParts, ", "))
 
@@ -431,6 +443,8 @@ func (d PostgreSQL) FormatBinaryOp(op ast.BinaryOp) string {
 		return "&&"
 	case ast.OpArrayConcat:
 		return "||"
+	case ast.OpArrayContainedBy:
+		return "<@"
 	default:
 		return ""
 	}
@@ -470,6 +484,26 @@ func (d PostgreSQL) FormatCast(typeName string) string {
 	return typeName
 }
 
+// WrapExplain builds EXPLAIN (options) sql; the format defaults to JSON
+func (d PostgreSQL) WrapExplain(sql string, opts dialecttypes.ExplainOptions) string {
+	var options []string
+	if opts.Analyze {
+		options = append(options, "ANALYZE")
+	}
+	if opts.Buffers {
+		options = append(options, "BUFFERS")
+	}
+	if opts.Verbose {
+		options = append(options, "VERBOSE")
+	}
+	format := strings.ToUpper(opts.Format)
+	if format == "" {
+		format = "JSON"
+	}
+	options = append(options, "FORMAT "+format)
+	return "EXPLAIN (" + strings.Join(options, ", ") + ") " + sql
+}
+
 func (d PostgreSQL) EscapeString(value string) string {
 	return strings.ReplaceAll(value, `'`, `''`)
 }
@@ -0,0 +1,24 @@
+package dialects
+
+import (
+	"testing"
+
+	"github.com/eddieafk/goinmonster/sql/stringifiers/dialecttypes"
+)
+
+func TestWrapExplain(t *testing.T) {
+	const query = `SELECT u."id" FROM "users" u`
+	tests := []struct {
+		opts dialecttypes.ExplainOptions
+		want string
+	}{
+		{dialecttypes.ExplainOptions{}, `EXPLAIN (FORMAT JSON) ` + query},
+		{dialecttypes.ExplainOptions{Analyze: true}, `EXPLAIN (ANALYZE, FORMAT JSON) ` + query},
+		{dialecttypes.ExplainOptions{Analyze: true, Buffers: true, Verbose: true, Format: "text"}, `EXPLAIN (ANALYZE, BUFFERS, VERBOSE, FORMAT TEXT) ` + query},
+	}
+	for _, tt := range tests {
+		if got := (PostgreSQL{}).WrapExplain(query, tt.opts); got != tt.want {
+			t.Errorf("WrapExplain(%+v) = %s\nwant %s", tt.opts, got, tt.want)
+		}
+	}
+}
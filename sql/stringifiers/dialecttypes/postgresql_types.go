@@ -138,6 +138,7 @@ type PostgreSQLInsertOptions struct {
 	TableName  string
 	Columns    []string
 	Values     [][]string
+	Select     string // Inserts the rows of this SELECT instead of Values
 	OnConflict *OnConflictClause
 	Returning  []string
 }
@@ -152,6 +153,14 @@ type PostgreSQLUpdateOptions struct {
 	Returning  []string
 }
 
+// ExplainOptions controls how a statement is wrapped in EXPLAIN
+type ExplainOptions struct {
+	Analyze bool   // Run the statement and report actual times and row counts
+	Buffers bool   // Report buffer usage (with Analyze)
+	Verbose bool   // Report output columns and schema-qualified names
+	Format  string // TEXT, JSON, YAML or XML; empty means JSON
+}
+
 // PostgreSQLDeleteOptions represents DELETE options for PostgreSQL
 type PostgreSQLDeleteOptions struct {
 	TableName  string
@@ -2,11 +2,13 @@ module test
 
 go 1.24.5
 
-require github.com/eddieafk/goinmonster v0.0.0-00010101000000-000000000000
+require (
+	github.com/eddieafk/goinmonster v0.0.0-00010101000000-000000000000
+	github.com/lib/pq v1.10.9
+)
 
 require (
 	github.com/agnivade/levenshtein v1.2.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
 	github.com/vektah/gqlparser/v2 v2.5.31 // indirect
 )
 
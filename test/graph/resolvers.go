@@ -53,22 +53,7 @@ func (r *queryResolver) Users(es *graph.ExecutableSchema) graph.ResolverFunc {
 		}
 		defer rows.Close()
 
-		var users []map[string]interface{}
-		for rows.Next() {
-			var id, name, email string
-			if err := rows.Scan(&id, &name, &email); err != nil {
-				return nil, err
-			}
-			users = append(users, map[string]interface{}{
-				"id":    id,
-				"name":  name,
-				"email": email,
-			})
-		}
-		if err := rows.Err(); err != nil {
-			return nil, err
-		}
-		return users, nil
+		return sqlConverter.ScanRows(rows, "User")
 
 	}
 }
@@ -29,7 +29,17 @@ directive @sql(
   column: String
   table: String
   relation: String
-) on FIELD_DEFINITION
+  filterable: Boolean
+  sortable: Boolean
+  default: String
+  cast: String
+  index: String
+  readonly: Boolean
+  schema: String
+  primaryKey: String
+  joinOn: String
+  jsonExtract: String
+) on FIELD_DEFINITION | OBJECT
 
 # Example types - replace with your own
 
@@ -108,9 +118,10 @@ input UpdateUserInput {
 	}))
 
 	// Set up HTTP handler with CORS
+	h := srv.Handler(handler.CORSMiddleware(handler.DefaultCORSConfig()))
 	mux := http.NewServeMux()
-	mux.Handle("/graphql", corsMiddleware(srv))
-	mux.Handle("/playground", corsMiddleware(srv))
+	mux.Handle("/graphql", h)
+	mux.Handle("/playground", h)
 
 	// Start server
 	addr := ":8080"
@@ -122,22 +133,6 @@ input UpdateUserInput {
 	}
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // truncateQuery truncates a query for logging
 func truncateQuery(query string) string {
 	if len(query) > 100 {